@@ -1,5 +1,5 @@
 import config from "./config";
-import { fetchComments, submitComment, type CommentData } from "./fetch";
+import { fetchComments, submitComment, toggleCommentReaction, type CommentData, type CommentThreadState, type ReactionCount } from "./fetch";
 import { findElementsWithAttribute, getBrowserUA, getUserIPInfo } from "./util";
 
 type ReplyTarget = {
@@ -14,6 +14,8 @@ const COMMENT_REFRESH_EVENT = 'marku:comment-success';
 
 const formAnchorMap = new WeakMap<Element, Comment>();
 const commentFormRegistry = new Map<string, Set<HTMLFormElement>>();
+// 各评论列表所在站点可用的表情回应
+const reactionSetRegistry = new Map<string, string[]>();
 let commentReplyBridgeBound = false;
 type CommentListState = {
     key: string;
@@ -21,6 +23,8 @@ type CommentListState = {
     pageSize: number;
     total: number;
     pageCount: number;
+    sort: string;
+    featured: boolean;
     listElement: Element;
     bodyElement: HTMLElement;
     parentTemplate: HTMLTemplateElement;
@@ -101,6 +105,8 @@ const getCommentListState = (listElement: Element) => {
         pageSize: Math.min(parsePositiveInt(listElement.getAttribute('marku-comment-page-size') || '', 10), 100),
         total: 0,
         pageCount: 1,
+        sort: listElement.getAttribute('marku-comment-sort') || '',
+        featured: listElement.hasAttribute('marku-comment-featured'),
         listElement,
         bodyElement: getCommentListBody(listElement),
         parentTemplate: templates.parentTemplate,
@@ -210,6 +216,26 @@ const renderCommentListIntoState = (state: CommentListState, comments: CommentDa
     });
 };
 
+// 按页面评论状态隐藏表单与回复按钮：关闭时隐藏全部表单，锁定时只禁止回复
+const applyThreadState = (state: CommentListState, thread?: CommentThreadState) => {
+    const closed = Boolean(thread?.closed);
+    const locked = closed || Boolean(thread?.locked);
+
+    state.listElement.toggleAttribute('marku-comment-closed', closed);
+    state.listElement.toggleAttribute('marku-comment-locked', locked);
+
+    state.bodyElement.querySelectorAll('[marku-comment-reply]').forEach(button => {
+        (button as HTMLElement).hidden = locked;
+    });
+
+    commentFormRegistry.get(state.key)?.forEach(form => {
+        if (locked) {
+            setReplyTarget(form, null);
+        }
+        form.hidden = closed;
+    });
+};
+
 const loadCommentListPage = async (state: CommentListState, page = state.page) => {
     if (state.loading) {
         return;
@@ -218,7 +244,7 @@ const loadCommentListPage = async (state: CommentListState, page = state.page) =
     state.loading = true;
     updateCommentListSummary(state);
 
-    const result = await fetchComments(state.key, page, state.pageSize);
+    const result = await fetchComments(state.key, page, state.pageSize, { sort: state.sort, featured: state.featured });
     if (result.code !== 200) {
         clearCommentListBody(state);
         const errorNode = document.createElement('p');
@@ -237,7 +263,9 @@ const loadCommentListPage = async (state: CommentListState, page = state.page) =
     state.pageSize = result.pageSize || state.pageSize;
     state.total = result.total || 0;
     state.pageCount = result.pageCount || 1;
+    reactionSetRegistry.set(state.key, result.reactionSet || []);
     renderCommentListIntoState(state, result.data || []);
+    applyThreadState(state, result.thread);
     state.loading = false;
     updateCommentListSummary(state);
 };
@@ -255,6 +283,7 @@ const createCommentElement = (
     const commentElement = node.firstElementChild as HTMLElement;
 
     fillCommentData(commentElement, comment);
+    renderCommentReactions(commentElement, comment, reactionSetRegistry.get(listKey) || []);
 
     const replyButton = commentElement.querySelector('[marku-comment-reply]');
     if (replyButton && comment.id) {
@@ -291,6 +320,38 @@ const createCommentElement = (
     return commentElement;
 };
 
+// 在 marku-comment-reactions 容器中渲染表情回应按钮，点击切换当前访客的回应
+const renderCommentReactions = (element: Element, comment: CommentData, reactionSet: string[]) => {
+    const container = element.querySelector('[marku-comment-reactions]') as HTMLElement | null;
+    if (!container || !comment.id || reactionSet.length === 0) {
+        return;
+    }
+
+    const commentId = Number(comment.id);
+    const render = (counts: ReactionCount[], mine: string[]) => {
+        container.replaceChildren();
+        reactionSet.forEach(emoji => {
+            const count = counts.find(item => item.emoji === emoji)?.count || 0;
+            const button = document.createElement('button');
+            button.type = 'button';
+            button.setAttribute('data-marku-reaction', emoji);
+            button.toggleAttribute('data-marku-reacted', mine.includes(emoji));
+            button.textContent = count > 0 ? `${emoji} ${count}` : emoji;
+            button.addEventListener('click', async () => {
+                button.disabled = true;
+                const summary = await toggleCommentReaction(commentId, emoji);
+                button.disabled = false;
+                if (summary) {
+                    render(summary.counts, summary.mine);
+                }
+            });
+            container.appendChild(button);
+        });
+    };
+
+    render(comment.reactions || [], comment.my_reactions || []);
+};
+
 const getFormReplyState = (form: Element) => {
     let parentInput = form.querySelector('[marku-comment-parent]') as HTMLInputElement | null;
     if (!parentInput) {
@@ -545,12 +606,19 @@ const renderCommentNode = (
 const fillCommentData = (element: Element, comment: CommentData) => {
     if (comment.id !== undefined && comment.id !== null) {
         element.setAttribute('data-marku-comment-id', String(comment.id));
+        // 供 @提及 链接跳转定位
+        if (!element.id) {
+            element.id = `marku-comment-${comment.id}`;
+        }
     }
 
     if (comment.username) {
         element.setAttribute('data-marku-comment-username', comment.username);
     }
 
+    element.toggleAttribute('data-marku-comment-pinned', Boolean(comment.pinned));
+    element.toggleAttribute('data-marku-comment-featured', Boolean(comment.featured));
+
     const avatarSrc = (comment.avatar || comment.user?.avatar || '').trim();
     const displayName = (comment.username || '匿名用户').trim();
     const avatarFallback = displayName.charAt(0).toUpperCase() || 'U';
@@ -597,7 +665,11 @@ const fillCommentData = (element: Element, comment: CommentData) => {
     // 评论内容
     const contentEl = element.querySelector('[marku-comment-content]');
     if (contentEl) {
-        contentEl.textContent = comment.content || '';
+        if (comment.content_html !== undefined) {
+            contentEl.innerHTML = comment.content_html;
+        } else {
+            contentEl.textContent = comment.content || '';
+        }
     }
 
     // 时间（如果有 created_at 字段）
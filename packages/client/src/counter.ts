@@ -1,10 +1,11 @@
-import { fetchCountersBatch, setCountersBatch } from "./fetch";
+import { fetchCommentCountsBatch, fetchCountersBatch, setCountersBatch } from "./fetch";
 import { findElementsWithAttribute, parseIncrement } from "./util";
 
 export const processCounters = async () => {
     // 先执行 set 操作，再执行 get 操作以保证数据一致性
     await processSetCounter();
     await processGetCounter();
+    await processCommentCount();
 }
 
 export const processGetCounter = async () => {
@@ -119,4 +120,42 @@ export const processSetCounter = async () => {
     });
 
     console.log('Marku Counter: All set-counters processed');
-}
\ No newline at end of file
+}
+
+export const processCommentCount = async () => {
+    // 查找所有带有marku-comment-count属性的元素，显示对应页面的评论数
+    const countElements = findElementsWithAttribute('marku-comment-count');
+    if (countElements.length === 0) {
+        return;
+    }
+    console.log(`Marku Counter: Found ${countElements.length} comment count elements`);
+
+    const keyElementMap = new Map<string, Element[]>();
+    countElements.forEach(element => {
+        const key = element.getAttribute('marku-comment-count');
+        if (!key) {
+            console.warn('Marku Counter: Element has empty marku-comment-count attribute', element);
+            return;
+        }
+        if (!keyElementMap.has(key)) {
+            keyElementMap.set(key, []);
+        }
+        keyElementMap.get(key)!.push(element);
+        element.classList.add('marku-loading');
+    });
+
+    const counts = await fetchCommentCountsBatch(Array.from(keyElementMap.keys()));
+    keyElementMap.forEach((elements, key) => {
+        const count = counts.get(key);
+
+        elements.forEach(element => {
+            element.classList.remove('marku-loading');
+            if (count !== null && count !== undefined) {
+                element.textContent = count.toString();
+                element.classList.add('marku-loaded');
+            } else {
+                element.classList.add('marku-error');
+            }
+        });
+    });
+}
@@ -37,12 +37,22 @@ export interface CommentData {
     url?: string;
     avatar?: string;
     content: string;
+    // 服务端转义后的内容，@提及 已渲染为链接或 span
+    content_html?: string;
+    mentions?: { username: string; comment_id?: number }[];
+    // 表情回应数量与当前访客自己的回应
+    reactions?: ReactionCount[];
+    my_reactions?: string[];
     mark: string;
     siteId: string;
     parent?: number | string;
     ip?: string;
     location?: string;
     ua?: string;
+    // 非管理员获取评论列表时只返回邮箱摘要
+    email_hash?: string;
+    pinned?: boolean;
+    featured?: boolean;
     created_at?: string;
     updated_at?: string;
     user?: {
@@ -51,6 +61,7 @@ export interface CommentData {
         email?: string;
         url?: string;
         avatar?: string;
+        email_hash?: string;
     };
 }
 
@@ -65,6 +76,32 @@ export interface CommentSubmitResponse {
     };
 }
 
+/**
+ * 页面评论状态
+ */
+export interface CommentThreadState {
+    closed: boolean;
+    locked: boolean;
+    force_moderation: boolean;
+    closes_at?: string;
+}
+
+/**
+ * 表情回应数量
+ */
+export interface ReactionCount {
+    emoji: string;
+    count: number;
+}
+
+/**
+ * 表情回应汇总
+ */
+export interface ReactionSummary {
+    counts: ReactionCount[];
+    mine: string[];
+}
+
 /**
  * 评论列表响应接口
  */
@@ -77,6 +114,8 @@ export interface CommentListResponse {
     page?: number;
     pageSize?: number;
     pageCount?: number;
+    thread?: CommentThreadState;
+    reactionSet?: string[];
 }
 
 type CommentListPayload = {
@@ -85,6 +124,8 @@ type CommentListPayload = {
     page?: number;
     pageSize?: number;
     pageCount?: number;
+    thread?: CommentThreadState;
+    reactionSet?: string[];
 };
 
 const normalizeCommentListResponse = (result: Record<string, unknown>): CommentListResponse => {
@@ -104,6 +145,8 @@ const normalizeCommentListResponse = (result: Record<string, unknown>): CommentL
         page: typeof result.page === 'number' ? result.page : payload?.page,
         pageSize: typeof result.pageSize === 'number' ? result.pageSize : payload?.pageSize,
         pageCount: typeof result.pageCount === 'number' ? result.pageCount : payload?.pageCount,
+        thread: payload?.thread,
+        reactionSet: payload?.reactionSet,
     };
 };
 
@@ -193,6 +236,86 @@ export const fetchCountersBatch = async (keys: string[]): Promise<Map<string, nu
     return result;
 }
 
+/**
+ * 批量查询评论数响应接口
+ */
+export interface BatchCommentCountAPIResponse {
+    code: number;
+    message?: string;
+    data?: Array<{
+        mark: string;
+        count: number;
+        replies?: number;
+        latest_at?: string;
+    }>;
+}
+
+export const fetchCommentCountsBatch = async (keys: string[]): Promise<Map<string, number | null>> => {
+    const result = new Map<string, number | null>();
+
+    if (keys.length === 0) {
+        return result;
+    }
+
+    if (!config.apiBaseUrl || !config.siteId) {
+        console.error('Marku Comment: apiBaseUrl and siteId are required for fetchCommentCountsBatch');
+        keys.forEach(key => {
+            result.set(key, null);
+        });
+        return result;
+    }
+
+    try {
+        const url = new URL('/api/comment/count/batch', config.apiBaseUrl);
+
+        const controller = new AbortController();
+        // 10s超时
+        const timeoutId = setTimeout(() => controller.abort(), 10000);
+
+        const response = await fetch(url.toString(), {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({
+                siteId: config.siteId,
+                marks: keys
+            }),
+            signal: controller.signal
+        });
+
+        clearTimeout(timeoutId);
+
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+
+        const data: BatchCommentCountAPIResponse = await response.json();
+
+        if (data.code === 200 && data.data) {
+            data.data.forEach(item => {
+                result.set(item.mark, item.count || 0);
+            });
+            keys.forEach(key => {
+                if (!result.has(key)) {
+                    result.set(key, 0);
+                }
+            });
+        } else {
+            keys.forEach(key => {
+                result.set(key, null);
+            });
+        }
+    } catch (error) {
+        console.error('Marku Comment: Failed to fetch comment counts batch:', error);
+        keys.forEach(key => {
+            result.set(key, null);
+        });
+    }
+
+    return result;
+}
+
 export const setCountersBatch = async (counters: Array<{ mark: string; increment: number }>): Promise<boolean> => {
     if (counters.length === 0) {
         return true;
@@ -302,7 +425,158 @@ export const submitComment = async (commentData: CommentData): Promise<CommentSu
     }
 }
 
-export const fetchComments = async (key: string, page = 1, pageSize = 10): Promise<CommentListResponse> => {
+/**
+ * 切换当前访客对评论的表情回应，返回更新后的汇总
+ */
+export const toggleCommentReaction = async (commentId: number, emoji: string): Promise<ReactionSummary | null> => {
+    if (!config.apiBaseUrl) {
+        console.error('Marku Comment: apiBaseUrl is required');
+        return null;
+    }
+
+    try {
+        const url = new URL(`/api/comment/${commentId}/reactions`, config.apiBaseUrl);
+
+        const controller = new AbortController();
+        const timeoutId = setTimeout(() => controller.abort(), 10000);
+
+        const response = await fetch(url.toString(), {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ emoji }),
+            signal: controller.signal
+        });
+
+        clearTimeout(timeoutId);
+
+        if (!response.ok) {
+            console.error('Marku Comment: HTTP error', response.status, response.statusText);
+            return null;
+        }
+
+        const result = await response.json();
+        if (result.code === 200 && result.data?.reactions) {
+            return result.data.reactions as ReactionSummary;
+        }
+        console.error('Marku Comment: Toggle reaction failed', result.message || result.msg);
+        return null;
+    } catch (error) {
+        console.error('Marku Comment: Network error', error);
+        return null;
+    }
+}
+
+/**
+ * 举报评论，reason 可选 spam、abuse、harassment、illegal、off_topic、other；同一访客重复举报视为成功
+ */
+export const reportComment = async (commentId: number, reason: string, detail?: string): Promise<boolean> => {
+    if (!config.apiBaseUrl) {
+        console.error('Marku Comment: apiBaseUrl is required');
+        return false;
+    }
+
+    try {
+        const url = new URL(`/api/comment/${commentId}/report`, config.apiBaseUrl);
+
+        const controller = new AbortController();
+        const timeoutId = setTimeout(() => controller.abort(), 10000);
+
+        const response = await fetch(url.toString(), {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ reason, detail }),
+            signal: controller.signal
+        });
+
+        clearTimeout(timeoutId);
+
+        if (!response.ok) {
+            console.error('Marku Comment: HTTP error', response.status, response.statusText);
+            return false;
+        }
+
+        const result = await response.json();
+        if (result.code === 200) {
+            return true;
+        }
+        console.error('Marku Comment: Report comment failed', result.message || result.msg);
+        return false;
+    } catch (error) {
+        console.error('Marku Comment: Network error', error);
+        return false;
+    }
+}
+
+/**
+ * 图片上传结果
+ */
+export interface UploadedImage {
+    url: string;
+    thumbnail_url: string;
+    width: number;
+    height: number;
+    // 可直接插入评论内容的 Markdown 图片语法
+    markdown: string;
+}
+
+/**
+ * 上传评论图片，成功后将返回的 markdown 插入评论内容即可在提交时关联图片
+ */
+export const uploadCommentImage = async (file: File): Promise<UploadedImage | null> => {
+    if (!config.apiBaseUrl || !config.siteId) {
+        console.error('Marku Comment: apiBaseUrl and siteId are required');
+        return null;
+    }
+
+    try {
+        const url = new URL('/api/comment/upload', config.apiBaseUrl);
+        const form = new FormData();
+        form.append('siteId', config.siteId);
+        form.append('file', file);
+
+        const controller = new AbortController();
+        const timeoutId = setTimeout(() => controller.abort(), 30000);
+
+        const response = await fetch(url.toString(), {
+            method: 'POST',
+            body: form,
+            signal: controller.signal
+        });
+
+        clearTimeout(timeoutId);
+
+        if (!response.ok) {
+            console.error('Marku Comment: HTTP error', response.status, response.statusText);
+            return null;
+        }
+
+        const result = await response.json();
+        if (result.code === 200 && result.data?.url) {
+            return result.data as UploadedImage;
+        }
+        console.error('Marku Comment: Upload image failed', result.message || result.msg);
+        return null;
+    } catch (error) {
+        console.error('Marku Comment: Network error', error);
+        return null;
+    }
+}
+
+/**
+ * 评论列表查询选项
+ */
+export interface CommentListOptions {
+    // 排序方式：newest、oldest、top
+    sort?: string;
+    // 只返回精选评论
+    featured?: boolean;
+}
+
+export const fetchComments = async (key: string, page = 1, pageSize = 10, options: CommentListOptions = {}): Promise<CommentListResponse> => {
     if (!config.apiBaseUrl) {
         console.error('Marku Comment: apiBaseUrl is required');
         return {
@@ -327,6 +601,12 @@ export const fetchComments = async (key: string, page = 1, pageSize = 10): Promi
         url.searchParams.append('key', key);
         url.searchParams.append('page', String(page));
         url.searchParams.append('pageSize', String(pageSize));
+        if (options.sort) {
+            url.searchParams.append('sort', options.sort);
+        }
+        if (options.featured) {
+            url.searchParams.append('featured', '1');
+        }
 
         const controller = new AbortController();
         const timeoutId = setTimeout(() => controller.abort(), 10000);
@@ -1,6 +1,7 @@
 import { processCommentSubmit, processCommentList } from "./comment";
 import { initConfig, type MarkuConfig } from "./config";
 import { processCounters } from "./counter";
+import { reportComment, uploadCommentImage, type UploadedImage } from "./fetch";
 
 // Marku类，支持实例化调用
 class Marku {
@@ -66,6 +67,24 @@ class Marku {
         processCommentList();
     }
 
+    // 上传评论图片，返回可插入评论内容的 Markdown
+    async uploadImage(file: File): Promise<UploadedImage | null> {
+        if (!this.initialized) {
+            console.warn('Marku: Not initialized yet. Call init() first.');
+            return null;
+        }
+        return uploadCommentImage(file);
+    }
+
+    // 举报评论
+    async reportComment(commentId: number, reason: string, detail?: string): Promise<boolean> {
+        if (!this.initialized) {
+            console.warn('Marku: Not initialized yet. Call init() first.');
+            return false;
+        }
+        return reportComment(commentId, reason, detail);
+    }
+
     // 检查是否已初始化
     isInitialized(): boolean {
         return this.initialized;
@@ -78,5 +97,5 @@ export default Marku;
 export type { MarkuConfig } from "./config";
 
 // 导出其他有用的接口
-export type { CommentData, CommentSubmitResponse } from "./fetch";
+export type { CommentData, CommentSubmitResponse, UploadedImage } from "./fetch";
 export type { IPInfo } from "./util";
\ No newline at end of file
@@ -14,7 +14,11 @@ type Config struct {
 	Site     SiteConfig     `yaml:"site"`
 	Admin    AdminConfig    `yaml:"admin"`
 	Comment  CommentConfig  `yaml:"comment"`
+	Avatar   AvatarConfig   `yaml:"avatar"`
+	Auth     AuthConfig     `yaml:"auth"`
+	OAuth    OAuthConfig    `yaml:"oauth"`
 	SMTP     SMTPConfig     `yaml:"smtp"`
+	Upload   UploadConfig   `yaml:"upload"`
 	Database DatabaseConfig `yaml:"database"`
 }
 
@@ -26,6 +30,7 @@ type SiteConfig struct {
 	LogPath        string   `yaml:"log_path"`
 	DropTable      bool     `yaml:"drop_table"`
 	AllowedOrigins []string `yaml:"allowed_origins"`
+	PublicBaseURL  string   `yaml:"public_base_url"` // 服务对外访问地址，用于邮件、回调与资源链接，留空则按请求地址推导
 }
 
 // DatabaseConfig 数据库配置结构体
@@ -59,8 +64,85 @@ type AdminConfig struct {
 
 // CommentConfig 评论状态配置结构体
 type CommentConfig struct {
-	DefaultStatus string `yaml:"default_status"`
-	RequireLogin  bool   `yaml:"require_login"`
+	DefaultStatus   string               `yaml:"default_status"`
+	RequireLogin    bool                 `yaml:"require_login"`
+	Privacy         CommentPrivacyConfig `yaml:"privacy"`
+	UARulesPath     string               `yaml:"ua_rules_path"`    // User-Agent 解析规则文件，留空使用内置规则
+	Reactions       []string             `yaml:"reactions"`        // 评论与页面可用的表情回应，站点可单独覆盖
+	ReportThreshold int                  `yaml:"report_threshold"` // 评论被多少位访客举报后自动转为待审核，负数表示不自动处理
+}
+
+// CommentPrivacyConfig 评论列表对非管理员公开的作者字段
+type CommentPrivacyConfig struct {
+	PublicFields []string            `yaml:"public_fields"` // 默认公开字段
+	Sites        map[string][]string `yaml:"sites"`         // 按站点覆盖公开字段
+}
+
+// AvatarConfig 头像解析配置结构体
+type AvatarConfig struct {
+	Mirror    string `yaml:"mirror"`     // gravatar / cravatar / weavatar / 自定义模板，留空则只使用本地生成的头像
+	Default   string `yaml:"default"`    // 镜像找不到头像时的回退，local 表示本地生成的头像
+	Size      int    `yaml:"size"`       // 头像边长（像素）
+	Hash      string `yaml:"hash"`       // 邮箱摘要算法: md5 / sha256
+	PublicURL string `yaml:"public_url"` // 服务对外访问地址，用于拼接本地头像地址，留空则使用 site.public_base_url
+}
+
+// AuthConfig 登录认证配置结构体
+type AuthConfig struct {
+	EmailLoginAutoRegister bool          `yaml:"email_login_auto_register"` // 邮箱验证码登录时是否自动注册未知邮箱
+	RequireAdmin2FA        bool          `yaml:"require_admin_2fa"`         // 管理员是否必须启用两步验证
+	TOTPIssuer             string        `yaml:"totp_issuer"`               // 认证器应用中显示的签发者名称
+	JWT                    JWTConfig     `yaml:"jwt"`
+	Lockout                LockoutConfig `yaml:"lockout"`
+}
+
+// LockoutConfig 登录与验证码防暴力破解配置
+type LockoutConfig struct {
+	MaxFailures          int `yaml:"max_failures"`           // 同一账户或邮箱连续失败多少次后锁定
+	IPMaxFailures        int `yaml:"ip_max_failures"`        // 同一 IP 连续失败多少次后锁定
+	FailureWindowSeconds int `yaml:"failure_window_seconds"` // 超过该时长未再失败则重新计数
+	BaseLockoutSeconds   int `yaml:"base_lockout_seconds"`   // 首次锁定时长，之后每次翻倍
+	MaxLockoutSeconds    int `yaml:"max_lockout_seconds"`    // 锁定时长上限
+	CodeMaxAttempts      int `yaml:"code_max_attempts"`      // 单个验证码允许的错误次数，超过后作废
+}
+
+// JWTConfig 访问令牌签发配置
+type JWTConfig struct {
+	Issuer           string         `yaml:"issuer"`             // 签发者 iss
+	Audience         []string       `yaml:"audience"`           // 受众 aud
+	ActiveKID        string         `yaml:"active_kid"`         // 当前用于签发的密钥 kid
+	Keys             []JWTKeyConfig `yaml:"keys"`               // 全部可用于校验的密钥，轮换时保留旧密钥
+	LegacyTokenUntil string         `yaml:"legacy_token_until"` // 旧格式令牌（含升级前的 userID:expiry:sig）的兼容截止时间（RFC 3339 或 2006-01-02）
+}
+
+// JWTKeyConfig 单个签名密钥配置
+type JWTKeyConfig struct {
+	KID            string `yaml:"kid"`
+	Algorithm      string `yaml:"algorithm"`        // HS256 / EdDSA / RS256
+	Secret         string `yaml:"secret"`           // HS256 共享密钥
+	PrivateKeyPath string `yaml:"private_key_path"` // EdDSA / RS256 私钥（PKCS#8 或 PKCS#1 PEM）
+	PublicKeyPath  string `yaml:"public_key_path"`  // 仅用于校验的已退役公钥（PKIX PEM）
+}
+
+// OAuthConfig 第三方登录配置结构体
+type OAuthConfig struct {
+	RedirectURL string                `yaml:"redirect_url"` // 登录完成后默认跳转的前端地址
+	Providers   []OAuthProviderConfig `yaml:"providers"`
+}
+
+// OAuthProviderConfig 单个第三方登录提供方配置
+type OAuthProviderConfig struct {
+	Name         string   `yaml:"name"`          // 提供方标识，出现在回调地址中
+	Type         string   `yaml:"type"`          // github / gitee / oidc
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	CallbackURL  string   `yaml:"callback_url"`  // 在提供方登记的回调地址，留空则按请求地址推导
+	Issuer       string   `yaml:"issuer"`        // OIDC 签发者，用于自动发现端点
+	AuthURL      string   `yaml:"auth_url"`      // 以下端点留空时使用内置默认值或自动发现结果
+	TokenURL     string   `yaml:"token_url"`
+	UserInfoURL  string   `yaml:"userinfo_url"`
+	EmailsURL    string   `yaml:"emails_url"`
+	Scopes       []string `yaml:"scopes"`
 }
 
 // SMTPConfig 邮件服务器配置结构体
@@ -76,6 +158,43 @@ type SMTPConfig struct {
 	SkipVerify  bool  `yaml:"skip_verify"`
 }
 
+// UploadConfig 评论图片上传配置
+type UploadConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxSizeMB     int           `yaml:"max_size_mb"`    // 单张图片大小上限（MB）
+	AllowedTypes  []string      `yaml:"allowed_types"`  // 允许的 MIME 类型，按文件内容嗅探判断
+	MaxPixels     int           `yaml:"max_pixels"`     // 解码前校验的像素总数上限，防止解压炸弹
+	ThumbnailSize int           `yaml:"thumbnail_size"` // 缩略图最长边（像素）
+	JPEGQuality   int           `yaml:"jpeg_quality"`   // 重新编码 JPEG 的质量
+	MaxPerHour    int           `yaml:"max_per_hour"`   // 同一登录用户或同一 IP 的游客每小时最多上传数量
+	MaxUnlinked   int           `yaml:"max_unlinked"`   // 同一 IP 已上传但尚未被评论引用的图片数量上限
+	PublicURL     string        `yaml:"public_url"`     // 图片对外访问地址前缀（如 CDN），留空则使用 /api/uploads
+	Storage       StorageConfig `yaml:"storage"`
+}
+
+// StorageConfig 上传文件存储后端配置
+type StorageConfig struct {
+	Type  string             `yaml:"type"` // local / s3
+	Local LocalStorageConfig `yaml:"local"`
+	S3    S3StorageConfig    `yaml:"s3"`
+}
+
+// LocalStorageConfig 本地文件系统存储配置
+type LocalStorageConfig struct {
+	Path string `yaml:"path"`
+}
+
+// S3StorageConfig S3 兼容对象存储配置
+type S3StorageConfig struct {
+	Endpoint  string `yaml:"endpoint"` // 如 https://s3.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Prefix    string `yaml:"prefix"`     // 对象键前缀
+	PathStyle bool   `yaml:"path_style"` // 使用 endpoint/bucket/key 形式访问，MinIO 等自建服务通常需要开启
+}
+
 // 全局配置变量 (保持向后兼容)
 var (
 	AppKey         string
@@ -176,6 +295,14 @@ func GetAllowedOrigins() []string {
 	return []string{}
 }
 
+// GetPublicBaseURL 获取配置的服务对外访问地址（不含末尾斜杠），未配置时返回空字符串
+func GetPublicBaseURL() string {
+	if GlobalConfig != nil {
+		return strings.TrimRight(strings.TrimSpace(GlobalConfig.Site.PublicBaseURL), "/")
+	}
+	return ""
+}
+
 // GetDatabaseConfig 获取数据库配置
 func GetDatabaseConfig() *DatabaseConfig {
 	if GlobalConfig != nil {
@@ -233,6 +360,155 @@ func GetSMTPConfig() *SMTPConfig {
 	return nil
 }
 
+// GetAvatarConfig 获取头像解析配置，未配置的项使用默认值
+func GetAvatarConfig() AvatarConfig {
+	avatar := AvatarConfig{}
+	if GlobalConfig != nil {
+		avatar = GlobalConfig.Avatar
+	}
+	avatar.Mirror = strings.TrimSpace(avatar.Mirror)
+	avatar.Default = strings.TrimSpace(avatar.Default)
+	if avatar.Default == "" {
+		avatar.Default = "local"
+	}
+	if avatar.Size <= 0 {
+		avatar.Size = 80
+	}
+	avatar.Hash = strings.ToLower(strings.TrimSpace(avatar.Hash))
+	if avatar.Hash != "sha256" {
+		avatar.Hash = "md5"
+	}
+	avatar.PublicURL = strings.TrimRight(strings.TrimSpace(avatar.PublicURL), "/")
+	return avatar
+}
+
+// GetUploadConfig 获取图片上传配置，未配置的项使用默认值
+func GetUploadConfig() UploadConfig {
+	upload := UploadConfig{}
+	if GlobalConfig != nil {
+		upload = GlobalConfig.Upload
+	}
+	if upload.MaxSizeMB <= 0 {
+		upload.MaxSizeMB = 5
+	}
+	if len(upload.AllowedTypes) == 0 {
+		upload.AllowedTypes = []string{"image/jpeg", "image/png", "image/gif"}
+	}
+	if upload.MaxPixels <= 0 {
+		upload.MaxPixels = 40000000
+	}
+	if upload.ThumbnailSize <= 0 {
+		upload.ThumbnailSize = 400
+	}
+	if upload.JPEGQuality <= 0 || upload.JPEGQuality > 100 {
+		upload.JPEGQuality = 85
+	}
+	if upload.MaxPerHour <= 0 {
+		upload.MaxPerHour = 30
+	}
+	if upload.MaxUnlinked <= 0 {
+		upload.MaxUnlinked = 20
+	}
+	upload.PublicURL = strings.TrimRight(strings.TrimSpace(upload.PublicURL), "/")
+	upload.Storage.Type = strings.ToLower(strings.TrimSpace(upload.Storage.Type))
+	if upload.Storage.Type == "" {
+		upload.Storage.Type = "local"
+	}
+	if strings.TrimSpace(upload.Storage.Local.Path) == "" {
+		upload.Storage.Local.Path = "./data/uploads"
+	}
+	if strings.TrimSpace(upload.Storage.S3.Region) == "" {
+		upload.Storage.S3.Region = "us-east-1"
+	}
+	return upload
+}
+
+// GetAuthConfig 获取登录认证配置
+func GetAuthConfig() *AuthConfig {
+	if GlobalConfig != nil {
+		return &GlobalConfig.Auth
+	}
+	return nil
+}
+
+// IsEmailLoginAutoRegisterEnabled 返回邮箱验证码登录是否自动注册未知邮箱
+func IsEmailLoginAutoRegisterEnabled() bool {
+	authConfig := GetAuthConfig()
+	return authConfig != nil && authConfig.EmailLoginAutoRegister
+}
+
+// IsAdmin2FARequired 返回管理员是否必须启用两步验证
+func IsAdmin2FARequired() bool {
+	authConfig := GetAuthConfig()
+	return authConfig != nil && authConfig.RequireAdmin2FA
+}
+
+// GetTOTPIssuer 获取两步验证签发者名称
+func GetTOTPIssuer() string {
+	authConfig := GetAuthConfig()
+	if authConfig != nil && strings.TrimSpace(authConfig.TOTPIssuer) != "" {
+		return strings.TrimSpace(authConfig.TOTPIssuer)
+	}
+	return "Marku"
+}
+
+// GetLockoutConfig 获取防暴力破解配置，未配置的项使用默认值
+func GetLockoutConfig() LockoutConfig {
+	lockout := LockoutConfig{}
+	if authConfig := GetAuthConfig(); authConfig != nil {
+		lockout = authConfig.Lockout
+	}
+	if lockout.MaxFailures <= 0 {
+		lockout.MaxFailures = 5
+	}
+	if lockout.IPMaxFailures <= 0 {
+		lockout.IPMaxFailures = 30
+	}
+	if lockout.FailureWindowSeconds <= 0 {
+		lockout.FailureWindowSeconds = 900
+	}
+	if lockout.BaseLockoutSeconds <= 0 {
+		lockout.BaseLockoutSeconds = 60
+	}
+	if lockout.MaxLockoutSeconds <= 0 {
+		lockout.MaxLockoutSeconds = 3600
+	}
+	if lockout.CodeMaxAttempts <= 0 {
+		lockout.CodeMaxAttempts = 5
+	}
+	return lockout
+}
+
+// GetJWTConfig 获取访问令牌签发配置
+func GetJWTConfig() *JWTConfig {
+	if GlobalConfig != nil {
+		return &GlobalConfig.Auth.JWT
+	}
+	return nil
+}
+
+// GetOAuthConfig 获取第三方登录配置
+func GetOAuthConfig() *OAuthConfig {
+	if GlobalConfig != nil {
+		return &GlobalConfig.OAuth
+	}
+	return nil
+}
+
+// GetOAuthProviderConfig 根据名称获取第三方登录提供方配置
+func GetOAuthProviderConfig(name string) *OAuthProviderConfig {
+	oauthConfig := GetOAuthConfig()
+	if oauthConfig == nil {
+		return nil
+	}
+	for i := range oauthConfig.Providers {
+		if oauthConfig.Providers[i].Name == name {
+			return &oauthConfig.Providers[i]
+		}
+	}
+	return nil
+}
+
 // GetDefaultCommentStatusValue 获取默认评论状态对应的数字值
 func GetDefaultCommentStatusValue() int {
 	return GetCommentStatusValue(CommentDefaultStatus)
@@ -243,6 +519,57 @@ func IsCommentLoginRequired() bool {
 	return CommentRequireLogin
 }
 
+// 未配置时评论列表默认公开的作者字段
+var defaultCommentPublicFields = []string{"email_hash", "location", "url", "browser"}
+
+// GetCommentPublicFields 获取指定站点评论列表对非管理员公开的作者字段
+func GetCommentPublicFields(siteID string) []string {
+	if GlobalConfig == nil {
+		return defaultCommentPublicFields
+	}
+	privacy := GlobalConfig.Comment.Privacy
+	if fields, ok := privacy.Sites[siteID]; ok {
+		return fields
+	}
+	if privacy.PublicFields != nil {
+		return privacy.PublicFields
+	}
+	return defaultCommentPublicFields
+}
+
+// 未配置时默认可用的表情回应
+var defaultCommentReactions = []string{"👍", "❤️", "😂", "🎉", "😮", "😢"}
+
+// GetCommentReactions 获取全局可用的表情回应
+func GetCommentReactions() []string {
+	if GlobalConfig != nil && len(GlobalConfig.Comment.Reactions) > 0 {
+		return GlobalConfig.Comment.Reactions
+	}
+	return defaultCommentReactions
+}
+
+// 未配置时评论自动转为待审核的举报次数
+const defaultCommentReportThreshold = 3
+
+// GetCommentReportThreshold 获取评论自动转为待审核的举报次数，返回 0 表示不自动处理
+func GetCommentReportThreshold() int {
+	if GlobalConfig == nil || GlobalConfig.Comment.ReportThreshold == 0 {
+		return defaultCommentReportThreshold
+	}
+	if GlobalConfig.Comment.ReportThreshold < 0 {
+		return 0
+	}
+	return GlobalConfig.Comment.ReportThreshold
+}
+
+// GetUARulesPath 获取 User-Agent 解析规则文件路径
+func GetUARulesPath() string {
+	if GlobalConfig != nil {
+		return GlobalConfig.Comment.UARulesPath
+	}
+	return ""
+}
+
 // GetApprovedCommentStatusValue 获取已通过评论状态对应的数字值
 func GetApprovedCommentStatusValue() int {
 	return GetCommentStatusValue("approved")
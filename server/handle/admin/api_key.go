@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"errors"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CreateAPIKeyRequest struct {
+	SiteID        string   `json:"siteId" binding:"required,max=100"`
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expiresInDays"` // 0 表示永不过期
+}
+
+// ListAPIKeys 列出 API 密钥，可按 siteId 过滤
+func ListAPIKeys(c *gin.Context) {
+	keys, err := model.ListAPIKeys(strings.TrimSpace(c.Query("siteId")))
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询 API 密钥失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "获取 API 密钥成功", keys)
+}
+
+// CreateAPIKey 为站点创建 API 密钥，明文只在本次响应中返回
+func CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	siteID := strings.TrimSpace(req.SiteID)
+	name := strings.TrimSpace(req.Name)
+	if siteID == "" || name == "" {
+		utils.SendError(c, http.StatusBadRequest, "站点标识和名称不能为空")
+		return
+	}
+	if _, err := model.ResolveSite(siteID); err != nil {
+		if errors.Is(err, model.ErrUnknownSite) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else {
+			utils.SendError(c, http.StatusServiceUnavailable, err.Error())
+		}
+		return
+	}
+
+	scopes := trimStringList(req.Scopes)
+	if len(scopes) == 0 {
+		utils.SendError(c, http.StatusBadRequest, "至少需要一个权限范围")
+		return
+	}
+	for _, scope := range scopes {
+		if !model.IsValidAPIKeyScope(scope) {
+			utils.SendError(c, http.StatusBadRequest, "无效的权限范围: "+scope+"，可选值为 "+strings.Join(model.APIKeyScopes, "、"))
+			return
+		}
+	}
+
+	if req.ExpiresInDays < 0 {
+		utils.SendError(c, http.StatusBadRequest, "有效天数不能为负数")
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &expires
+	}
+
+	key, plain, err := model.CreateAPIKey(siteID, name, scopes, expiresAt, middleware.CurrentUser(c).ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "创建 API 密钥失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "创建 API 密钥成功，请妥善保存，密钥不会再次显示", gin.H{
+		"key":     plain,
+		"api_key": key,
+	})
+}
+
+// RevokeAPIKey 吊销 API 密钥
+func RevokeAPIKey(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的密钥ID")
+		return
+	}
+
+	if err := model.RevokeAPIKey(uri.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "API 密钥不存在或已吊销")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "吊销 API 密钥失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "已吊销 API 密钥", gin.H{"revoked": true})
+}
+
+// ListAPIKeyAudits 分页查询 API 密钥的调用记录
+func ListAPIKeyAudits(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的密钥ID")
+		return
+	}
+
+	page := parsePositiveInt(c.Query("page"), 1)
+	pageSize := parsePositiveInt(c.Query("pageSize"), 20)
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	audits, total, err := model.ListAPIKeyAudits(uri.ID, page, pageSize)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询调用记录失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取调用记录成功", gin.H{
+		"data":      audits,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"pageCount": int(math.Ceil(float64(total) / float64(pageSize))),
+	})
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"errors"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListLockouts 分页查询登录失败计数与锁定状态，locked=1 时仅返回锁定中的记录
+func ListLockouts(c *gin.Context) {
+	page := parsePositiveInt(c.Query("page"), 1)
+	pageSize := parsePositiveInt(c.Query("pageSize"), 20)
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	attempts, total, err := model.ListLoginAttempts(c.Query("scope"), c.Query("locked") == "1", page, pageSize)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询锁定记录失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取锁定记录成功", gin.H{
+		"data":      attempts,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"pageCount": int(math.Ceil(float64(total) / float64(pageSize))),
+	})
+}
+
+// UnlockLockout 手动解除锁定
+func UnlockLockout(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的记录ID")
+		return
+	}
+
+	if err := model.UnlockLoginAttempt(uri.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "锁定记录不存在")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "解除锁定失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "已解除锁定", gin.H{"unlocked": true})
+}
+
+func parsePositiveInt(value string, fallback int) int {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return fallback
+	}
+	return parsed
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"errors"
+	"marku-server/config"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ResolveReportRequest 处理举报请求结构
+type ResolveReportRequest struct {
+	Action string `json:"action" binding:"required"`
+}
+
+// ListReports 分页查询待处理的举报队列：按评论聚合举报原因，可按 siteId 过滤
+func ListReports(c *gin.Context) {
+	page := parsePositiveInt(c.Query("page"), 1)
+	pageSize := parsePositiveInt(c.Query("pageSize"), 20)
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	reported, total, err := model.ListReportedComments(strings.TrimSpace(c.Query("siteId")), page, pageSize)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询举报失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取举报成功", gin.H{
+		"data":      reported,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"pageCount": int(math.Ceil(float64(total) / float64(pageSize))),
+	})
+}
+
+// ResolveReport 处理评论的全部待处理举报：dismiss 忽略举报，approve 恢复评论为已通过，reject 拒绝评论
+func ResolveReport(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的评论ID")
+		return
+	}
+	var req ResolveReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	if !model.IsValidReportResolution(action) {
+		utils.SendError(c, http.StatusBadRequest, "无效的处理方式，可选值为 dismiss、approve、reject")
+		return
+	}
+
+	comment, err := model.GetCommentByID(uri.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "评论不存在")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+		return
+	}
+
+	var status *int
+	switch action {
+	case model.ReportResolutionApprove:
+		value := config.GetApprovedCommentStatusValue()
+		status = &value
+	case model.ReportResolutionReject:
+		value := config.GetCommentStatusValue("rejected")
+		status = &value
+	}
+
+	resolved, err := model.ResolveReports(comment, action, status)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "该评论没有待处理的举报")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "处理举报失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "举报已处理", gin.H{
+		"id":       comment.ID,
+		"status":   comment.Status,
+		"resolved": resolved,
+	})
+}
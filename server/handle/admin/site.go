@@ -0,0 +1,177 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SiteRequest struct {
+	SiteID             string   `json:"siteId" binding:"required,max=100"`
+	Name               string   `json:"name" binding:"required,max=100"`
+	AllowedOrigins     []string `json:"allowedOrigins"`
+	DefaultStatus      string   `json:"defaultStatus"`
+	RequireLogin       *bool    `json:"requireLogin"`
+	ModerationKeywords []string `json:"moderationKeywords"`
+	MaxCommentLength   int      `json:"maxCommentLength"`
+	PublicFields       []string `json:"publicFields"`
+	Reactions          []string `json:"reactions"`
+	Disabled           bool     `json:"disabled"`
+}
+
+// ListSites 列出全部站点
+func ListSites(c *gin.Context) {
+	sites, err := model.ListSites()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询站点失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "获取站点成功", sites)
+}
+
+// CreateSite 登记站点
+func CreateSite(c *gin.Context) {
+	var req SiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	site := &model.Site{}
+	if err := applySiteRequest(site, &req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := model.CreateSite(site); err != nil {
+		utils.SendError(c, http.StatusConflict, "登记站点失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "登记站点成功", site)
+}
+
+// UpdateSite 更新站点设置
+func UpdateSite(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的站点ID")
+		return
+	}
+
+	var req SiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	site, err := model.GetSiteByID(uri.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "站点不存在")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "查询站点失败: "+err.Error())
+		return
+	}
+
+	if err := applySiteRequest(site, &req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := model.UpdateSite(site); err != nil {
+		utils.SendError(c, http.StatusConflict, "更新站点失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "更新站点成功", site)
+}
+
+// DeleteSite 删除站点登记
+func DeleteSite(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的站点ID")
+		return
+	}
+
+	if err := model.DeleteSite(uri.ID); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "删除站点失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "删除站点成功", gin.H{"deleted": true})
+}
+
+// applySiteRequest 校验请求并写入站点字段
+func applySiteRequest(site *model.Site, req *SiteRequest) error {
+	siteID := strings.TrimSpace(req.SiteID)
+	name := strings.TrimSpace(req.Name)
+	if siteID == "" || name == "" {
+		return errors.New("站点标识和名称不能为空")
+	}
+
+	status := strings.ToLower(strings.TrimSpace(req.DefaultStatus))
+	switch status {
+	case "", "pending", "approved", "rejected":
+	default:
+		return errors.New("无效的默认评论状态")
+	}
+
+	if req.MaxCommentLength < 0 {
+		return errors.New("评论最大字符数不能为负数")
+	}
+
+	reactions := trimStringList(req.Reactions)
+	if len(reactions) > model.MaxReactionSetSize {
+		return fmt.Errorf("表情回应最多配置 %d 个", model.MaxReactionSetSize)
+	}
+	seen := make(map[string]struct{}, len(reactions))
+	for _, reaction := range reactions {
+		if len(reaction) > model.MaxReactionLength {
+			return errors.New("表情回应过长: " + reaction)
+		}
+		if _, exists := seen[reaction]; exists {
+			return errors.New("表情回应重复: " + reaction)
+		}
+		seen[reaction] = struct{}{}
+	}
+
+	origins := make(types.StringList, 0, len(req.AllowedOrigins))
+	for _, origin := range req.AllowedOrigins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" {
+			return errors.New("无效的来源地址: " + origin + "，格式应为 scheme://host[:port]")
+		}
+		origins = append(origins, origin)
+	}
+
+	site.SiteID = siteID
+	site.Name = name
+	site.AllowedOrigins = origins
+	site.DefaultStatus = status
+	site.RequireLogin = req.RequireLogin
+	site.ModerationKeywords = trimStringList(req.ModerationKeywords)
+	site.MaxCommentLength = req.MaxCommentLength
+	site.PublicFields = trimStringList(req.PublicFields)
+	site.Reactions = reactions
+	site.Disabled = req.Disabled
+	return nil
+}
+
+func trimStringList(values []string) types.StringList {
+	result := make(types.StringList, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
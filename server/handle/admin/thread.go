@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"errors"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ThreadSettingRequest struct {
+	SiteID          string `json:"siteId" binding:"required,max=100"`
+	Mark            string `json:"mark" binding:"required,max=255"`
+	Closed          bool   `json:"closed"`
+	Locked          bool   `json:"locked"`
+	AutoCloseDays   int    `json:"autoCloseDays"`
+	ForceModeration bool   `json:"forceModeration"`
+}
+
+// ListThreadSettings 分页查询页面评论设置，可按 siteId 过滤
+func ListThreadSettings(c *gin.Context) {
+	page := parsePositiveInt(c.Query("page"), 1)
+	pageSize := parsePositiveInt(c.Query("pageSize"), 20)
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	settings, total, err := model.ListThreadSettings(strings.TrimSpace(c.Query("siteId")), page, pageSize)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询页面设置失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取页面设置成功", gin.H{
+		"data":      settings,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"pageCount": int(math.Ceil(float64(total) / float64(pageSize))),
+	})
+}
+
+// SaveThreadSetting 创建或更新页面评论设置：关闭评论、锁定回复、自动关闭与强制审核
+func SaveThreadSetting(c *gin.Context) {
+	var req ThreadSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	siteID := strings.TrimSpace(req.SiteID)
+	mark := strings.TrimSpace(req.Mark)
+	if siteID == "" || mark == "" {
+		utils.SendError(c, http.StatusBadRequest, "站点标识和页面标识不能为空")
+		return
+	}
+	if req.AutoCloseDays < 0 {
+		utils.SendError(c, http.StatusBadRequest, "自动关闭天数不能为负数")
+		return
+	}
+
+	setting := &model.ThreadSetting{
+		SiteID:          siteID,
+		Mark:            mark,
+		Closed:          req.Closed,
+		Locked:          req.Locked,
+		AutoCloseDays:   req.AutoCloseDays,
+		ForceModeration: req.ForceModeration,
+	}
+	if err := model.SaveThreadSetting(setting); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "保存页面设置失败: "+err.Error())
+		return
+	}
+
+	state, err := model.GetThreadState(siteID, mark)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询页面评论设置失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "保存页面设置成功", gin.H{
+		"setting": setting,
+		"state":   state,
+	})
+}
+
+// DeleteThreadSetting 删除页面评论设置
+func DeleteThreadSetting(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的设置ID")
+		return
+	}
+
+	if err := model.DeleteThreadSetting(uri.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "页面设置不存在")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "删除页面设置失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "删除页面设置成功", gin.H{"deleted": true})
+}
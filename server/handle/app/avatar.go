@@ -0,0 +1,29 @@
+package app
+
+import (
+	"encoding/hex"
+	"marku-server/config"
+	"marku-server/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Identicon 返回根据邮箱摘要生成的 SVG 头像，地址中只包含摘要，不暴露邮箱
+func Identicon(c *gin.Context) {
+	hash := strings.ToLower(strings.TrimSuffix(c.Param("hash"), ".svg"))
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) < 32 || len(hash) > 64 {
+		utils.SendError(c, http.StatusBadRequest, "无效的头像标识")
+		return
+	}
+
+	size := config.GetAvatarConfig().Size
+	if value, err := strconv.Atoi(c.Query("s")); err == nil && value > 0 {
+		size = value
+	}
+
+	c.Header("Cache-Control", "public, max-age=604800, immutable")
+	c.Data(http.StatusOK, "image/svg+xml", utils.GenerateIdenticon(hash, size))
+}
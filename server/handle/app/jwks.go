@@ -0,0 +1,14 @@
+package app
+
+import (
+	"marku-server/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 公开访问令牌的校验公钥，供其他服务校验 Marku 令牌
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.GetJWKS()})
+}
@@ -0,0 +1,45 @@
+package comment
+
+import (
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单次最多查询的页面数
+const maxBatchCountMarks = 200
+
+// BatchCommentCountRequest 批量查询评论数请求结构
+type BatchCommentCountRequest struct {
+	SiteID         string   `json:"siteId" binding:"required"`
+	Marks          []string `json:"marks" binding:"required"`
+	IncludeReplies bool     `json:"includeReplies"` // 同时返回回复数
+	IncludeLatest  bool     `json:"includeLatest"`  // 同时返回最新评论时间
+}
+
+// BatchCommentCounts 批量查询多个页面的已通过评论数，用于文章列表页
+func BatchCommentCounts(c *gin.Context) {
+	var req BatchCommentCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+	if len(req.Marks) > maxBatchCountMarks {
+		utils.SendError(c, http.StatusBadRequest, "单次最多查询 200 个页面")
+		return
+	}
+
+	if _, ok := middleware.ResolveSite(c, req.SiteID, model.ScopeCommentsRead); !ok {
+		return
+	}
+
+	counts, err := model.BatchCountApprovedComments(req.SiteID, req.Marks, req.IncludeReplies, req.IncludeLatest)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "统计评论数量失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "获取评论数成功", counts)
+}
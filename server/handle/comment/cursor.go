@@ -0,0 +1,115 @@
+package comment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"marku-server/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 游标翻页方向
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+// errInvalidCursor 游标无法解析或与当前排序方式不一致
+var errInvalidCursor = errors.New("无效的分页游标")
+
+// commentCursor 按 (created_at, id) 定位的分页游标，对外以 base64 编码传递，客户端不应解析其内容
+type commentCursor struct {
+	CreatedAt int64  `json:"t"` // 创建时间，Unix 纳秒
+	ID        uint   `json:"i"`
+	Direction string `json:"d"`
+	Sort      string `json:"s"`
+}
+
+func encodeCursor(comment model.Comment, direction, sort string) string {
+	data, _ := json.Marshal(commentCursor{
+		CreatedAt: comment.CreatedAt.UnixNano(),
+		ID:        comment.ID,
+		Direction: direction,
+		Sort:      sort,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标，空字符串表示从第一页开始
+func decodeCursor(value, sort string) (*commentCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor commentCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errInvalidCursor
+	}
+	if cursor.Sort != sort || (cursor.Direction != cursorNext && cursor.Direction != cursorPrev) {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// findCommentsByCursor 按游标查询一页评论，返回结果按 sort 排列，并给出前后两个方向的游标
+func findCommentsByCursor(db *gorm.DB, cursor *commentCursor, sort string, pageSize int) ([]model.Comment, string, string, error) {
+	// newest 为倒序；向前翻页时反转比较方向与排序，查询后再把结果倒回来
+	descending := sort != "oldest"
+	backward := cursor != nil && cursor.Direction == cursorPrev
+	if backward {
+		descending = !descending
+	}
+
+	query := db
+	if cursor != nil {
+		createdAt := time.Unix(0, cursor.CreatedAt)
+		operator := ">"
+		if descending {
+			operator = "<"
+		}
+		query = query.Where("created_at "+operator+" ? OR (created_at = ? AND id "+operator+" ?)", createdAt, createdAt, cursor.ID)
+	}
+	if descending {
+		query = query.Order("created_at DESC, id DESC")
+	} else {
+		query = query.Order("created_at ASC, id ASC")
+	}
+
+	var comments []model.Comment
+	if err := query.Limit(pageSize + 1).Find(&comments).Error; err != nil {
+		return nil, "", "", err
+	}
+
+	hasMore := len(comments) > pageSize
+	if hasMore {
+		comments = comments[:pageSize]
+	}
+	if backward {
+		for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+			comments[i], comments[j] = comments[j], comments[i]
+		}
+	}
+	if len(comments) == 0 {
+		return comments, "", "", nil
+	}
+
+	// 沿当前方向是否还有数据由多查的一条判断；反方向只要是经游标翻页而来就认为存在
+	hasNext, hasPrev := hasMore, cursor != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	var nextCursor, prevCursor string
+	if hasNext {
+		nextCursor = encodeCursor(comments[len(comments)-1], cursorNext, sort)
+	}
+	if hasPrev {
+		prevCursor = encodeCursor(comments[0], cursorPrev, sort)
+	}
+	return comments, nextCursor, prevCursor, nil
+}
@@ -0,0 +1,145 @@
+package comment
+
+import (
+	"encoding/base64"
+	"errors"
+	"marku-server/internal/testutil"
+	"marku-server/model"
+	"testing"
+	"time"
+)
+
+// seedCursorComments 创建 7 条评论，其中两组的 created_at 完全相同，返回按创建顺序排列的ID
+func seedCursorComments(t *testing.T) []uint {
+	t.Helper()
+	testutil.SetupDB(t, &model.DB, &model.Comment{})
+	base := time.Date(2026, 1, 1, 12, 0, 0, 123456000, time.UTC)
+	offsets := []time.Duration{0, time.Minute, time.Minute, time.Minute, 2 * time.Minute, 2 * time.Minute, 3 * time.Minute}
+
+	ids := make([]uint, 0, len(offsets))
+	for _, offset := range offsets {
+		comment := model.Comment{SiteID: "blog", Mark: "/post", Content: "hi", Status: 1}
+		comment.CreatedAt = base.Add(offset)
+		if err := model.DB.Create(&comment).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, comment.ID)
+	}
+	return ids
+}
+
+// cursorPage 查询一页并返回评论ID与前后游标
+func cursorPage(t *testing.T, cursorValue, sort string) ([]uint, string, string) {
+	t.Helper()
+	cursor, err := decodeCursor(cursorValue, sort)
+	if err != nil {
+		t.Fatalf("decode %q: %v", cursorValue, err)
+	}
+	comments, next, prev, err := findCommentsByCursor(model.DB.Model(&model.Comment{}), cursor, sort, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	return ids, next, prev
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCursorPaginationWithTies(t *testing.T) {
+	ids := seedCursorComments(t)
+
+	tests := []struct {
+		sort string
+		want []uint
+	}{
+		// (created_at, id) 倒序：相同时间的评论按 id 倒序排列
+		{"newest", []uint{ids[6], ids[5], ids[4], ids[3], ids[2], ids[1], ids[0]}},
+		{"oldest", ids},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			// 向后翻页直至末页，相同时间的评论既不重复也不遗漏
+			var pages [][]uint
+			var cursors []string
+			var got []uint
+			cursor := ""
+			for {
+				page, next, prev := cursorPage(t, cursor, tt.sort)
+				if len(pages) == 0 && prev != "" {
+					t.Fatal("first page has a previous cursor")
+				}
+				pages = append(pages, page)
+				cursors = append(cursors, prev)
+				got = append(got, page...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if !equalIDs(got, tt.want) {
+				t.Fatalf("forward pages %v, want %v", got, tt.want)
+			}
+
+			// 从末页逐页向前翻，每页内容与向后翻页时一致
+			cursor = cursors[len(cursors)-1]
+			for i := len(pages) - 2; i >= 0; i-- {
+				page, next, prev := cursorPage(t, cursor, tt.sort)
+				if !equalIDs(page, pages[i]) {
+					t.Fatalf("backward page %d = %v, want %v", i, page, pages[i])
+				}
+				if next == "" {
+					t.Fatalf("backward page %d has no next cursor", i)
+				}
+				if i == 0 {
+					if prev != "" {
+						t.Fatal("first page reached backwards still has a previous cursor")
+					}
+					break
+				}
+				cursor = prev
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	comment := model.Comment{ID: 3}
+	comment.CreatedAt = time.Now()
+
+	if cursor, err := decodeCursor("", "newest"); cursor != nil || err != nil {
+		t.Fatalf("empty cursor: %v %v", cursor, err)
+	}
+	cursor, err := decodeCursor(encodeCursor(comment, cursorPrev, "oldest"), "oldest")
+	if err != nil || cursor.ID != 3 || cursor.Direction != cursorPrev || cursor.CreatedAt != comment.CreatedAt.UnixNano() {
+		t.Fatalf("round trip: %+v %v", cursor, err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"sort mismatch", encodeCursor(comment, cursorNext, "newest")},
+		{"malformed base64", "!!!not-base64"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"t":1,"i":1,"d":"next","s":"oldest"}`))},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("created_at=1"))},
+		{"unknown direction", base64.RawURLEncoding.EncodeToString([]byte(`{"t":1,"i":1,"d":"up","s":"oldest"}`))},
+	}
+	for _, tt := range tests {
+		if _, err := decodeCursor(tt.value, "oldest"); !errors.Is(err, errInvalidCursor) {
+			t.Errorf("%s: err = %v, want errInvalidCursor", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package comment
+
+import (
+	"fmt"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 侧边栏数据允许的延迟，新评论最迟在该时长后出现
+	feedCacheTTL       = time.Minute
+	feedMaxLimit       = 50
+	feedExcerptLength  = 100
+	topCommenterMaxAge = 365
+	// 每种侧边栏数据最多缓存的查询组合数，防止任意 siteId 撑大缓存
+	feedCacheMaxEntries = 256
+)
+
+// FeedComment 最新评论条目
+type FeedComment struct {
+	ID        uint      `json:"id"`
+	Mark      string    `json:"mark"`
+	Parent    int       `json:"parent"`
+	Excerpt   string    `json:"excerpt"`
+	Username  string    `json:"username"`
+	Avatar    *string   `json:"avatar,omitempty"`
+	URL       *string   `json:"url,omitempty"`
+	EmailHash string    `json:"email_hash,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TopCommenter 活跃评论者条目
+type TopCommenter struct {
+	Username  string    `json:"username"`
+	Avatar    *string   `json:"avatar,omitempty"`
+	URL       *string   `json:"url,omitempty"`
+	EmailHash string    `json:"email_hash,omitempty"`
+	Count     int64     `json:"count"`
+	LatestAt  time.Time `json:"latest_at"`
+}
+
+// topCommentersResult 缓存的活跃评论者统计及其最新评论
+type topCommentersResult struct {
+	stats  []model.CommenterStat
+	latest []model.Comment
+}
+
+// 缓存数据库查询结果而非响应，响应中的地址按每个请求单独生成
+var (
+	recentCommentsCache = utils.NewTTLCache[[]model.Comment](feedCacheTTL, feedCacheMaxEntries)
+	topCommentersCache  = utils.NewTTLCache[topCommentersResult](feedCacheTTL, feedCacheMaxEntries)
+)
+
+// feedCacheSiteKey 缓存键中的站点部分，已注册的站点使用规范的站点ID
+func feedCacheSiteKey(site *model.Site, siteID string) string {
+	if site != nil {
+		return site.SiteID
+	}
+	return siteID
+}
+
+// GetRecentComments 站点内最新的已通过评论，始终按公开字段裁剪作者信息
+func GetRecentComments(c *gin.Context) {
+	siteID := strings.TrimSpace(c.Query("siteId"))
+	if siteID == "" {
+		utils.SendError(c, http.StatusBadRequest, "siteId 参数必需")
+		return
+	}
+	site, ok := middleware.ResolveSite(c, siteID, model.ScopeCommentsRead)
+	if !ok {
+		return
+	}
+
+	limit := parsePositiveInt(c.Query("limit"), 10)
+	if limit > feedMaxLimit {
+		limit = feedMaxLimit
+	}
+
+	cacheKey := fmt.Sprintf("%s|%d", feedCacheSiteKey(site, siteID), limit)
+	comments, ok := recentCommentsCache.Get(cacheKey)
+	if !ok {
+		var err error
+		comments, err = model.ListRecentApprovedComments(siteID, "", limit)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "查询最新评论失败: "+err.Error())
+			return
+		}
+		recentCommentsCache.Set(cacheKey, comments)
+	}
+
+	publicFields := publicFieldSet(site.CommentPublicFields(siteID))
+	items := make([]FeedComment, 0, len(comments))
+	for i, response := range buildCommentResponses(c, comments) {
+		projectPublicComment(&response, publicFields)
+		items = append(items, FeedComment{
+			ID:        response.ID,
+			Mark:      response.Mark,
+			Parent:    response.Parent,
+			Excerpt:   commentExcerpt(response.Content),
+			Username:  response.Username,
+			Avatar:    response.Avatar,
+			URL:       response.URL,
+			EmailHash: response.EmailHash,
+			CreatedAt: comments[i].CreatedAt,
+		})
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取最新评论成功", items)
+}
+
+// GetTopCommenters 站点内最近 days 天评论最多的评论者，days=0 时统计全部时间
+func GetTopCommenters(c *gin.Context) {
+	siteID := strings.TrimSpace(c.Query("siteId"))
+	if siteID == "" {
+		utils.SendError(c, http.StatusBadRequest, "siteId 参数必需")
+		return
+	}
+	site, ok := middleware.ResolveSite(c, siteID, model.ScopeCommentsRead)
+	if !ok {
+		return
+	}
+
+	limit := parsePositiveInt(c.Query("limit"), 10)
+	if limit > feedMaxLimit {
+		limit = feedMaxLimit
+	}
+	days := 30
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > topCommenterMaxAge {
+			utils.SendError(c, http.StatusBadRequest, fmt.Sprintf("days 参数应为 0 到 %d 之间的整数", topCommenterMaxAge))
+			return
+		}
+		days = parsed
+	}
+
+	cacheKey := fmt.Sprintf("%s|%d|%d", feedCacheSiteKey(site, siteID), limit, days)
+	result, ok := topCommentersCache.Get(cacheKey)
+	if !ok {
+		var since time.Time
+		if days > 0 {
+			since = time.Now().AddDate(0, 0, -days)
+		}
+		stats, err := model.ListTopCommenters(siteID, since, limit)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "统计活跃评论者失败: "+err.Error())
+			return
+		}
+
+		latestIDs := make([]uint, 0, len(stats))
+		for _, stat := range stats {
+			latestIDs = append(latestIDs, stat.LatestID)
+		}
+		latest, err := model.GetCommentsByIDs(latestIDs)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+			return
+		}
+		result = topCommentersResult{stats: stats, latest: latest}
+		topCommentersCache.Set(cacheKey, result)
+	}
+	stats, latest := result.stats, result.latest
+
+	// 展示信息取自每位评论者最新的一条评论
+	publicFields := publicFieldSet(site.CommentPublicFields(siteID))
+	responses := buildCommentResponses(c, latest)
+	byID := make(map[uint]int, len(latest))
+	for i := range responses {
+		projectPublicComment(&responses[i], publicFields)
+		byID[responses[i].ID] = i
+	}
+
+	items := make([]TopCommenter, 0, len(stats))
+	for _, stat := range stats {
+		index, ok := byID[stat.LatestID]
+		if !ok {
+			continue
+		}
+		response := responses[index]
+		items = append(items, TopCommenter{
+			Username:  response.Username,
+			Avatar:    response.Avatar,
+			URL:       response.URL,
+			EmailHash: response.EmailHash,
+			Count:     stat.Count,
+			LatestAt:  latest[index].CreatedAt,
+		})
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取活跃评论者成功", items)
+}
+
+// commentExcerpt 截取评论摘要，合并连续空白
+func commentExcerpt(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(content) <= feedExcerptLength {
+		return content
+	}
+	runes := []rune(content)
+	return string(runes[:feedExcerptLength]) + "…"
+}
@@ -3,6 +3,7 @@ package comment
 import (
 	"math"
 	"marku-server/config"
+	"marku-server/middleware"
 	"marku-server/model"
 	"marku-server/utils"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type UserResponse struct {
@@ -18,6 +20,8 @@ type UserResponse struct {
 	Email    *string `json:"email,omitempty"`
 	URL      *string `json:"url,omitempty"`
 	Avatar   *string `json:"avatar,omitempty"`
+	// 邮箱摘要，非管理员只能拿到摘要
+	EmailHash string `json:"email_hash,omitempty"`
 }
 
 type CommentResponse struct {
@@ -25,10 +29,23 @@ type CommentResponse struct {
 	SiteID    string  `json:"site_id"`
 	Mark      string  `json:"mark"`
 	Featured  bool    `json:"featured"`
+	Pinned    bool    `json:"pinned"`
 	Content   string  `json:"content"`
+	// 转义后的评论内容，已解析的 @提及 渲染为链接或 span
+	ContentHTML string            `json:"content_html"`
+	Mentions    []MentionResponse `json:"mentions,omitempty"`
+	// 表情回应数量与当前访客自己的回应
+	Reactions   []model.ReactionCount `json:"reactions,omitempty"`
+	MyReactions []string              `json:"my_reactions,omitempty"`
 	IP        *string `json:"ip,omitempty"`
 	Location  *string `json:"location,omitempty"`
 	UA        *string `json:"ua,omitempty"`
+	// 由 UA 解析得到的浏览器、系统与设备类型
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device,omitempty"`
 	Parent    int     `json:"parent"`
 	Status    int     `json:"status"`
 	Up        int     `json:"up"`
@@ -42,6 +59,15 @@ type CommentResponse struct {
 	Email    *string `json:"email,omitempty"`
 	URL      *string `json:"url,omitempty"`
 	Avatar   *string `json:"avatar,omitempty"`
+	EmailHash string `json:"email_hash,omitempty"`
+}
+
+// 评论列表排序方式，top 按赞踩差值排序；均以 id 兜底，保证分数或时间相同的评论在分页间顺序稳定
+var commentSortOrders = map[string]string{
+	"":       "created_at DESC, id DESC",
+	"newest": "created_at DESC, id DESC",
+	"oldest": "created_at ASC, id ASC",
+	"top":    "up - down DESC, created_at DESC, id DESC",
 }
 
 // GetComments 获取评论列表
@@ -54,32 +80,145 @@ func GetComments(c *gin.Context) {
 		return
 	}
 
+	site, ok := middleware.ResolveSite(c, siteId, model.ScopeCommentsRead)
+	if !ok {
+		return
+	}
+
 	page := parsePositiveInt(c.Query("page"), 1)
 	pageSize := parsePositiveInt(c.Query("pageSize"), 10)
 	if pageSize > 100 {
 		pageSize = 100
 	}
 
-	// 支持可选查询参数 includePending=1 用于包含未审核评论（便于测试）
-	includePending := c.Query("includePending") == "1"
-	db := model.DB.Where("site_id = ? AND mark = ?", siteId, key)
+	sort := strings.ToLower(strings.TrimSpace(c.Query("sort")))
+	order, ok := commentSortOrders[sort]
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "无效的排序方式，可选值为 newest、oldest、top")
+		return
+	}
+
+	// 携带 cursor 参数（首页可为空）时使用游标分页，按 (created_at, id) 定位，不受新评论插入影响
+	cursorValue, cursorMode := c.GetQuery("cursor")
+	var cursor *commentCursor
+	if cursorMode {
+		if sort == "top" {
+			utils.SendError(c, http.StatusBadRequest, "游标分页仅支持 newest、oldest 排序")
+			return
+		}
+		if sort == "" {
+			sort = "newest"
+		}
+		var err error
+		if cursor, err = decodeCursor(strings.TrimSpace(cursorValue), sort); err != nil {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// 可选查询参数 includePending=1 用于包含未审核评论，仅对有审核权限的调用方生效
+	includePending := c.Query("includePending") == "1" && canModerate(c, siteId)
+	db := model.DB.Model(&model.Comment{}).Where("site_id = ? AND mark = ?", siteId, key)
 	if !includePending {
 		db = db.Where("status = ?", config.GetApprovedCommentStatusValue())
 	}
+	// featured=1 时只返回精选评论
+	if c.Query("featured") == "1" {
+		db = db.Where("featured = ?", true)
+	}
+	db = db.Session(&gorm.Session{})
 
-	var total int64
-	if err := db.Model(&model.Comment{}).Count(&total).Error; err != nil {
-		utils.SendError(c, http.StatusInternalServerError, "统计评论数量失败: "+err.Error())
-		return
+	// 游标分页默认不统计总数，withTotal=1 时才返回
+	withTotal := !cursorMode || c.Query("withTotal") == "1"
+	var pinnedTotal, regularTotal int64
+	if withTotal {
+		if err := db.Where("pinned = ?", true).Count(&pinnedTotal).Error; err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "统计评论数量失败: "+err.Error())
+			return
+		}
+		if err := db.Where("pinned = ?", false).Count(&regularTotal).Error; err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "统计评论数量失败: "+err.Error())
+			return
+		}
 	}
+	total := pinnedTotal + regularTotal
 
+	// 置顶评论不参与分页，固定显示在第一页顶部
 	var comments []model.Comment
-	offset := (page - 1) * pageSize
-	if err := db.Order("created_at DESC").Limit(pageSize).Offset(offset).Find(&comments).Error; err != nil {
-		utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+	if (cursorMode && cursor == nil) || (!cursorMode && page == 1) {
+		if err := db.Where("pinned = ?", true).Order("pin_order ASC, created_at DESC, id DESC").Find(&comments).Error; err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+			return
+		}
+	}
+
+	var regular []model.Comment
+	var nextCursor, prevCursor string
+	if cursorMode {
+		var err error
+		regular, nextCursor, prevCursor, err = findCommentsByCursor(db.Where("pinned = ?", false), cursor, sort, pageSize)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+			return
+		}
+	} else {
+		offset := (page - 1) * pageSize
+		if err := db.Where("pinned = ?", false).Order(order).Limit(pageSize).Offset(offset).Find(&regular).Error; err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+			return
+		}
+	}
+	comments = append(comments, regular...)
+
+	responses := buildCommentResponses(c, comments)
+
+	// 非管理员只能看到站点配置公开的作者字段，拥有审核权限的 API 密钥视同管理员
+	if !canModerate(c, siteId) {
+		publicFields := publicFieldSet(site.CommentPublicFields(siteId))
+		for i := range responses {
+			projectPublicComment(&responses[i], publicFields)
+		}
+	}
+
+	if err := applyCommentReactions(c, site, siteId, key, responses); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询表情回应失败: "+err.Error())
 		return
 	}
 
+	thread, err := model.GetThreadState(siteId, key)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询页面评论设置失败: "+err.Error())
+		return
+	}
+
+	response := gin.H{
+		"data":     responses,
+		"pageSize": pageSize,
+		"thread":   thread,
+		// 站点可用的表情回应
+		"reactionSet": site.ReactionSet(),
+	}
+	if cursorMode {
+		response["nextCursor"] = nextCursor
+		response["prevCursor"] = prevCursor
+		if withTotal {
+			response["total"] = total
+		}
+	} else {
+		pageCount := int(math.Ceil(float64(regularTotal) / float64(pageSize)))
+		if pageCount == 0 && pinnedTotal > 0 {
+			pageCount = 1
+		}
+		response["total"] = total
+		response["page"] = page
+		response["pageCount"] = pageCount
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取评论成功", response)
+}
+
+// buildCommentResponses 将评论转换为响应结构：登录用户的评论用 users 表补全作者信息，缺少头像时按邮箱解析
+func buildCommentResponses(c *gin.Context, comments []model.Comment) []CommentResponse {
 	userIDs := make([]uint, 0, len(comments))
 	userIDSet := make(map[uint]struct{}, len(comments))
 	for _, comment := range comments {
@@ -106,6 +245,7 @@ func GetComments(c *gin.Context) {
 	}
 
 	// 构建响应数据，包含用户信息
+	baseURL := utils.RequestBaseURL(c)
 	responses := make([]CommentResponse, 0)
 	for _, comment := range comments {
 		// 优先使用评论快照字段；登录用户则用 users 表补缺
@@ -138,16 +278,34 @@ func GetComments(c *gin.Context) {
 		if username == "" {
 			username = "匿名用户"
 		}
+		// 未提供头像时由服务端按邮箱摘要解析，前端无需拿到邮箱
+		if avatar == nil {
+			avatarEmail := ""
+			if email != nil {
+				avatarEmail = *email
+			}
+			resolved := utils.ResolveAvatarURL(avatarEmail, username, baseURL)
+			avatar = &resolved
+		}
+		if userInfo != nil && userInfo.Avatar == nil {
+			userInfo.Avatar = avatar
+		}
 
 		responses = append(responses, CommentResponse{
 			ID:        comment.ID,
 			SiteID:    comment.SiteID,
 			Mark:      comment.Mark,
 			Featured:  comment.Featured,
+			Pinned:    comment.Pinned,
 			Content:   comment.Content,
 			IP:        comment.IP,
 			Location:  comment.Location,
 			UA:        comment.UA,
+			Browser:        comment.Browser,
+			BrowserVersion: comment.BrowserVersion,
+			OS:             comment.OS,
+			OSVersion:      comment.OSVersion,
+			Device:         comment.Device,
 			Parent:    comment.Parent,
 			Status:    comment.Status,
 			Up:        comment.Up,
@@ -162,19 +320,8 @@ func GetComments(c *gin.Context) {
 			Avatar:    avatar,
 		})
 	}
-
-	pageCount := 0
-	if pageSize > 0 {
-		pageCount = int(math.Ceil(float64(total) / float64(pageSize)))
-	}
-
-	utils.SendResponse(c, http.StatusOK, "获取评论成功", gin.H{
-		"data":      responses,
-		"total":     total,
-		"page":      page,
-		"pageSize":  pageSize,
-		"pageCount": pageCount,
-	})
+	applyCommentContent(responses, baseURL)
+	return responses
 }
 
 func parsePositiveInt(value string, fallback int) int {
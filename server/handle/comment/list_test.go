@@ -0,0 +1,89 @@
+package comment
+
+import (
+	"encoding/json"
+	"fmt"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIncludePendingRequiresModerator(t *testing.T) {
+	testutil.SetupDB(t, &model.DB, &model.Comment{}, &model.Site{}, &model.ThreadSetting{}, &model.Reaction{},
+		&model.User{}, &model.Session{}, &model.TwoFactor{})
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "include-pending-test-app-key-0123456789"
+	testutil.UseConfig(t, cfg)
+	if err := utils.InitJWTKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, comment := range []model.Comment{
+		{SiteID: "blog", Mark: "/post", Content: "approved", Status: 1},
+		{SiteID: "blog", Mark: "/post", Content: "pending", Status: 0},
+		{SiteID: "blog", Mark: "/post", Content: "rejected", Status: -1},
+	} {
+		if err := model.DB.Create(&comment).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tokenFor := func(role int) string {
+		user := model.User{Username: fmt.Sprintf("role%d", role), Role: role}
+		if err := model.DB.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		session, _, err := model.CreateSession(user.ID, "test", "127.0.0.1", "go-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := utils.GenerateAuthToken(user.ID, session.ID, user.Role, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	router := gin.New()
+	router.GET("/comment/list", middleware.OptionalAuth(), GetComments)
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"anonymous", "", 1},
+		{"regular user", tokenFor(types.RoleUser), 1},
+		{"admin", tokenFor(types.RoleAdmin), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/comment/list?siteId=blog&key=/post&includePending=1", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			var response struct {
+				Data struct {
+					Data []CommentResponse `json:"data"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response %q", recorder.Body.String())
+			}
+			if got := len(response.Data.Data); got != tt.want {
+				t.Fatalf("got %d comments, want %d: %s", got, tt.want, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package comment
+
+import (
+	"fmt"
+	"log"
+	"marku-server/config"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// MentionResponse 评论中已解析的提及
+type MentionResponse struct {
+	Username  string `json:"username"`
+	CommentID uint   `json:"comment_id,omitempty"` // 被提及者在同页面的最近一条评论
+}
+
+// 提及邮件中引用的评论内容长度
+const mentionExcerptLength = 200
+
+// saveCommentMentions 解析并保存评论中的 @提及，失败时只记录日志，不影响评论提交
+func saveCommentMentions(comment *model.Comment) {
+	mentions, err := model.ResolveMentions(comment.SiteID, comment.Mark, utils.ParseMentions(comment.Content))
+	if err != nil {
+		log.Printf("解析评论 %d 的提及失败: %v", comment.ID, err)
+		return
+	}
+	if err := model.CreateMentions(comment.ID, mentions); err != nil {
+		log.Printf("保存评论 %d 的提及失败: %v", comment.ID, err)
+	}
+}
+
+// notifyCommentMentions 异步向被提及的注册用户发送邮件；仅在评论通过审核后发送，每条提及只处理一次
+func notifyCommentMentions(comment model.Comment, baseURL string) {
+	if comment.Status != config.GetApprovedCommentStatusValue() {
+		return
+	}
+	smtpConfig := config.GetSMTPConfig()
+	if smtpConfig == nil || !smtpConfig.Enabled {
+		return
+	}
+
+	go func() {
+		mentions, users, err := model.ListPendingMentionNotifications(&comment)
+		if err != nil {
+			log.Printf("查询评论 %d 的提及通知失败: %v", comment.ID, err)
+			return
+		}
+
+		for _, mention := range mentions {
+			// 已退订或没有邮箱的用户同样标记为已处理，避免重新审核时补发
+			if user, ok := users[mention.UserID]; ok {
+				subject, body, err := buildMentionEmailContent(&comment, user.ID, baseURL)
+				if err == nil {
+					err = utils.SendSMTPEmail(
+						smtpConfig.Host,
+						smtpConfig.Port,
+						smtpConfig.Username,
+						smtpConfig.Password,
+						smtpConfig.From,
+						smtpConfig.SenderName,
+						smtpConfig.Security,
+						smtpConfig.SkipVerify,
+						[]string{*user.Email},
+						subject,
+						body,
+					)
+				}
+				if err != nil {
+					log.Printf("发送提及通知给用户 %d 失败: %v", user.ID, err)
+					continue
+				}
+			}
+			if err := model.MarkMentionNotified(mention.ID); err != nil {
+				log.Printf("更新提及 %d 的通知状态失败: %v", mention.ID, err)
+			}
+		}
+	}()
+}
+
+func buildMentionEmailContent(comment *model.Comment, userID uint, baseURL string) (string, string, error) {
+	token, err := utils.GenerateUnsubscribeToken(userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	author := strings.TrimSpace(comment.Username)
+	if author == "" {
+		author = "匿名用户"
+	}
+	excerpt := comment.Content
+	if utf8.RuneCountInString(excerpt) > mentionExcerptLength {
+		excerpt = string([]rune(excerpt)[:mentionExcerptLength]) + "…"
+	}
+	unsubscribeURL := baseURL + "/api/user/mentions/unsubscribe?token=" + url.QueryEscape(token)
+
+	subject := fmt.Sprintf("%s 在评论中提到了你", author)
+	body := fmt.Sprintf("%s 在页面 %s 的评论中提到了你：\n\n%s\n\n不想再收到此类邮件？打开以下链接即可退订：\n%s\n", author, comment.Mark, excerpt, unsubscribeURL)
+	return subject, body, nil
+}
@@ -0,0 +1,151 @@
+package comment
+
+import (
+	"errors"
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ModerateCommentRequest 评论审核请求结构
+type ModerateCommentRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// PinCommentRequest 评论置顶请求结构
+type PinCommentRequest struct {
+	Pinned *bool `json:"pinned" binding:"required"`
+	Order  int   `json:"order"`
+}
+
+// FeatureCommentRequest 精选评论请求结构
+type FeatureCommentRequest struct {
+	Featured *bool `json:"featured" binding:"required"`
+}
+
+// ModerateComment 修改评论审核状态，需要管理员或拥有该站点 comments:moderate 权限的 API 密钥
+func ModerateComment(c *gin.Context) {
+	var req ModerateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	switch status {
+	case "pending", "approved", "rejected":
+	default:
+		utils.SendError(c, http.StatusBadRequest, "无效的评论状态，可选值为 pending、approved、rejected")
+		return
+	}
+
+	comment, ok := loadModeratedComment(c)
+	if !ok {
+		return
+	}
+
+	if err := model.UpdateCommentStatus(comment, config.GetCommentStatusValue(status)); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "更新评论状态失败: "+err.Error())
+		return
+	}
+	// 待审核评论通过后补发提及通知
+	notifyCommentMentions(*comment, utils.RequestBaseURL(c))
+
+	utils.SendResponse(c, http.StatusOK, "评论状态已更新", gin.H{
+		"id":     comment.ID,
+		"status": comment.Status,
+	})
+}
+
+// PinComment 置顶或取消置顶评论，仅顶级评论可以置顶
+func PinComment(c *gin.Context) {
+	var req PinCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	comment, ok := loadModeratedComment(c)
+	if !ok {
+		return
+	}
+	if *req.Pinned && comment.Parent != 0 {
+		utils.SendError(c, http.StatusBadRequest, "只能置顶顶级评论")
+		return
+	}
+
+	if err := model.SetCommentPinned(comment, *req.Pinned, req.Order); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "更新置顶状态失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "置顶状态已更新", gin.H{
+		"id":        comment.ID,
+		"pinned":    comment.Pinned,
+		"pin_order": comment.PinOrder,
+	})
+}
+
+// FeatureComment 设置或取消精选评论
+func FeatureComment(c *gin.Context) {
+	var req FeatureCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	comment, ok := loadModeratedComment(c)
+	if !ok {
+		return
+	}
+
+	if err := model.SetCommentFeatured(comment, *req.Featured); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "更新精选状态失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "精选状态已更新", gin.H{
+		"id":       comment.ID,
+		"featured": comment.Featured,
+	})
+}
+
+// loadModeratedComment 按路径参数加载评论并校验当前请求的管理权限，失败时直接返回错误响应
+func loadModeratedComment(c *gin.Context) (*model.Comment, bool) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的评论ID")
+		return nil, false
+	}
+
+	comment, err := model.GetCommentByID(uri.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "评论不存在")
+			return nil, false
+		}
+		utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+		return nil, false
+	}
+
+	if !canModerate(c, comment.SiteID) {
+		utils.SendError(c, http.StatusForbidden, "没有管理该评论的权限")
+		return nil, false
+	}
+	return comment, true
+}
+
+// canModerate 当前请求是否为管理员，或携带拥有该站点审核权限的 API 密钥
+func canModerate(c *gin.Context, siteID string) bool {
+	if viewer := middleware.CurrentUser(c); viewer != nil && viewer.Role == types.RoleAdmin {
+		return !config.IsAdmin2FARequired() || model.IsTwoFactorEnabled(viewer.ID)
+	}
+	return middleware.CurrentAPIKey(c).HasScope(siteID, model.ScopeCommentsModerate)
+}
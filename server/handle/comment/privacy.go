@@ -0,0 +1,73 @@
+package comment
+
+import (
+	"marku-server/utils"
+	"strings"
+)
+
+// 可对非管理员公开的作者字段
+const (
+	publicFieldEmailHash   = "email_hash"
+	publicFieldEmailMasked = "email_masked"
+	publicFieldLocation    = "location"
+	publicFieldURL         = "url"
+	publicFieldBrowser     = "browser"
+	publicFieldUA          = "ua"
+)
+
+func publicFieldSet(publicFields []string) map[string]bool {
+	fields := make(map[string]bool)
+	for _, field := range publicFields {
+		fields[strings.ToLower(strings.TrimSpace(field))] = true
+	}
+	return fields
+}
+
+// projectPublicComment 裁剪评论中的作者隐私信息：IP 始终隐藏，邮箱只以摘要或打码形式出现，地区截取到省级
+func projectPublicComment(response *CommentResponse, fields map[string]bool) {
+	response.IP = nil
+
+	if !fields[publicFieldUA] {
+		response.UA = nil
+	}
+	if !fields[publicFieldBrowser] {
+		response.Browser, response.BrowserVersion = "", ""
+		response.OS, response.OSVersion = "", ""
+		response.Device = ""
+	}
+
+	if response.Location != nil && fields[publicFieldLocation] {
+		coarse := utils.CoarseLocation(*response.Location)
+		response.Location = &coarse
+	} else {
+		response.Location = nil
+	}
+
+	if !fields[publicFieldURL] {
+		response.URL = nil
+	}
+
+	response.EmailHash, response.Email = projectEmail(response.Email, fields)
+	if response.User != nil {
+		response.User.EmailHash, response.User.Email = projectEmail(response.User.Email, fields)
+		if !fields[publicFieldURL] {
+			response.User.URL = nil
+		}
+	}
+}
+
+func projectEmail(email *string, fields map[string]bool) (string, *string) {
+	if email == nil || strings.TrimSpace(*email) == "" {
+		return "", nil
+	}
+
+	hash := ""
+	if fields[publicFieldEmailHash] {
+		hash = utils.EmailHash(*email)
+	}
+	if fields[publicFieldEmailMasked] {
+		masked := utils.MaskEmail(*email)
+		return hash, &masked
+	}
+	return hash, nil
+}
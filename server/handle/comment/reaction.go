@@ -0,0 +1,200 @@
+package comment
+
+import (
+	"errors"
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CommentReactionRequest 评论表情回应请求结构
+type CommentReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// PageReactionRequest 页面表情回应请求结构
+type PageReactionRequest struct {
+	SiteID string `json:"siteId" binding:"required"`
+	Mark   string `json:"mark" binding:"required"`
+	Emoji  string `json:"emoji" binding:"required"`
+}
+
+// ReactionSummary 表情回应汇总与当前访客自己的回应
+type ReactionSummary struct {
+	Counts []model.ReactionCount `json:"counts"`
+	Mine   []string              `json:"mine"`
+}
+
+// ToggleCommentReaction 切换当前访客对评论的表情回应，登录用户按账户去重，游客按匿名指纹去重
+func ToggleCommentReaction(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的评论ID")
+		return
+	}
+	var req CommentReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	comment, err := model.GetCommentByID(uri.ID)
+	if err != nil || comment.Status != config.GetApprovedCommentStatusValue() {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "评论不存在")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+		return
+	}
+
+	site, ok := middleware.ResolveSite(c, comment.SiteID, model.ScopeCommentsWrite)
+	if !ok {
+		return
+	}
+	toggleReaction(c, site, comment.SiteID, comment.Mark, comment.ID, req.Emoji)
+}
+
+// TogglePageReaction 切换当前访客对页面的表情回应
+func TogglePageReaction(c *gin.Context) {
+	var req PageReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	site, ok := middleware.ResolveSite(c, req.SiteID, model.ScopeCommentsWrite)
+	if !ok {
+		return
+	}
+	toggleReaction(c, site, req.SiteID, req.Mark, 0, req.Emoji)
+}
+
+// GetPageReactions 获取页面的表情回应汇总与站点可用的表情
+func GetPageReactions(c *gin.Context) {
+	siteID := strings.TrimSpace(c.Query("siteId"))
+	mark := strings.TrimSpace(c.Query("mark"))
+	if siteID == "" || mark == "" {
+		utils.SendError(c, http.StatusBadRequest, "siteId 和 mark 参数必需")
+		return
+	}
+
+	site, ok := middleware.ResolveSite(c, siteID, model.ScopeCommentsRead)
+	if !ok {
+		return
+	}
+
+	summary, err := summarizeReactions(c, site, siteID, mark, 0, true)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询表情回应失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "获取表情回应成功", gin.H{
+		"reactionSet": site.ReactionSet(),
+		"reactions":   summary,
+	})
+}
+
+func toggleReaction(c *gin.Context, site *model.Site, siteID, mark string, commentID uint, emoji string) {
+	emoji = strings.TrimSpace(emoji)
+	if !containsString(site.ReactionSet(), emoji) {
+		utils.SendError(c, http.StatusBadRequest, "不支持的表情回应")
+		return
+	}
+
+	reacted, err := model.ToggleReaction(siteID, mark, commentID, currentReactor(c), emoji)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "更新表情回应失败: "+err.Error())
+		return
+	}
+
+	summary, err := summarizeReactions(c, site, siteID, mark, commentID, commentID == 0)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询表情回应失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "表情回应已更新", gin.H{
+		"emoji":     emoji,
+		"reacted":   reacted,
+		"reactions": summary,
+	})
+}
+
+// summarizeReactions 汇总单个评论或页面（commentID 为 0）的回应，withZero 为 true 时包含数量为 0 的表情
+func summarizeReactions(c *gin.Context, site *model.Site, siteID, mark string, commentID uint, withZero bool) (ReactionSummary, error) {
+	counts, err := model.CountReactions(siteID, mark, []uint{commentID})
+	if err != nil {
+		return ReactionSummary{}, err
+	}
+	mine, err := model.ListReactorReactions(siteID, mark, currentReactor(c), []uint{commentID})
+	if err != nil {
+		return ReactionSummary{}, err
+	}
+	return ReactionSummary{
+		Counts: orderReactionCounts(site.ReactionSet(), counts[commentID], withZero),
+		Mine:   append([]string{}, mine[commentID]...),
+	}, nil
+}
+
+// applyCommentReactions 为评论列表填充回应数量与当前访客自己的回应
+func applyCommentReactions(c *gin.Context, site *model.Site, siteID, mark string, responses []CommentResponse) error {
+	commentIDs := make([]uint, 0, len(responses))
+	for _, response := range responses {
+		commentIDs = append(commentIDs, response.ID)
+	}
+	counts, err := model.CountReactions(siteID, mark, commentIDs)
+	if err != nil {
+		return err
+	}
+	mine, err := model.ListReactorReactions(siteID, mark, currentReactor(c), commentIDs)
+	if err != nil {
+		return err
+	}
+
+	reactionSet := site.ReactionSet()
+	for i := range responses {
+		responses[i].Reactions = orderReactionCounts(reactionSet, counts[responses[i].ID], false)
+		responses[i].MyReactions = mine[responses[i].ID]
+	}
+	return nil
+}
+
+// orderReactionCounts 按站点配置的顺序排列回应数量，已从配置中移除的表情不再展示
+func orderReactionCounts(reactionSet []string, counts []model.ReactionCount, withZero bool) []model.ReactionCount {
+	byEmoji := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		byEmoji[count.Emoji] = count.Count
+	}
+
+	ordered := make([]model.ReactionCount, 0, len(reactionSet))
+	for _, emoji := range reactionSet {
+		if count := byEmoji[emoji]; count > 0 || withZero {
+			ordered = append(ordered, model.ReactionCount{Emoji: emoji, Count: count})
+		}
+	}
+	return ordered
+}
+
+// currentReactor 当前访客的回应者标识，登录用户使用账户，游客使用 IP 与 UA 计算的匿名指纹
+func currentReactor(c *gin.Context) string {
+	if user := middleware.CurrentUser(c); user != nil {
+		return model.UserReactor(user.ID)
+	}
+	return model.VisitorReactor(utils.VisitorFingerprint(c.ClientIP(), c.Request.UserAgent()))
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
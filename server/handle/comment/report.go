@@ -0,0 +1,90 @@
+package comment
+
+import (
+	"errors"
+	"log"
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportCommentRequest 举报评论请求结构
+type ReportCommentRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Detail string `json:"detail" binding:"max=500"`
+}
+
+// ReportComment 举报评论，登录用户按账户去重，游客按匿名指纹去重；
+// 不同登录用户与不同游客 IP 的未处理举报达到阈值时评论自动转为待审核
+func ReportComment(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的评论ID")
+		return
+	}
+	var req ReportCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	reason := strings.ToLower(strings.TrimSpace(req.Reason))
+	if !model.IsValidReportReason(reason) {
+		utils.SendError(c, http.StatusBadRequest, "无效的举报原因，可选值为 "+strings.Join(model.ReportReasons, "、"))
+		return
+	}
+
+	comment, err := model.GetCommentByID(uri.ID)
+	if err != nil || comment.Status != config.GetApprovedCommentStatusValue() {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "评论不存在")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+		return
+	}
+
+	if _, ok := middleware.ResolveSite(c, comment.SiteID, model.ScopeCommentsWrite); !ok {
+		return
+	}
+
+	report := model.Report{
+		CommentID: comment.ID,
+		SiteID:    comment.SiteID,
+		Reporter:  currentReactor(c),
+		Reason:    reason,
+		Detail:    strings.TrimSpace(req.Detail),
+	}
+	// 游客指纹包含 User-Agent，可随意更换，阈值改按 IP 计数
+	if middleware.CurrentUser(c) == nil {
+		report.IPHash = utils.ClientIPHash(c.ClientIP())
+	}
+	created, err := model.CreateReport(&report)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "保存举报失败: "+err.Error())
+		return
+	}
+	if !created {
+		utils.SendResponse(c, http.StatusOK, "已举报过该评论", gin.H{"reported": true})
+		return
+	}
+
+	if threshold := config.GetCommentReportThreshold(); threshold > 0 {
+		count, err := model.CountOpenReporters(comment.ID)
+		if err == nil && count >= int64(threshold) {
+			err = model.UpdateCommentStatus(comment, config.GetCommentStatusValue("pending"))
+		}
+		if err != nil {
+			log.Printf("处理评论 %d 的举报阈值失败: %v", comment.ID, err)
+		}
+	}
+
+	utils.SendResponse(c, http.StatusOK, "举报成功，感谢反馈", gin.H{"reported": true})
+}
@@ -0,0 +1,144 @@
+package comment
+
+import (
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	searchMaxQueryLength = 100
+	searchSnippetContext = 40
+)
+
+// SearchResult 搜索结果，highlight 为 HTML 转义后用 <mark> 标出匹配位置的内容片段
+type SearchResult struct {
+	CommentResponse
+	Highlight         string `json:"highlight"`
+	UsernameHighlight string `json:"username_highlight"`
+}
+
+// SearchComments 按内容与作者昵称搜索评论。
+// 公开访问必须指定 siteId，只能搜到已通过的评论且作者信息按公开字段裁剪；
+// 管理员可跨站点搜索并按 status 过滤，拥有 comments:moderate 权限的 API 密钥可在所属站点内同样操作
+func SearchComments(c *gin.Context) {
+	keyword := strings.TrimSpace(c.Query("q"))
+	if keyword == "" {
+		utils.SendError(c, http.StatusBadRequest, "q 参数必需")
+		return
+	}
+	if utf8.RuneCountInString(keyword) > searchMaxQueryLength {
+		utils.SendError(c, http.StatusBadRequest, "检索词过长")
+		return
+	}
+
+	siteID := strings.TrimSpace(c.Query("siteId"))
+	moderator := canModerate(c, siteID)
+	if siteID == "" && !moderator {
+		utils.SendError(c, http.StatusBadRequest, "siteId 参数必需")
+		return
+	}
+
+	var site *model.Site
+	if siteID != "" {
+		var ok bool
+		if site, ok = middleware.ResolveSite(c, siteID, model.ScopeCommentsRead); !ok {
+			return
+		}
+	}
+
+	params := model.CommentSearchParams{
+		Query:    keyword,
+		SiteID:   siteID,
+		Mark:     strings.TrimSpace(c.Query("mark")),
+		Page:     parsePositiveInt(c.Query("page"), 1),
+		PageSize: parsePositiveInt(c.Query("pageSize"), 20),
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+
+	approved := config.GetApprovedCommentStatusValue()
+	params.Status = &approved
+	if moderator {
+		switch status := strings.ToLower(strings.TrimSpace(c.Query("status"))); status {
+		case "", "all":
+			params.Status = nil
+		case "pending", "approved", "rejected":
+			value := config.GetCommentStatusValue(status)
+			params.Status = &value
+		default:
+			utils.SendError(c, http.StatusBadRequest, "无效的评论状态，可选值为 pending、approved、rejected、all")
+			return
+		}
+	}
+
+	var err error
+	if params.From, err = parseSearchDate(c.Query("from"), false); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "from 参数格式应为 YYYY-MM-DD 或 RFC3339")
+		return
+	}
+	if params.To, err = parseSearchDate(c.Query("to"), true); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "to 参数格式应为 YYYY-MM-DD 或 RFC3339")
+		return
+	}
+
+	comments, total, err := model.SearchComments(params)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "搜索评论失败: "+err.Error())
+		return
+	}
+
+	responses := buildCommentResponses(c, comments)
+	if !moderator {
+		publicFields := publicFieldSet(site.CommentPublicFields(siteID))
+		for i := range responses {
+			projectPublicComment(&responses[i], publicFields)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(responses))
+	for _, response := range responses {
+		results = append(results, SearchResult{
+			CommentResponse:   response,
+			Highlight:         utils.HighlightSnippet(response.Content, keyword, searchSnippetContext),
+			UsernameHighlight: utils.HighlightSnippet(response.Username, keyword, searchSnippetContext),
+		})
+	}
+
+	utils.SendResponse(c, http.StatusOK, "搜索评论成功", gin.H{
+		"data":      results,
+		"total":     total,
+		"page":      params.Page,
+		"pageSize":  params.PageSize,
+		"pageCount": int(math.Ceil(float64(total) / float64(params.PageSize))),
+		"mode":      model.GetCommentSearchMode(),
+	})
+}
+
+// parseSearchDate 解析日期参数；只给出日期且作为结束时间时包含当天
+func parseSearchDate(value string, end bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return parsed, nil
+}
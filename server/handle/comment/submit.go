@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"marku-server/config"
+	"marku-server/middleware"
 	"marku-server/model"
 	"marku-server/utils"
 	"net/http"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
@@ -78,6 +80,15 @@ func SubmitComment(c *gin.Context) {
 		return
 	}
 
+	site, ok := middleware.ResolveSite(c, req.SiteID, model.ScopeCommentsWrite)
+	if !ok {
+		return
+	}
+	if site != nil && site.MaxCommentLength > 0 && utf8.RuneCountInString(req.Content) > site.MaxCommentLength {
+		utils.SendError(c, http.StatusBadRequest, fmt.Sprintf("评论内容不能超过 %d 个字符", site.MaxCommentLength))
+		return
+	}
+
 	var user *model.User
 	authToken := strings.TrimSpace(req.Token)
 	if authToken == "" {
@@ -85,18 +96,15 @@ func SubmitComment(c *gin.Context) {
 	}
 
 	if authToken != "" {
-		userID, err := utils.ParseAuthToken(authToken)
-		if err != nil {
-			utils.SendError(c, http.StatusUnauthorized, "登录状态无效: "+err.Error())
-			return
-		}
-		user, err = model.GetUserByID(userID)
+		var err error
+		user, _, err = model.AuthenticateAccessToken(authToken)
 		if err != nil {
 			utils.SendError(c, http.StatusUnauthorized, "登录状态无效: "+err.Error())
 			return
 		}
 	} else {
-		if config.IsCommentLoginRequired() {
+		// 持有 comments:write 密钥的服务端可代用户提交评论，不受登录要求限制
+		if site.LoginRequired() && middleware.CurrentAPIKey(c) == nil {
 			utils.SendError(c, http.StatusUnauthorized, "当前评论功能需要登录后使用")
 			return
 		}
@@ -153,6 +161,16 @@ func SubmitComment(c *gin.Context) {
 		}
 	}
 
+	thread, err := model.GetThreadState(req.SiteID, req.Mark)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询页面评论设置失败: "+err.Error())
+		return
+	}
+	if err := thread.CheckNewComment(parentID); err != nil {
+		utils.SendError(c, http.StatusForbidden, err.Error())
+		return
+	}
+
 	// 创建评论
 	comment := model.Comment{
 		SiteID:   req.SiteID,
@@ -162,7 +180,7 @@ func SubmitComment(c *gin.Context) {
 		IP:       &req.IP,
 		UA:       &req.UA,
 		Location: &req.Location,
-		Status:   config.GetDefaultCommentStatusValue(),
+		Status:   site.CommentStatus(),
 		Featured: false,
 		Up:       0,
 		Down:     0,
@@ -174,12 +192,22 @@ func SubmitComment(c *gin.Context) {
 	if user != nil {
 		comment.UserID = fmt.Sprintf("%d", user.ID)
 	}
+	comment.ApplyUserAgent()
+	// 命中站点审核关键词的评论转入待审核
+	if comment.Status == config.GetApprovedCommentStatusValue() && site.NeedsModeration(req.Content) {
+		comment.Status = config.GetCommentStatusValue("pending")
+	}
+	// 页面开启强制审核时，原本直接通过的评论同样转入待审核
+	comment.Status = thread.CommentStatus(comment.Status)
 
 	// 保存到数据库
 	if err := model.DB.Create(&comment).Error; err != nil {
 		utils.SendError(c, http.StatusInternalServerError, "保存评论失败: "+err.Error())
 		return
 	}
+	saveCommentMentions(&comment)
+	linkCommentAttachments(&comment)
+	notifyCommentMentions(comment, utils.RequestBaseURL(c))
 
 	// 返回成功
 	utils.SendResponse(c, http.StatusOK, "评论提交成功", map[string]interface{}{
@@ -0,0 +1,237 @@
+package comment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 订阅源最多包含的评论条数
+const syndicationLimit = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Author    atomAuthor  `xml:"author"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// CommentAtomFeed 已通过评论的 Atom 订阅源，携带 mark 参数时只包含该页面
+func CommentAtomFeed(c *gin.Context) {
+	serveCommentFeed(c, "atom")
+}
+
+// CommentRSSFeed 已通过评论的 RSS 2.0 订阅源，携带 mark 参数时只包含该页面
+func CommentRSSFeed(c *gin.Context) {
+	serveCommentFeed(c, "rss")
+}
+
+func serveCommentFeed(c *gin.Context, format string) {
+	siteID := strings.TrimSpace(c.Query("siteId"))
+	mark := strings.TrimSpace(c.Query("mark"))
+	if siteID == "" {
+		utils.SendError(c, http.StatusBadRequest, "siteId 参数必需")
+		return
+	}
+	site, ok := middleware.ResolveSite(c, siteID, model.ScopeCommentsRead)
+	if !ok {
+		return
+	}
+
+	comments, err := model.ListRecentApprovedComments(siteID, mark, syndicationLimit)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询评论失败: "+err.Error())
+		return
+	}
+
+	// 订阅源的更新时间取条目中最晚的修改时间，没有评论时为 Unix 纪元，保证 ETag 稳定
+	updated := time.Unix(0, 0).UTC()
+	for _, comment := range comments {
+		if comment.UpdatedAt.After(updated) {
+			updated = comment.UpdatedAt.UTC()
+		}
+	}
+
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s|%s|%s|%d", format, siteID, mark, updated.UnixNano())
+	for _, comment := range comments {
+		fmt.Fprintf(digest, "|%d", comment.ID)
+	}
+	etag := `W/"` + hex.EncodeToString(digest.Sum(nil))[:32] + `"`
+	lastModified := updated.Truncate(time.Second)
+
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age=300")
+	if feedNotModified(c, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	title := siteID
+	if site != nil && site.Name != "" {
+		title = site.Name
+	}
+	if mark != "" {
+		title += " · " + mark
+	}
+	title += " 的评论"
+	selfURL := utils.RequestBaseURL(c) + c.Request.URL.RequestURI()
+
+	var body interface{}
+	contentType := "application/atom+xml; charset=utf-8"
+	if format == "atom" {
+		feedID := "urn:marku:feed:" + url.PathEscape(siteID)
+		if mark != "" {
+			feedID += ":" + url.PathEscape(mark)
+		}
+		feed := atomFeed{
+			ID:      feedID,
+			Title:   title,
+			Updated: updated.Format(time.RFC3339),
+			Link:    atomLink{Rel: "self", Href: selfURL},
+			Entries: make([]atomEntry, 0, len(comments)),
+		}
+		for _, comment := range comments {
+			feed.Entries = append(feed.Entries, atomEntry{
+				ID:        commentGUID(comment),
+				Title:     feedEntryTitle(comment),
+				Author:    atomAuthor{Name: feedAuthor(comment)},
+				Published: comment.CreatedAt.UTC().Format(time.RFC3339),
+				Updated:   comment.UpdatedAt.UTC().Format(time.RFC3339),
+				Content:   atomContent{Type: "html", Body: commentHTML(comment.Content)},
+			})
+		}
+		body = feed
+	} else {
+		contentType = "application/rss+xml; charset=utf-8"
+		feed := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:         title,
+				Link:          selfURL,
+				Description:   title,
+				LastBuildDate: updated.Format(time.RFC1123Z),
+				Items:         make([]rssItem, 0, len(comments)),
+			},
+		}
+		for _, comment := range comments {
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				Title:       feedEntryTitle(comment),
+				Description: commentHTML(comment.Content),
+				GUID:        rssGUID{IsPermaLink: "false", Value: commentGUID(comment)},
+				PubDate:     comment.CreatedAt.UTC().Format(time.RFC1123Z),
+			})
+		}
+		body = feed
+	}
+
+	data, err := xml.MarshalIndent(body, "", "  ")
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "生成订阅源失败: "+err.Error())
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), data...))
+}
+
+// feedNotModified 按 If-None-Match 与 If-Modified-Since 判断客户端缓存是否仍然有效，前者优先
+func feedNotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if since := c.GetHeader("If-Modified-Since"); since != "" {
+		if parsed, err := http.ParseTime(since); err == nil && !lastModified.After(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// commentGUID 由评论 ID 生成的稳定条目标识
+func commentGUID(comment model.Comment) string {
+	return fmt.Sprintf("urn:marku:comment:%d", comment.ID)
+}
+
+func feedAuthor(comment model.Comment) string {
+	if name := strings.TrimSpace(comment.Username); name != "" {
+		return name
+	}
+	return "匿名用户"
+}
+
+func feedEntryTitle(comment model.Comment) string {
+	return feedAuthor(comment) + " 评论了 " + comment.Mark
+}
+
+// commentHTML 将评论正文转义为 HTML，保留换行
+func commentHTML(content string) string {
+	return strings.ReplaceAll(html.EscapeString(content), "\n", "<br>")
+}
@@ -0,0 +1,151 @@
+package comment
+
+import (
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"marku-server/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupFeedTest(t *testing.T) *gin.Engine {
+	t.Helper()
+	testutil.SetupDB(t, &model.DB, &model.Comment{}, &model.Site{})
+	testutil.UseConfig(t, &config.Config{})
+
+	router := gin.New()
+	router.GET("/comment/feed.atom", CommentAtomFeed)
+	router.GET("/comment/feed.rss", CommentRSSFeed)
+	return router
+}
+
+// getFeed 请求订阅源，headers 为附加的条件请求头
+func getFeed(t *testing.T, router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestCommentFeedConditionalGet(t *testing.T) {
+	router := setupFeedTest(t)
+	const path = "/comment/feed.atom?siteId=blog&mark=/post"
+
+	approved := model.Comment{SiteID: "blog", Mark: "/post", Content: "first", Status: 1}
+	if err := model.DB.Create(&approved).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	first := getFeed(t, router, path, nil)
+	if first.Code != http.StatusOK || !strings.Contains(first.Body.String(), "first") {
+		t.Fatalf("initial request: %d %s", first.Code, first.Body.String())
+	}
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+	if !strings.HasPrefix(etag, `W/"`) || lastModified == "" {
+		t.Fatalf("missing validators: ETag %q Last-Modified %q", etag, lastModified)
+	}
+	parsedLastModified, err := http.ParseTime(lastModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"weak etag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"strong form of the weak etag", map[string]string{"If-None-Match": strings.TrimPrefix(etag, "W/")}, http.StatusNotModified},
+		{"wildcard", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"etag in a list", map[string]string{"If-None-Match": `"stale", ` + etag}, http.StatusNotModified},
+		{"stale etag", map[string]string{"If-None-Match": `W/"stale"`}, http.StatusOK},
+		// If-None-Match 存在时忽略 If-Modified-Since
+		{"stale etag with a fresh date", map[string]string{
+			"If-None-Match":     `W/"stale"`,
+			"If-Modified-Since": parsedLastModified.Add(time.Hour).Format(http.TimeFormat),
+		}, http.StatusOK},
+		{"same date", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"later date", map[string]string{"If-Modified-Since": parsedLastModified.Add(time.Hour).Format(http.TimeFormat)}, http.StatusNotModified},
+		{"earlier date", map[string]string{"If-Modified-Since": parsedLastModified.Add(-time.Second).Format(http.TimeFormat)}, http.StatusOK},
+		{"invalid date", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := getFeed(t, router, path, tt.headers)
+			if recorder.Code != tt.want {
+				t.Fatalf("status %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && recorder.Body.Len() != 0 {
+				t.Fatal("304 response has a body")
+			}
+			if recorder.Header().Get("ETag") != etag {
+				t.Fatalf("ETag %q, want %q", recorder.Header().Get("ETag"), etag)
+			}
+		})
+	}
+
+	// RSS 与 Atom 的 ETag 互不相同
+	if rss := getFeed(t, router, "/comment/feed.rss?siteId=blog&mark=/post", nil); rss.Header().Get("ETag") == etag {
+		t.Fatal("rss and atom share an ETag")
+	}
+}
+
+func TestCommentFeedETagFollowsModeration(t *testing.T) {
+	router := setupFeedTest(t)
+	const path = "/comment/feed.rss?siteId=blog"
+
+	approved := model.Comment{SiteID: "blog", Mark: "/post", Content: "first", Status: 1}
+	pending := model.Comment{SiteID: "blog", Mark: "/post", Content: "second", Status: 0}
+	for _, comment := range []*model.Comment{&approved, &pending} {
+		if err := model.DB.Create(comment).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	initial := getFeed(t, router, path, nil)
+	if strings.Contains(initial.Body.String(), "second") {
+		t.Fatal("pending comment in the feed")
+	}
+	seen := map[string]bool{initial.Header().Get("ETag"): true}
+	previous := initial.Header().Get("ETag")
+
+	steps := []struct {
+		name    string
+		comment *model.Comment
+		status  int
+	}{
+		{"approve pending", &pending, 1},
+		{"reject approved", &approved, -1},
+	}
+	for _, step := range steps {
+		if err := model.UpdateCommentStatus(step.comment, step.status); err != nil {
+			t.Fatal(err)
+		}
+		// 携带上一次的 ETag 请求，内容变化后必须返回完整响应
+		recorder := getFeed(t, router, path, map[string]string{"If-None-Match": previous})
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, want 200", step.name, recorder.Code)
+		}
+		etag := recorder.Header().Get("ETag")
+		if seen[etag] {
+			t.Fatalf("%s: ETag %q reused", step.name, etag)
+		}
+		seen[etag] = true
+		previous = etag
+	}
+
+	final := getFeed(t, router, path, nil).Body.String()
+	if strings.Contains(final, "first") || !strings.Contains(final, "second") {
+		t.Fatalf("feed after moderation: %s", final)
+	}
+}
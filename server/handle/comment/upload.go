@@ -0,0 +1,281 @@
+package comment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/storage"
+	"marku-server/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 可通过 /api/uploads 访问的存储键，包含缩略图
+var servableUploadKey = regexp.MustCompile(`^\d{4}/\d{2}/[A-Za-z0-9_-]{16,64}\.(?:jpg|png|gif)$`)
+
+// UploadImageResponse 图片上传结果
+type UploadImageResponse struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Markdown     string `json:"markdown"`
+}
+
+// uploadURL 生成存储键的对外访问地址
+func uploadURL(baseURL, key string) string {
+	if publicURL := config.GetUploadConfig().PublicURL; publicURL != "" {
+		return publicURL + "/" + key
+	}
+	return baseURL + "/api/uploads/" + key
+}
+
+// UploadImage 上传评论图片：按内容嗅探类型，重新编码去除元数据并生成缩略图
+func UploadImage(c *gin.Context) {
+	uploadConfig := config.GetUploadConfig()
+	if !uploadConfig.Enabled || storage.Default == nil {
+		utils.SendError(c, http.StatusForbidden, "图片上传未开启")
+		return
+	}
+
+	maxBytes := int64(uploadConfig.MaxSizeMB) << 20
+	// 预留表单字段的开销，文件本身的大小在读取时单独校验
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+
+	siteID := strings.TrimSpace(c.PostForm("siteId"))
+	if siteID == "" {
+		utils.SendError(c, http.StatusBadRequest, "站点ID不能为空")
+		return
+	}
+	site, ok := middleware.ResolveSite(c, siteID, model.ScopeCommentsWrite)
+	if !ok {
+		return
+	}
+	if site.LoginRequired() && middleware.CurrentUser(c) == nil && middleware.CurrentAPIKey(c) == nil {
+		utils.SendError(c, http.StatusUnauthorized, "当前评论功能需要登录后使用")
+		return
+	}
+
+	// 游客指纹包含可随意更换的 User-Agent，未登录时按 IP 限流
+	uploader := currentReactor(c)
+	uploaderIP := utils.ClientIPHash(c.ClientIP())
+	since := time.Now().Add(-time.Hour)
+	var count int64
+	var err error
+	if middleware.CurrentUser(c) != nil {
+		count, err = model.CountRecentAttachments(uploader, since)
+	} else {
+		count, err = model.CountRecentAttachmentsByIP(uploaderIP, since)
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询上传记录失败")
+		return
+	}
+	if count >= int64(uploadConfig.MaxPerHour) {
+		utils.SendError(c, http.StatusTooManyRequests, "上传过于频繁，请稍后再试")
+		return
+	}
+	unlinked, err := model.CountUnlinkedAttachmentsByIP(uploaderIP)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询上传记录失败")
+		return
+	}
+	if unlinked >= int64(uploadConfig.MaxUnlinked) {
+		utils.SendError(c, http.StatusTooManyRequests, "未使用的图片过多，请先发表评论或稍后再试")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.SendError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("图片大小不能超过 %dMB", uploadConfig.MaxSizeMB))
+			return
+		}
+		utils.SendError(c, http.StatusBadRequest, "请选择要上传的图片")
+		return
+	}
+	if fileHeader.Size > maxBytes {
+		utils.SendError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("图片大小不能超过 %dMB", uploadConfig.MaxSizeMB))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "读取图片失败")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "读取图片失败")
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		utils.SendError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("图片大小不能超过 %dMB", uploadConfig.MaxSizeMB))
+		return
+	}
+
+	processed, err := utils.ProcessImage(data, utils.ImageOptions{
+		AllowedTypes:  uploadConfig.AllowedTypes,
+		MaxPixels:     uploadConfig.MaxPixels,
+		ThumbnailSize: uploadConfig.ThumbnailSize,
+		JPEGQuality:   uploadConfig.JPEGQuality,
+	})
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "生成文件名失败")
+		return
+	}
+	prefix := time.Now().Format("2006/01") + "/" + token
+	key := prefix + processed.Ext
+	thumbnailKey := prefix + "_thumb" + processed.ThumbnailExt
+
+	ctx := c.Request.Context()
+	if err := storage.Default.Put(ctx, key, processed.Data, processed.ContentType); err != nil {
+		log.Printf("保存上传图片失败: %v", err)
+		utils.SendError(c, http.StatusInternalServerError, "保存图片失败")
+		return
+	}
+	if err := storage.Default.Put(ctx, thumbnailKey, processed.Thumbnail, processed.ThumbnailContentType); err != nil {
+		log.Printf("保存图片缩略图失败: %v", err)
+		_ = storage.Default.Delete(ctx, key)
+		utils.SendError(c, http.StatusInternalServerError, "保存图片失败")
+		return
+	}
+
+	attachment := model.Attachment{
+		SiteID:       siteID,
+		StorageKey:   key,
+		ThumbnailKey: thumbnailKey,
+		ContentType:  processed.ContentType,
+		Size:         len(processed.Data),
+		Width:        processed.Width,
+		Height:       processed.Height,
+		Uploader:     uploader,
+		UploaderIP:   uploaderIP,
+	}
+	if err := model.CreateAttachment(&attachment); err != nil {
+		_ = storage.Default.Delete(ctx, key)
+		_ = storage.Default.Delete(ctx, thumbnailKey)
+		utils.SendError(c, http.StatusInternalServerError, "保存图片记录失败")
+		return
+	}
+
+	baseURL := utils.RequestBaseURL(c)
+	imageURL := uploadURL(baseURL, key)
+	utils.SendResponse(c, http.StatusOK, "上传成功", UploadImageResponse{
+		URL:          imageURL,
+		ThumbnailURL: uploadURL(baseURL, thumbnailKey),
+		Width:        processed.Width,
+		Height:       processed.Height,
+		Markdown:     "![](" + imageURL + ")",
+	})
+}
+
+// ServeUpload 输出已上传的图片，文件名随机且内容不变，允许长期缓存
+func ServeUpload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !servableUploadKey.MatchString(key) || storage.Default == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	object, err := storage.Default.Get(c.Request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("读取上传图片 %s 失败: %v", key, err)
+		}
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer object.Body.Close()
+
+	header := c.Writer.Header()
+	header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	if object.ETag != "" {
+		header.Set("ETag", object.ETag)
+		if match := c.GetHeader("If-None-Match"); match != "" && match == object.ETag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	if !object.LastModified.IsZero() {
+		header.Set("Last-Modified", object.LastModified.UTC().Format(http.TimeFormat))
+	}
+	contentType := object.ContentType
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	if object.Size > 0 {
+		header.Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	if c.Request.Method != http.MethodHead {
+		_, _ = io.Copy(c.Writer, object.Body)
+	}
+}
+
+// linkCommentAttachments 将评论中引用的已上传图片关联到评论，失败时只记录日志
+func linkCommentAttachments(comment *model.Comment) {
+	if err := model.LinkAttachments(comment.ID, comment.SiteID, utils.ParseImageKeys(comment.Content)); err != nil {
+		log.Printf("关联评论 %d 的图片失败: %v", comment.ID, err)
+	}
+}
+
+// applyCommentContent 为评论响应填充提及列表，并渲染包含提及链接与已关联图片的 HTML 内容
+func applyCommentContent(responses []CommentResponse, baseURL string) {
+	if len(responses) == 0 {
+		return
+	}
+	commentIDs := make([]uint, 0, len(responses))
+	for _, response := range responses {
+		commentIDs = append(commentIDs, response.ID)
+	}
+	mentionsByComment, err := model.ListMentionsByCommentIDs(commentIDs)
+	if err != nil {
+		mentionsByComment = nil
+	}
+	attachmentsByComment, err := model.ListAttachmentsByCommentIDs(commentIDs)
+	if err != nil {
+		attachmentsByComment = nil
+	}
+
+	for i := range responses {
+		mentions := mentionsByComment[responses[i].ID]
+		targets := make(map[string]uint, len(mentions))
+		for _, mention := range mentions {
+			targets[mention.Username] = mention.TargetCommentID
+			responses[i].Mentions = append(responses[i].Mentions, MentionResponse{
+				Username:  mention.Username,
+				CommentID: mention.TargetCommentID,
+			})
+		}
+
+		attachments := attachmentsByComment[responses[i].ID]
+		images := make(map[string]utils.EmbeddedImage, len(attachments))
+		for key, attachment := range attachments {
+			images[key] = utils.EmbeddedImage{
+				URL:          uploadURL(baseURL, attachment.StorageKey),
+				ThumbnailURL: uploadURL(baseURL, attachment.ThumbnailKey),
+			}
+		}
+		responses[i].ContentHTML = utils.RenderCommentHTML(responses[i].Content, targets, images)
+	}
+}
@@ -1,6 +1,7 @@
 package count
 
 import (
+	"marku-server/middleware"
 	"marku-server/model"
 	"marku-server/utils"
 	"net/http"
@@ -21,6 +22,10 @@ func BatchGetCounters(c *gin.Context) {
 		return
 	}
 
+	if _, ok := middleware.ResolveSite(c, req.SiteID, model.ScopeCountersRead); !ok {
+		return
+	}
+
 	counters, err := model.BatchGetCountersByMarks(req.SiteID, req.Marks)
 	if err != nil {
 		utils.SendError(c, http.StatusInternalServerError, "Failed to query counters: "+err.Error())
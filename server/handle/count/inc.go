@@ -1,6 +1,7 @@
 package count
 
 import (
+	"marku-server/middleware"
 	"marku-server/model"
 	"marku-server/utils"
 	"net/http"
@@ -25,6 +26,10 @@ func BatchIncrementCounters(c *gin.Context) {
 		return
 	}
 
+	if _, ok := middleware.ResolveSite(c, req.SiteID, model.ScopeCountersWrite); !ok {
+		return
+	}
+
 	counters, err := model.BatchIncrementCountersByMarks(req.SiteID, req.Counters)
 	if err != nil {
 		utils.SendError(c, http.StatusInternalServerError, "Failed to increment counters: "+err.Error())
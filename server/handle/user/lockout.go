@@ -0,0 +1,68 @@
+package user
+
+import (
+	"fmt"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accountAttemptKeys 账户维度与 IP 维度的失败计数键；已找到用户时按用户ID计数，
+// 避免同一账户换用用户名或邮箱登录绕过限制
+func accountAttemptKeys(c *gin.Context, account string, user *model.User) []model.LoginAttemptKey {
+	key := "account:" + strings.ToLower(strings.TrimSpace(account))
+	if user != nil {
+		key = fmt.Sprintf("user:%d", user.ID)
+	}
+	return []model.LoginAttemptKey{
+		{Scope: model.LoginScopeAccount, Key: key},
+		ipAttemptKey(c),
+	}
+}
+
+// emailCodeAttemptKeys 邮箱验证码维度与 IP 维度的失败计数键
+func emailCodeAttemptKeys(c *gin.Context, email, purpose string) []model.LoginAttemptKey {
+	return []model.LoginAttemptKey{
+		{Scope: model.LoginScopeEmailCode, Key: strings.ToLower(strings.TrimSpace(email)) + ":" + purpose},
+		ipAttemptKey(c),
+	}
+}
+
+func ipAttemptKey(c *gin.Context) model.LoginAttemptKey {
+	return model.LoginAttemptKey{Scope: model.LoginScopeIP, Key: c.ClientIP()}
+}
+
+// rejectIfLocked 任一维度处于锁定中时返回 429，并通过 Retry-After 告知剩余秒数
+func rejectIfLocked(c *gin.Context, keys []model.LoginAttemptKey) bool {
+	lockedUntil := model.CheckLoginLockout(keys...)
+	if lockedUntil.IsZero() {
+		return false
+	}
+
+	retryAfter := int(time.Until(lockedUntil).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	utils.SendError(c, http.StatusTooManyRequests, fmt.Sprintf("尝试次数过多，请在 %d 秒后重试", retryAfter))
+	return true
+}
+
+// recordAttemptFailure 记录一次失败；计数失败不影响本次请求的错误响应
+func recordAttemptFailure(keys []model.LoginAttemptKey) {
+	_, _ = model.RecordLoginFailure(keys...)
+}
+
+// clearAttemptFailures 验证成功后清除账户或邮箱维度的计数，IP 维度保留，
+// 防止攻击者用自己的账户登录来重置 IP 计数
+func clearAttemptFailures(keys []model.LoginAttemptKey) {
+	cleared := make([]model.LoginAttemptKey, 0, len(keys))
+	for _, key := range keys {
+		if key.Scope != model.LoginScopeIP {
+			cleared = append(cleared, key)
+		}
+	}
+	_ = model.ClearLoginFailures(cleared...)
+}
@@ -0,0 +1,80 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accountModels 账户相关的全部模型，供各测试迁移
+var accountModels = []interface{}{&model.User{}, &model.Session{}, &model.Identity{}, &model.OAuthState{}, &model.TwoFactor{}, &model.RecoveryCode{},
+	&model.WebAuthnCredential{}, &model.WebAuthnChallenge{}, &model.LoginAttempt{}, &model.Site{}, &model.EmailVerificationCode{}}
+
+// useTestConfig 替换全局配置并按其 app_key 重新加载 JWT 密钥
+func useTestConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	testutil.UseConfig(t, cfg)
+	if err := utils.InitJWTKeys(); err != nil {
+		t.Fatalf("init jwt keys: %v", err)
+	}
+}
+
+// createTestUser 创建普通用户并签发绑定会话的访问令牌
+func createTestUser(t *testing.T, username string) (*model.User, string) {
+	t.Helper()
+	user := &model.User{Username: username}
+	if err := model.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	session, _, err := model.CreateSession(user.ID, "test", "127.0.0.1", "go-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := utils.GenerateAuthToken(user.ID, session.ID, user.Role, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+// testResponse 解析后的标准响应体
+type testResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// doJSON 发送 JSON 请求并解析标准响应体
+func doJSON(t *testing.T, router *gin.Engine, method, path string, body interface{}, headers map[string]string) testResponse {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	var response testResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: invalid response %q", method, path, recorder.Body.String())
+	}
+	return response
+}
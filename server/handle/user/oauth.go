@@ -0,0 +1,230 @@
+package user
+
+import (
+	"errors"
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oauthBrowserCookie 绑定授权状态与发起流程的浏览器的 Cookie
+const oauthBrowserCookie = "marku_oauth_state"
+
+var errOAuthRedirect = errors.New("不允许的跳转地址")
+
+type oauthProviderResponse struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ListOAuthProviders 列出已配置的第三方登录方式
+func ListOAuthProviders(c *gin.Context) {
+	providers := make([]oauthProviderResponse, 0)
+	if oauthConfig := config.GetOAuthConfig(); oauthConfig != nil {
+		for _, provider := range oauthConfig.Providers {
+			providers = append(providers, oauthProviderResponse{Name: provider.Name, Type: provider.Type})
+		}
+	}
+	utils.SendResponse(c, http.StatusOK, "获取登录方式成功", providers)
+}
+
+// OAuthAuthorize 跳转到第三方授权页
+func OAuthAuthorize(c *gin.Context) {
+	authURL, err := startOAuthFlow(c, 0)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthLink 为当前登录用户绑定第三方账户，返回授权页地址；
+// 前端需携带凭据（credentials: include）调用，回调时才能带回同一浏览器的 Cookie
+func OAuthLink(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	authURL, err := startOAuthFlow(c, user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "获取授权地址成功", gin.H{"url": authURL})
+}
+
+// OAuthCallback 处理第三方授权回调并签发 Marku 令牌
+func OAuthCallback(c *gin.Context) {
+	providerName := c.Param("provider")
+	browserNonce, _ := c.Cookie(oauthBrowserCookie)
+	state, err := model.ConsumeOAuthState(providerName, c.Query("state"), browserNonce)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "第三方登录失败: "+err.Error())
+		return
+	}
+	setOAuthBrowserCookie(c, "", -1)
+
+	if providerError := c.Query("error"); providerError != "" {
+		finishOAuthWithError(c, state.RedirectURL, http.StatusUnauthorized, "第三方授权被拒绝: "+providerError)
+		return
+	}
+
+	code := strings.TrimSpace(c.Query("code"))
+	if code == "" {
+		finishOAuthWithError(c, state.RedirectURL, http.StatusBadRequest, "缺少授权码")
+		return
+	}
+
+	provider, err := utils.GetOAuthProvider(providerName)
+	if err != nil {
+		finishOAuthWithError(c, state.RedirectURL, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accessToken, err := provider.Exchange(code, state.CodeVerifier, state.CallbackURL)
+	if err != nil {
+		finishOAuthWithError(c, state.RedirectURL, http.StatusBadGateway, "授权码换取令牌失败: "+err.Error())
+		return
+	}
+
+	info, err := provider.FetchUserInfo(accessToken)
+	if err != nil {
+		finishOAuthWithError(c, state.RedirectURL, http.StatusBadGateway, "获取第三方账户信息失败: "+err.Error())
+		return
+	}
+
+	user, err := model.ResolveOAuthUser(provider.Name, info, state.LinkUserID)
+	if err != nil {
+		finishOAuthWithError(c, state.RedirectURL, http.StatusConflict, "第三方账户登录失败: "+err.Error())
+		return
+	}
+
+	if state.RedirectURL == "" {
+		completeLogin(c, "登录成功", user)
+		return
+	}
+
+	// 令牌放在 URL 片段中，不会随请求发送到前端服务器
+	fragment := url.Values{}
+	fragment.Set("provider", provider.Name)
+	if model.IsTwoFactorEnabled(user.ID) {
+		challenge, expiresAt, err := utils.GenerateChallengeToken(user.ID)
+		if err != nil {
+			finishOAuthWithError(c, state.RedirectURL, http.StatusInternalServerError, "生成两步验证令牌失败: "+err.Error())
+			return
+		}
+		fragment.Set("two_factor_required", "1")
+		fragment.Set("challenge_token", challenge)
+		fragment.Set("expires_at", strconv.FormatInt(expiresAt.Unix(), 10))
+		c.Redirect(http.StatusFound, state.RedirectURL+"#"+fragment.Encode())
+		return
+	}
+
+	response, err := issueAuthTokens(c, user)
+	if err != nil {
+		finishOAuthWithError(c, state.RedirectURL, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	fragment.Set("token", response.Token)
+	fragment.Set("refresh_token", response.RefreshToken)
+	fragment.Set("expires_at", strconv.FormatInt(response.ExpiresAt.Unix(), 10))
+	c.Redirect(http.StatusFound, state.RedirectURL+"#"+fragment.Encode())
+}
+
+// ListIdentities 列出当前用户绑定的第三方账户
+func ListIdentities(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	identities, err := model.ListUserIdentities(user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询第三方账户失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "获取第三方账户成功", identities)
+}
+
+// startOAuthFlow 创建授权状态并返回授权页地址
+func startOAuthFlow(c *gin.Context, linkUserID uint) (string, error) {
+	provider, err := utils.GetOAuthProvider(c.Param("provider"))
+	if err != nil {
+		return "", err
+	}
+
+	redirectURL := strings.TrimSpace(c.Query("redirect"))
+	if redirectURL == "" {
+		if oauthConfig := config.GetOAuthConfig(); oauthConfig != nil {
+			redirectURL = strings.TrimSpace(oauthConfig.RedirectURL)
+		}
+	} else if !isAllowedOAuthRedirect(redirectURL) {
+		return "", errOAuthRedirect
+	}
+
+	callbackURL := provider.CallbackURL
+	if callbackURL == "" {
+		callbackURL = utils.RequestBaseURL(c) + "/api/oauth/" + url.PathEscape(provider.Name) + "/callback"
+	}
+
+	verifier, challenge, err := utils.NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	browserNonce, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := model.CreateOAuthState(provider.Name, verifier, callbackURL, redirectURL, browserNonce, linkUserID)
+	if err != nil {
+		return "", err
+	}
+	setOAuthBrowserCookie(c, browserNonce, int(time.Until(state.ExpiresAt)/time.Second))
+	return provider.AuthCodeURL(state.State, challenge, callbackURL), nil
+}
+
+// setOAuthBrowserCookie 写入授权流程的浏览器标识；HTTPS 下允许跨站请求写入，供前端跨站发起绑定
+func setOAuthBrowserCookie(c *gin.Context, value string, maxAge int) {
+	secure := strings.HasPrefix(utils.RequestBaseURL(c), "https://")
+	if secure {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	c.SetCookie(oauthBrowserCookie, value, maxAge, "/", "", secure, true)
+}
+
+// isAllowedOAuthRedirect 仅允许跳回配置的前端地址或 CORS 白名单中的站点，防止令牌被带到外部站点
+func isAllowedOAuthRedirect(redirectURL string) bool {
+	parsed, err := url.Parse(redirectURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return false
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+
+	if oauthConfig := config.GetOAuthConfig(); oauthConfig != nil && oauthConfig.RedirectURL != "" {
+		if configured, err := url.Parse(oauthConfig.RedirectURL); err == nil && configured.Scheme+"://"+configured.Host == origin {
+			return true
+		}
+	}
+	for _, allowedOrigin := range config.GetAllowedOrigins() {
+		if allowedOrigin == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func finishOAuthWithError(c *gin.Context, redirectURL string, status int, message string) {
+	if redirectURL == "" {
+		utils.SendError(c, status, message)
+		return
+	}
+	fragment := url.Values{}
+	fragment.Set("error", message)
+	c.Redirect(http.StatusFound, redirectURL+"#"+fragment.Encode())
+}
@@ -0,0 +1,332 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"marku-server/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockOIDCProvider 模拟 OIDC 提供方：支持端点发现，令牌端点按 S256 校验 PKCE
+type mockOIDCProvider struct {
+	server *httptest.Server
+
+	mu         sync.Mutex
+	challenges map[string]string // 授权码 -> code_challenge
+	redirects  map[string]string // 授权码 -> redirect_uri
+	exchanged  int
+	userInfo   map[string]interface{}
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+	provider := &mockOIDCProvider{challenges: map[string]string{}, redirects: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.server.URL,
+			"authorization_endpoint": provider.server.URL + "/authorize",
+			"token_endpoint":         provider.server.URL + "/token",
+			"userinfo_endpoint":      provider.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+		code := r.PostForm.Get("code")
+		challenge, ok := provider.challenges[code]
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || r.PostForm.Get("client_secret") != "secret" || r.PostForm.Get("redirect_uri") != provider.redirects[code] ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(provider.challenges, code)
+		provider.exchanged++
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access-" + code, "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer access-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(provider.userInfo)
+	})
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// authorize 模拟用户在授权页同意授权，返回提供方签发的授权码与 state
+func (p *mockOIDCProvider) authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil || !strings.HasPrefix(authURL, p.server.URL+"/authorize?") {
+		t.Fatalf("unexpected authorization url %q", authURL)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" || query.Get("state") == "" {
+		t.Fatalf("authorization url missing PKCE or state: %s", authURL)
+	}
+	code := "code-" + query.Get("state")[:8]
+	p.mu.Lock()
+	p.challenges[code] = query.Get("code_challenge")
+	p.redirects[code] = query.Get("redirect_uri")
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func setupOAuthTest(t *testing.T) (*gin.Engine, *mockOIDCProvider) {
+	t.Helper()
+	testutil.SetupDB(t, &model.DB, accountModels...)
+	provider := newMockOIDCProvider(t)
+
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "oauth-test-app-key-0123456789abcdef"
+	cfg.Site.AllowedOrigins = []string{"https://blog.example"}
+	cfg.OAuth.RedirectURL = "https://app.example/login/done"
+	cfg.OAuth.Providers = []config.OAuthProviderConfig{{
+		Name:         "mock",
+		Type:         "oidc",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Issuer:       provider.server.URL,
+	}}
+	useTestConfig(t, cfg)
+
+	router := gin.New()
+	router.GET("/api/oauth/:provider/authorize", OAuthAuthorize)
+	router.GET("/api/oauth/:provider/callback", OAuthCallback)
+	return router, provider
+}
+
+func serve(router *gin.Engine, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// browserCookie 取出授权时写入浏览器的状态 Cookie
+func browserCookie(t *testing.T, recorder *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == oauthBrowserCookie {
+			if !cookie.HttpOnly || cookie.MaxAge <= 0 {
+				t.Fatalf("state cookie not HttpOnly or already expired: %+v", cookie)
+			}
+			return cookie
+		}
+	}
+	t.Fatal("authorize did not set the state cookie")
+	return nil
+}
+
+// startLogin 发起授权并返回提供方授权码、state 与发起授权的浏览器 Cookie
+func startLogin(t *testing.T, router *gin.Engine, provider *mockOIDCProvider, redirect string) (string, string, *http.Cookie) {
+	t.Helper()
+	path := "/api/oauth/mock/authorize"
+	if redirect != "" {
+		path += "?redirect=" + url.QueryEscape(redirect)
+	}
+	recorder := serve(router, path)
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("authorize: %d %s", recorder.Code, recorder.Body.String())
+	}
+	code, state := provider.authorize(t, recorder.Header().Get("Location"))
+	return code, state, browserCookie(t, recorder)
+}
+
+// callbackFragment 以发起授权的浏览器调用回调并解析跳转地址片段中的参数
+func callbackFragment(t *testing.T, router *gin.Engine, code, state string, browser *http.Cookie) (string, url.Values) {
+	t.Helper()
+	recorder := serve(router, "/api/oauth/mock/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), browser)
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("callback: %d %s", recorder.Code, recorder.Body.String())
+	}
+	location, fragment, _ := strings.Cut(recorder.Header().Get("Location"), "#")
+	values, _ := url.ParseQuery(fragment)
+	return location, values
+}
+
+func TestOAuthLoginWithPKCE(t *testing.T) {
+	router, provider := setupOAuthTest(t)
+	provider.userInfo = map[string]interface{}{"sub": "subject-1", "preferred_username": "alice", "email": "alice@example.com", "email_verified": true}
+
+	code, state, browser := startLogin(t, router, provider, "https://blog.example/post/1")
+	location, fragment := callbackFragment(t, router, code, state, browser)
+	if location != "https://blog.example/post/1" || fragment.Get("token") == "" || fragment.Get("refresh_token") == "" {
+		t.Fatalf("unexpected redirect %s #%v", location, fragment)
+	}
+	if provider.exchanged != 1 {
+		t.Fatalf("token endpoint exchanged %d codes", provider.exchanged)
+	}
+
+	var identity model.Identity
+	if err := model.DB.Where("provider = ? AND subject = ?", "mock", "subject-1").First(&identity).Error; err != nil {
+		t.Fatalf("identity not stored: %v", err)
+	}
+}
+
+func TestOAuthCallbackRejectsWrongVerifier(t *testing.T) {
+	router, provider := setupOAuthTest(t)
+	provider.userInfo = map[string]interface{}{"sub": "subject-1"}
+
+	code, state, browser := startLogin(t, router, provider, "")
+	// 篡改服务端保存的 code_verifier，提供方的 S256 校验应当失败
+	if err := model.DB.Model(&model.OAuthState{}).Where("state = ?", state).Update("code_verifier", "forged-verifier").Error; err != nil {
+		t.Fatal(err)
+	}
+	location, fragment := callbackFragment(t, router, code, state, browser)
+	if location != "https://app.example/login/done" || fragment.Get("token") != "" || !strings.Contains(fragment.Get("error"), "换取令牌失败") {
+		t.Fatalf("unexpected redirect %s #%v", location, fragment)
+	}
+	if provider.exchanged != 0 {
+		t.Fatal("code exchanged with a wrong verifier")
+	}
+}
+
+func TestOAuthStateIsSingleUseAndExpires(t *testing.T) {
+	router, provider := setupOAuthTest(t)
+	provider.userInfo = map[string]interface{}{"sub": "subject-1"}
+
+	code, state, browser := startLogin(t, router, provider, "")
+	if _, fragment := callbackFragment(t, router, code, state, browser); fragment.Get("token") == "" {
+		t.Fatalf("first callback failed: %v", fragment)
+	}
+	replay := serve(router, "/api/oauth/mock/callback?code="+code+"&state="+state, browser)
+	if replay.Code != http.StatusOK || !strings.Contains(replay.Body.String(), "授权状态无效") {
+		t.Fatalf("replayed state: %d %s", replay.Code, replay.Body.String())
+	}
+
+	code, state, browser = startLogin(t, router, provider, "")
+	model.DB.Model(&model.OAuthState{}).Where("state = ?", state).Update("expires_at", time.Now().Add(-time.Minute))
+	expired := serve(router, "/api/oauth/mock/callback?code="+code+"&state="+state, browser)
+	if !strings.Contains(expired.Body.String(), "授权状态已过期") {
+		t.Fatalf("expired state: %d %s", expired.Code, expired.Body.String())
+	}
+
+	unknown := serve(router, "/api/oauth/mock/callback?code=x&state=never-issued")
+	if !strings.Contains(unknown.Body.String(), "授权状态无效") {
+		t.Fatalf("unknown state: %d %s", unknown.Code, unknown.Body.String())
+	}
+	if provider.exchanged != 1 {
+		t.Fatalf("token endpoint exchanged %d codes, want 1", provider.exchanged)
+	}
+}
+
+func TestOAuthCallbackRequiresInitiatingBrowser(t *testing.T) {
+	router, provider := setupOAuthTest(t)
+	provider.userInfo = map[string]interface{}{"sub": "subject-1"}
+
+	// 攻击者发起授权后把回调地址交给受害者：受害者的浏览器没有对应的 Cookie
+	code, state, browser := startLogin(t, router, provider, "")
+	_, _, otherBrowser := startLogin(t, router, provider, "")
+	for name, cookies := range map[string][]*http.Cookie{"no cookie": nil, "other browser": {otherBrowser}} {
+		recorder := serve(router, "/api/oauth/mock/callback?code="+code+"&state="+state, cookies...)
+		if !strings.Contains(recorder.Body.String(), "授权状态与当前浏览器不匹配") {
+			t.Fatalf("%s: %d %s", name, recorder.Code, recorder.Body.String())
+		}
+	}
+	if provider.exchanged != 0 {
+		t.Fatal("code exchanged for a foreign browser")
+	}
+
+	// 不匹配的回调不消耗状态，发起授权的浏览器仍可完成登录
+	if _, fragment := callbackFragment(t, router, code, state, browser); fragment.Get("token") == "" {
+		t.Fatalf("initiating browser rejected: %v", fragment)
+	}
+}
+
+func TestOAuthRedirectAllowlist(t *testing.T) {
+	router, _ := setupOAuthTest(t)
+
+	tests := []struct {
+		redirect string
+		allowed  bool
+	}{
+		{"https://blog.example/post/1", true},
+		{"https://app.example/elsewhere", true},
+		{"https://evil.example/steal", false},
+		{"https://blog.example.evil.example/", false},
+		{"http://blog.example/post/1", false},
+		{"//evil.example/steal", false},
+		{"/relative/path", false},
+		{"javascript:alert(1)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.redirect, func(t *testing.T) {
+			recorder := serve(router, "/api/oauth/mock/authorize?redirect="+url.QueryEscape(tt.redirect))
+			if allowed := recorder.Code == http.StatusFound; allowed != tt.allowed {
+				t.Fatalf("allowed = %v, want %v (%d %s)", allowed, tt.allowed, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestOAuthEmailMergeRequiresVerification(t *testing.T) {
+	tests := []struct {
+		name      string
+		verified  interface{}
+		wantMerge bool
+	}{
+		{"unverified", false, false},
+		{"verified missing", nil, false},
+		{"verified string", "true", true},
+		{"verified", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, provider := setupOAuthTest(t)
+			email := "alice@example.com"
+			existing := &model.User{Username: "alice", Email: &email}
+			if err := model.DB.Create(existing).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			provider.userInfo = map[string]interface{}{"sub": "subject-1", "preferred_username": "alice", "email": email}
+			if tt.verified != nil {
+				provider.userInfo["email_verified"] = tt.verified
+			}
+			code, state, browser := startLogin(t, router, provider, "")
+			if _, fragment := callbackFragment(t, router, code, state, browser); fragment.Get("token") == "" {
+				t.Fatalf("login failed: %v", fragment)
+			}
+
+			var identity model.Identity
+			if err := model.DB.Where("subject = ?", "subject-1").First(&identity).Error; err != nil {
+				t.Fatal(err)
+			}
+			if merged := identity.UserID == existing.ID; merged != tt.wantMerge {
+				t.Fatalf("merged into existing user = %v, want %v", merged, tt.wantMerge)
+			}
+			if !tt.wantMerge {
+				created, err := model.GetUserByID(identity.UserID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if created.Email != nil {
+					t.Fatalf("unverified email copied to new user: %s", *created.Email)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const passkeyTimeoutMillis = 300000
+
+type PasskeyRegisterFinishRequest struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+		AttestationObject string   `json:"attestationObject" binding:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response" binding:"required"`
+}
+
+type PasskeyLoginBeginRequest struct {
+	Account string `json:"account"`
+}
+
+type PasskeyLoginFinishRequest struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response" binding:"required"`
+}
+
+type passkeyCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// BeginPasskeyRegistration 下发通行密钥注册选项
+func BeginPasskeyRegistration(c *gin.Context) {
+	rpID, origin, status, err := resolveRelyingParty(c)
+	if err != nil {
+		utils.SendError(c, status, err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	challenge, err := model.CreateWebAuthnChallenge(model.WebAuthnCeremonyRegister, user.ID, rpID, origin)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "生成挑战失败: "+err.Error())
+		return
+	}
+
+	existing, err := model.ListUserWebAuthnCredentials(user.ID, rpID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询通行密钥失败: "+err.Error())
+		return
+	}
+
+	params := make([]gin.H, 0, len(utils.WebAuthnSupportedAlgorithms))
+	for _, alg := range utils.WebAuthnSupportedAlgorithms {
+		params = append(params, gin.H{"type": "public-key", "alg": alg})
+	}
+
+	displayName := user.Username
+	name := user.Username
+	if user.Email != nil && *user.Email != "" {
+		name = *user.Email
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取注册选项成功", gin.H{
+		"challenge": challenge.Challenge,
+		"rp":        gin.H{"id": rpID, "name": config.GetTOTPIssuer()},
+		"user": gin.H{
+			"id":          passkeyUserHandle(user.ID),
+			"name":        name,
+			"displayName": displayName,
+		},
+		"pubKeyCredParams":   params,
+		"timeout":            passkeyTimeoutMillis,
+		"attestation":        "none",
+		"excludeCredentials": toCredentialDescriptors(existing),
+		"authenticatorSelection": gin.H{
+			"residentKey":      "preferred",
+			"userVerification": "preferred",
+		},
+	})
+}
+
+// FinishPasskeyRegistration 校验注册证明并保存通行密钥
+func FinishPasskeyRegistration(c *gin.Context) {
+	var req PasskeyRegisterFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	clientDataJSON, challenge, err := consumePasskeyChallenge(model.WebAuthnCeremonyRegister, req.Response.ClientDataJSON)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥注册失败: "+err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if challenge.UserID != user.ID {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥注册失败: 挑战与当前用户不匹配")
+		return
+	}
+
+	if _, err := utils.ParseWebAuthnClientData(clientDataJSON, "webauthn.create", challenge.Challenge, challenge.Origin); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥注册失败: "+err.Error())
+		return
+	}
+
+	attestationObject, err := utils.DecodeBase64URL(req.Response.AttestationObject)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥注册失败: attestationObject 编码无效")
+		return
+	}
+
+	attestation, err := utils.ParseWebAuthnAttestation(attestationObject, clientDataJSON)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥注册失败: "+err.Error())
+		return
+	}
+	if err := utils.CheckWebAuthnRPID(attestation.AuthData, challenge.RPID); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥注册失败: "+err.Error())
+		return
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(attestation.AuthData.CredentialID)
+	if rawID, err := utils.DecodeBase64URL(req.ID); err != nil || base64.RawURLEncoding.EncodeToString(rawID) != credentialID {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥注册失败: 凭证ID不一致")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "通行密钥"
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+
+	credential := &model.WebAuthnCredential{
+		UserID:       user.ID,
+		CredentialID: credentialID,
+		PublicKey:    attestation.AuthData.PublicKey,
+		Algorithm:    attestation.KeyAlgorithm,
+		SignCount:    attestation.AuthData.SignCount,
+		AAGUID:       formatAAGUID(attestation.AuthData.AAGUID),
+		RPID:         challenge.RPID,
+		Name:         name,
+		Transports:   strings.Join(req.Response.Transports, ","),
+	}
+	if err := model.CreateWebAuthnCredential(credential); err != nil {
+		utils.SendError(c, http.StatusConflict, "通行密钥注册失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "通行密钥已登记", credential)
+}
+
+// ListPasskeys 列出当前用户的通行密钥
+func ListPasskeys(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	credentials, err := model.ListUserWebAuthnCredentials(user.ID, "")
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询通行密钥失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "获取通行密钥成功", credentials)
+}
+
+// DeletePasskey 删除当前用户的通行密钥
+func DeletePasskey(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的通行密钥ID")
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	deleted, err := model.DeleteWebAuthnCredential(user.ID, uri.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "删除通行密钥失败: "+err.Error())
+		return
+	}
+	if !deleted {
+		utils.SendError(c, http.StatusNotFound, "通行密钥不存在")
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "通行密钥已删除", gin.H{"deleted": true})
+}
+
+// BeginPasskeyLogin 下发通行密钥登录选项；未指定账户时由认证器选择可发现凭证
+func BeginPasskeyLogin(c *gin.Context) {
+	var req PasskeyLoginBeginRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+			return
+		}
+	}
+
+	rpID, origin, status, err := resolveRelyingParty(c)
+	if err != nil {
+		utils.SendError(c, status, err.Error())
+		return
+	}
+
+	userID := uint(0)
+	allowCredentials := make([]passkeyCredentialDescriptor, 0)
+	if account := strings.TrimSpace(req.Account); account != "" {
+		// 账户不存在时同样返回空列表，避免泄露账户是否注册
+		if user, err := model.FindRegisteredUserByAccount(account); err == nil {
+			userID = user.ID
+			if credentials, err := model.ListUserWebAuthnCredentials(user.ID, rpID); err == nil {
+				allowCredentials = toCredentialDescriptors(credentials)
+			}
+		}
+	}
+
+	challenge, err := model.CreateWebAuthnChallenge(model.WebAuthnCeremonyAuthenticate, userID, rpID, origin)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "生成挑战失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取登录选项成功", gin.H{
+		"challenge":        challenge.Challenge,
+		"rpId":             rpID,
+		"timeout":          passkeyTimeoutMillis,
+		"userVerification": "preferred",
+		"allowCredentials": allowCredentials,
+	})
+}
+
+// FinishPasskeyLogin 校验通行密钥断言并签发登录令牌
+func FinishPasskeyLogin(c *gin.Context) {
+	var req PasskeyLoginFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	clientDataJSON, challenge, err := consumePasskeyChallenge(model.WebAuthnCeremonyAuthenticate, req.Response.ClientDataJSON)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: "+err.Error())
+		return
+	}
+
+	rawID, err := utils.DecodeBase64URL(req.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥登录失败: 凭证ID编码无效")
+		return
+	}
+	credential, err := model.GetWebAuthnCredential(base64.RawURLEncoding.EncodeToString(rawID))
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: 凭证未登记")
+		return
+	}
+
+	if challenge.UserID != 0 && challenge.UserID != credential.UserID {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: 凭证与账户不匹配")
+		return
+	}
+	if credential.RPID != challenge.RPID {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: 凭证不属于当前站点")
+		return
+	}
+	if req.Response.UserHandle != "" {
+		userHandle, err := utils.DecodeBase64URL(req.Response.UserHandle)
+		if err != nil || string(userHandle) != strconv.FormatUint(uint64(credential.UserID), 10) {
+			utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: 用户标识不匹配")
+			return
+		}
+	}
+
+	if _, err := utils.ParseWebAuthnClientData(clientDataJSON, "webauthn.get", challenge.Challenge, challenge.Origin); err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: "+err.Error())
+		return
+	}
+
+	rawAuthData, err := utils.DecodeBase64URL(req.Response.AuthenticatorData)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥登录失败: authenticatorData 编码无效")
+		return
+	}
+	authData, err := utils.ParseWebAuthnAuthenticatorData(rawAuthData)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: "+err.Error())
+		return
+	}
+	if err := utils.CheckWebAuthnRPID(authData, challenge.RPID); err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: "+err.Error())
+		return
+	}
+
+	signature, err := utils.DecodeBase64URL(req.Response.Signature)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "通行密钥登录失败: 签名编码无效")
+		return
+	}
+	publicKey, algorithm, err := utils.ParseCOSEKey(credential.PublicKey)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "通行密钥登录失败: "+err.Error())
+		return
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signedData := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := utils.VerifyWebAuthnSignature(publicKey, algorithm, signedData, signature); err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: 签名无效")
+		return
+	}
+
+	if err := model.UpdateWebAuthnSignCount(credential, authData.SignCount); err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "通行密钥登录失败: "+err.Error())
+		return
+	}
+
+	user, err := model.GetUserByID(credential.UserID)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "用户不存在")
+		return
+	}
+
+	// 经过用户验证的通行密钥本身即为多因素凭证，无需再走两步验证
+	if authData.UserVerified() {
+		sendAuthResponse(c, "登录成功", user)
+		return
+	}
+	completeLogin(c, "登录成功", user)
+}
+
+// resolveRelyingParty 根据请求来源确定 RP ID（来源的主机名）。请求指定站点时来源必须在该站点的允许列表中，
+// 站点未限制来源或未指定站点时来源必须在全局允许列表或任一已登记站点的允许列表中
+func resolveRelyingParty(c *gin.Context) (string, string, int, error) {
+	origin := strings.TrimSpace(c.GetHeader("Origin"))
+	parsed, err := url.Parse(origin)
+	if origin == "" || err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Hostname() == "" {
+		return "", "", http.StatusBadRequest, fmt.Errorf("无法确定请求来源")
+	}
+
+	allowed := false
+	if siteID := requestSiteID(c); siteID != "" {
+		site, err := model.ResolveSite(siteID)
+		if err != nil {
+			if errors.Is(err, model.ErrUnknownSite) {
+				return "", "", http.StatusNotFound, err
+			}
+			return "", "", http.StatusServiceUnavailable, model.ErrSiteRegistryUnavailable
+		}
+		if site != nil && len(site.AllowedOrigins) > 0 {
+			if !site.AllowsOrigin(origin) && !isGlobalAllowedOrigin(origin) {
+				return "", "", http.StatusForbidden, fmt.Errorf("请求来源不在该站点的允许列表中")
+			}
+			allowed = true
+		}
+	}
+	if !allowed && !isGlobalAllowedOrigin(origin) && !model.IsOriginAllowedBySites("", origin) {
+		return "", "", http.StatusForbidden, fmt.Errorf("请求来源不在允许列表中")
+	}
+	return parsed.Hostname(), origin, http.StatusOK, nil
+}
+
+func isGlobalAllowedOrigin(origin string) bool {
+	for _, allowedOrigin := range config.GetAllowedOrigins() {
+		if allowedOrigin == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// consumePasskeyChallenge 从 clientDataJSON 中取出挑战并消费对应记录
+func consumePasskeyChallenge(ceremony, encodedClientData string) ([]byte, *model.WebAuthnChallenge, error) {
+	clientDataJSON, err := utils.DecodeBase64URL(encodedClientData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("clientDataJSON 编码无效")
+	}
+
+	var clientData utils.WebAuthnClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil || clientData.Challenge == "" {
+		return nil, nil, fmt.Errorf("clientDataJSON 无效")
+	}
+
+	challenge, err := model.ConsumeWebAuthnChallenge(ceremony, clientData.Challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientDataJSON, challenge, nil
+}
+
+func toCredentialDescriptors(credentials []model.WebAuthnCredential) []passkeyCredentialDescriptor {
+	descriptors := make([]passkeyCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptor := passkeyCredentialDescriptor{Type: "public-key", ID: credential.CredentialID}
+		if credential.Transports != "" {
+			descriptor.Transports = strings.Split(credential.Transports, ",")
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors
+}
+
+// passkeyUserHandle 用户句柄使用用户ID，不包含邮箱等个人信息
+func passkeyUserHandle(userID uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(userID), 10)))
+}
+
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	value := hex.EncodeToString(aaguid)
+	return value[0:8] + "-" + value[8:12] + "-" + value[12:16] + "-" + value[16:20] + "-" + value[20:32]
+}
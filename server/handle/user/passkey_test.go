@@ -0,0 +1,432 @@
+package user
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testOrigin = "https://blog.example"
+	testRPID   = "blog.example"
+)
+
+// softAuthenticator 软件实现的 WebAuthn 认证器，使用 none 证明
+type softAuthenticator struct {
+	alg          int
+	ecKey        *ecdsa.PrivateKey
+	edKey        ed25519.PrivateKey
+	credentialID []byte
+}
+
+func newSoftAuthenticator(t *testing.T, alg int) *softAuthenticator {
+	t.Helper()
+	a := &softAuthenticator{alg: alg, credentialID: make([]byte, 16)}
+	_, _ = rand.Read(a.credentialID)
+	var err error
+	switch alg {
+	case utils.COSEAlgES256:
+		a.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case utils.COSEAlgEdDSA:
+		_, a.edKey, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// CBOR 编码：只实现测试所需的整数、字节串、文本与映射
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+}
+
+func cborValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return cborHead(1, -1-v)
+		}
+		return cborHead(0, v)
+	case []byte:
+		return append(cborHead(2, len(v)), v...)
+	case string:
+		return append(cborHead(3, len(v)), v...)
+	case [][2]interface{}:
+		out := cborHead(5, len(v))
+		for _, pair := range v {
+			out = append(out, cborValue(pair[0])...)
+			out = append(out, cborValue(pair[1])...)
+		}
+		return out
+	}
+	panic("unsupported CBOR value")
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	if a.alg == utils.COSEAlgEdDSA {
+		return cborValue([][2]interface{}{{1, 1}, {3, utils.COSEAlgEdDSA}, {-1, 6}, {-2, []byte(a.edKey.Public().(ed25519.PublicKey))}})
+	}
+	x, y := make([]byte, 32), make([]byte, 32)
+	a.ecKey.X.FillBytes(x)
+	a.ecKey.Y.FillBytes(y)
+	return cborValue([][2]interface{}{{1, 2}, {3, utils.COSEAlgES256}, {-1, 1}, {-2, x}, {-3, y}})
+}
+
+func (a *softAuthenticator) authData(rpID string, signCount uint32, attested bool) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	flags := byte(0x01 | 0x04)
+	if attested {
+		flags |= 0x40
+	}
+	out := append(hash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, signCount)
+	if attested {
+		out = append(out, make([]byte, 16)...)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.credentialID)))
+		out = append(out, a.credentialID...)
+		out = append(out, a.coseKey()...)
+	}
+	return out
+}
+
+func (a *softAuthenticator) sign(data []byte) []byte {
+	if a.alg == utils.COSEAlgEdDSA {
+		return ed25519.Sign(a.edKey, data)
+	}
+	digest := sha256.Sum256(data)
+	signature, err := ecdsa.SignASN1(rand.Reader, a.ecKey, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signature
+}
+
+func clientDataJSON(ceremonyType, challenge, origin string) []byte {
+	raw, _ := json.Marshal(map[string]interface{}{"type": ceremonyType, "challenge": challenge, "origin": origin})
+	return raw
+}
+
+func b64(raw []byte) string {
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// assertion 认证器对登录挑战的应答，字段可在签名前后单独篡改
+type assertion struct {
+	rpID       string
+	signCount  uint32
+	ceremony   string
+	challenge  string
+	origin     string
+	userHandle string
+	tamper     func(authData, signature []byte)
+}
+
+func (a *softAuthenticator) assert(opts assertion) gin.H {
+	clientData := clientDataJSON(opts.ceremony, opts.challenge, opts.origin)
+	authData := a.authData(opts.rpID, opts.signCount, false)
+	hash := sha256.Sum256(clientData)
+	signature := a.sign(append(append([]byte(nil), authData...), hash[:]...))
+	if opts.tamper != nil {
+		opts.tamper(authData, signature)
+	}
+	return gin.H{
+		"id":   b64(a.credentialID),
+		"type": "public-key",
+		"response": gin.H{
+			"clientDataJSON":    b64(clientData),
+			"authenticatorData": b64(authData),
+			"signature":         b64(signature),
+			"userHandle":        opts.userHandle,
+		},
+	}
+}
+
+func passkeyRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/passkeys/login/begin", BeginPasskeyLogin)
+	router.POST("/passkeys/login/finish", FinishPasskeyLogin)
+	authed := router.Group("", middleware.AuthRequired())
+	authed.POST("/passkeys/register/begin", BeginPasskeyRegistration)
+	authed.POST("/passkeys/register/finish", FinishPasskeyRegistration)
+	return router
+}
+
+func setupPasskeyTest(t *testing.T) *gin.Engine {
+	t.Helper()
+	testutil.SetupDB(t, &model.DB, accountModels...)
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "passkey-test-app-key-0123456789abcdef"
+	cfg.Site.AllowedOrigins = []string{"https://global.example"}
+	useTestConfig(t, cfg)
+
+	// 创建站点同时使站点缓存失效，避免沿用其他测试数据库中的站点
+	sites := []model.Site{
+		{SiteID: "blog", Name: "Blog", AllowedOrigins: types.StringList{testOrigin}},
+		{SiteID: "open", Name: "Open"},
+	}
+	for i := range sites {
+		if err := model.CreateSite(&sites[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return passkeyRouter()
+}
+
+var siteHeaders = map[string]string{"Origin": testOrigin, "X-Marku-Site": "blog"}
+
+func registerPasskey(t *testing.T, router *gin.Engine, token string, authenticator *softAuthenticator) {
+	t.Helper()
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	for name, value := range siteHeaders {
+		headers[name] = value
+	}
+	begin := doJSON(t, router, http.MethodPost, "/passkeys/register/begin", nil, headers)
+	if begin.Code != http.StatusOK {
+		t.Fatalf("register begin: %d %s", begin.Code, begin.Message)
+	}
+	var options struct {
+		Challenge string `json:"challenge"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+	}
+	_ = json.Unmarshal(begin.Data, &options)
+	if options.RP.ID != testRPID {
+		t.Fatalf("rp.id = %q, want %q", options.RP.ID, testRPID)
+	}
+
+	attestationObject := cborValue([][2]interface{}{
+		{"fmt", "none"},
+		{"attStmt", [][2]interface{}{}},
+		{"authData", authenticator.authData(testRPID, 0, true)},
+	})
+	finish := doJSON(t, router, http.MethodPost, "/passkeys/register/finish", gin.H{
+		"id":   b64(authenticator.credentialID),
+		"type": "public-key",
+		"name": "test key",
+		"response": gin.H{
+			"clientDataJSON":    b64(clientDataJSON("webauthn.create", options.Challenge, testOrigin)),
+			"attestationObject": b64(attestationObject),
+		},
+	}, headers)
+	if finish.Code != http.StatusOK {
+		t.Fatalf("register finish: %d %s", finish.Code, finish.Message)
+	}
+}
+
+func beginLogin(t *testing.T, router *gin.Engine) string {
+	t.Helper()
+	begin := doJSON(t, router, http.MethodPost, "/passkeys/login/begin", nil, siteHeaders)
+	if begin.Code != http.StatusOK {
+		t.Fatalf("login begin: %d %s", begin.Code, begin.Message)
+	}
+	var options struct {
+		Challenge string `json:"challenge"`
+		RPID      string `json:"rpId"`
+	}
+	_ = json.Unmarshal(begin.Data, &options)
+	if options.RPID != testRPID {
+		t.Fatalf("rpId = %q, want %q", options.RPID, testRPID)
+	}
+	return options.Challenge
+}
+
+func validAssertion(challenge string, signCount uint32) assertion {
+	return assertion{rpID: testRPID, signCount: signCount, ceremony: "webauthn.get", challenge: challenge, origin: testOrigin}
+}
+
+func TestPasskeyRegisterAndLogin(t *testing.T) {
+	for _, alg := range []int{utils.COSEAlgES256, utils.COSEAlgEdDSA} {
+		t.Run(strconv.Itoa(alg), func(t *testing.T) {
+			router := setupPasskeyTest(t)
+			user, token := createTestUser(t, "alice")
+			authenticator := newSoftAuthenticator(t, alg)
+			registerPasskey(t, router, token, authenticator)
+
+			for _, signCount := range []uint32{1, 2} {
+				opts := validAssertion(beginLogin(t, router), signCount)
+				opts.userHandle = b64([]byte(strconv.FormatUint(uint64(user.ID), 10)))
+				finish := doJSON(t, router, http.MethodPost, "/passkeys/login/finish", authenticator.assert(opts), siteHeaders)
+				if finish.Code != http.StatusOK {
+					t.Fatalf("login with count %d: %d %s", signCount, finish.Code, finish.Message)
+				}
+				var auth struct {
+					ID    uint   `json:"id"`
+					Token string `json:"token"`
+				}
+				_ = json.Unmarshal(finish.Data, &auth)
+				if auth.ID != user.ID || auth.Token == "" {
+					t.Fatalf("unexpected login response %s", finish.Data)
+				}
+			}
+
+			credential, err := model.GetWebAuthnCredential(b64(authenticator.credentialID))
+			if err != nil || credential.SignCount != 2 || credential.RPID != testRPID {
+				t.Fatalf("stored credential %+v, err %v", credential, err)
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginRejectsInvalidAssertion(t *testing.T) {
+	router := setupPasskeyTest(t)
+	_, token := createTestUser(t, "alice")
+	authenticator := newSoftAuthenticator(t, utils.COSEAlgES256)
+	registerPasskey(t, router, token, authenticator)
+
+	tests := []struct {
+		name   string
+		modify func(*assertion)
+	}{
+		{"wrong rp id hash", func(a *assertion) { a.rpID = "evil.example" }},
+		{"wrong origin", func(a *assertion) { a.origin = "https://evil.example" }},
+		{"unknown challenge", func(a *assertion) { a.challenge = b64([]byte("not-issued-by-server")) }},
+		{"wrong ceremony type", func(a *assertion) { a.ceremony = "webauthn.create" }},
+		{"tampered signature", func(a *assertion) {
+			a.tamper = func(_, signature []byte) { signature[len(signature)-1] ^= 0x01 }
+		}},
+		{"tampered authenticator data", func(a *assertion) {
+			a.tamper = func(authData, _ []byte) { authData[36] ^= 0x01 }
+		}},
+		{"wrong user handle", func(a *assertion) { a.userHandle = b64([]byte("999")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := validAssertion(beginLogin(t, router), 1)
+			tt.modify(&opts)
+			finish := doJSON(t, router, http.MethodPost, "/passkeys/login/finish", authenticator.assert(opts), siteHeaders)
+			if finish.Code == http.StatusOK {
+				t.Fatal("invalid assertion accepted")
+			}
+		})
+	}
+
+	// 以上失败都不应推进签名计数器
+	credential, _ := model.GetWebAuthnCredential(b64(authenticator.credentialID))
+	if credential.SignCount != 0 {
+		t.Fatalf("sign count advanced to %d by rejected assertions", credential.SignCount)
+	}
+}
+
+func TestPasskeyLoginRejectsReplayAndCounterRollback(t *testing.T) {
+	router := setupPasskeyTest(t)
+	_, token := createTestUser(t, "alice")
+	authenticator := newSoftAuthenticator(t, utils.COSEAlgEdDSA)
+	registerPasskey(t, router, token, authenticator)
+
+	body := authenticator.assert(validAssertion(beginLogin(t, router), 10))
+	if finish := doJSON(t, router, http.MethodPost, "/passkeys/login/finish", body, siteHeaders); finish.Code != http.StatusOK {
+		t.Fatalf("first login: %d %s", finish.Code, finish.Message)
+	}
+	if replay := doJSON(t, router, http.MethodPost, "/passkeys/login/finish", body, siteHeaders); replay.Code == http.StatusOK {
+		t.Fatal("replayed assertion accepted")
+	}
+
+	for _, signCount := range []uint32{10, 5} {
+		rollback := authenticator.assert(validAssertion(beginLogin(t, router), signCount))
+		if finish := doJSON(t, router, http.MethodPost, "/passkeys/login/finish", rollback, siteHeaders); finish.Code == http.StatusOK {
+			t.Fatalf("sign count %d after 10 accepted", signCount)
+		}
+	}
+}
+
+func TestPasskeyRegisterRejectsWrongRPID(t *testing.T) {
+	router := setupPasskeyTest(t)
+	_, token := createTestUser(t, "alice")
+	authenticator := newSoftAuthenticator(t, utils.COSEAlgES256)
+	headers := map[string]string{"Authorization": "Bearer " + token, "Origin": testOrigin, "X-Marku-Site": "blog"}
+
+	begin := doJSON(t, router, http.MethodPost, "/passkeys/register/begin", nil, headers)
+	var options struct {
+		Challenge string `json:"challenge"`
+	}
+	_ = json.Unmarshal(begin.Data, &options)
+
+	attestationObject := cborValue([][2]interface{}{
+		{"fmt", "none"},
+		{"attStmt", [][2]interface{}{}},
+		{"authData", authenticator.authData("evil.example", 0, true)},
+	})
+	finish := doJSON(t, router, http.MethodPost, "/passkeys/register/finish", gin.H{
+		"id": b64(authenticator.credentialID),
+		"response": gin.H{
+			"clientDataJSON":    b64(clientDataJSON("webauthn.create", options.Challenge, testOrigin)),
+			"attestationObject": b64(attestationObject),
+		},
+	}, headers)
+	if finish.Code == http.StatusOK {
+		t.Fatal("registration with another RP ID hash accepted")
+	}
+}
+
+func TestResolveRelyingPartyPerSite(t *testing.T) {
+	router := setupPasskeyTest(t)
+
+	tests := []struct {
+		name     string
+		origin   string
+		site     string
+		wantCode int
+		wantRPID string
+	}{
+		{"site origin", testOrigin, "blog", http.StatusOK, testRPID},
+		{"site origin via query", testOrigin, "?blog", http.StatusOK, testRPID},
+		{"global origin on restricted site", "https://global.example", "blog", http.StatusOK, "global.example"},
+		{"foreign origin on restricted site", "https://evil.example", "blog", http.StatusForbidden, ""},
+		{"another site's origin", testOrigin, "open", http.StatusOK, testRPID},
+		{"foreign origin on unrestricted site", "https://evil.example", "open", http.StatusForbidden, ""},
+		{"unknown site", testOrigin, "missing", http.StatusNotFound, ""},
+		{"registered origin without site", testOrigin, "", http.StatusOK, testRPID},
+		{"foreign origin without site", "https://evil.example", "", http.StatusForbidden, ""},
+		{"missing origin", "", "blog", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/passkeys/login/begin"
+			headers := map[string]string{"Origin": tt.origin}
+			if len(tt.site) > 0 && tt.site[0] == '?' {
+				path += "?siteId=" + tt.site[1:]
+			} else if tt.site != "" {
+				headers["X-Marku-Site"] = tt.site
+			}
+			response := doJSON(t, router, http.MethodPost, path, nil, headers)
+			if response.Code != tt.wantCode {
+				t.Fatalf("code = %d (%s), want %d", response.Code, response.Message, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var options struct {
+				RPID string `json:"rpId"`
+			}
+			_ = json.Unmarshal(response.Data, &options)
+			if options.RPID != tt.wantRPID {
+				t.Fatalf("rpId = %q, want %q", options.RPID, tt.wantRPID)
+			}
+		})
+	}
+}
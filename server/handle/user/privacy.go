@@ -0,0 +1,192 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeleteAccountRequest struct {
+	Password  string `json:"password"`
+	EmailCode string `json:"emailCode"`
+	Comments  string `json:"comments"` // anonymize / remove，默认 anonymize
+}
+
+type GuestDataRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	EmailCode string `json:"emailCode" binding:"required"`
+	Format    string `json:"format"`   // 导出格式：json / zip
+	Comments  string `json:"comments"` // 删除时评论的处理方式：anonymize / remove
+}
+
+// ExportMyData 下载当前用户的全部个人数据，format=zip 时打包为 ZIP
+func ExportMyData(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	export, err := model.CollectUserData(user)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "导出个人数据失败: "+err.Error())
+		return
+	}
+	sendDataExport(c, export, c.Query("format"))
+}
+
+// DeleteMyAccount 注销当前账户；已设置密码的用户需提供密码，否则需提供发送到账户邮箱的 data_request 验证码
+func DeleteMyAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	retention := normalizeCommentRetention(req.Comments)
+	if retention == "" {
+		utils.SendError(c, http.StatusBadRequest, "无效的评论处理方式")
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if user.Password != nil {
+		attemptKeys := accountAttemptKeys(c, "", user)
+		if rejectIfLocked(c, attemptKeys) {
+			return
+		}
+		if !model.VerifyUserPassword(user, req.Password) {
+			recordAttemptFailure(attemptKeys)
+			utils.SendError(c, http.StatusUnauthorized, "密码错误")
+			return
+		}
+	} else {
+		if user.Email == nil {
+			utils.SendError(c, http.StatusBadRequest, "账户未绑定邮箱，无法确认身份")
+			return
+		}
+		if !verifyDataRequestCode(c, *user.Email, req.EmailCode) {
+			return
+		}
+	}
+
+	if err := model.DeleteUserAccount(user, retention); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "注销账户失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "账户已注销", gin.H{"deleted": true, "comments": retention})
+}
+
+// ExportGuestData 游客通过邮箱验证码导出以该邮箱发表的评论
+func ExportGuestData(c *gin.Context) {
+	var req GuestDataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if !verifyDataRequestCode(c, email, req.EmailCode) {
+		return
+	}
+
+	export, err := model.CollectGuestData(email)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "导出个人数据失败: "+err.Error())
+		return
+	}
+	sendDataExport(c, export, req.Format)
+}
+
+// DeleteGuestData 游客通过邮箱验证码删除或匿名化以该邮箱发表的评论
+func DeleteGuestData(c *gin.Context) {
+	var req GuestDataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	retention := normalizeCommentRetention(req.Comments)
+	if retention == "" {
+		utils.SendError(c, http.StatusBadRequest, "无效的评论处理方式")
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if !verifyDataRequestCode(c, email, req.EmailCode) {
+		return
+	}
+
+	affected, err := model.DeleteGuestData(email, retention)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "删除个人数据失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "个人数据已处理", gin.H{"comments": retention, "affected": affected})
+}
+
+// verifyDataRequestCode 校验发送到指定邮箱的个人数据请求验证码
+func verifyDataRequestCode(c *gin.Context, email, code string) bool {
+	attemptKeys := emailCodeAttemptKeys(c, email, model.EmailPurposeDataRequest)
+	if rejectIfLocked(c, attemptKeys) {
+		return false
+	}
+
+	if err := model.VerifyEmailVerificationCode(email, model.EmailPurposeDataRequest, strings.TrimSpace(code)); err != nil {
+		recordAttemptFailure(attemptKeys)
+		utils.SendError(c, http.StatusBadRequest, "邮箱验证码校验失败: "+err.Error())
+		return false
+	}
+	clearAttemptFailures(attemptKeys)
+	return true
+}
+
+func normalizeCommentRetention(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", model.CommentRetentionAnonymize:
+		return model.CommentRetentionAnonymize
+	case model.CommentRetentionRemove:
+		return model.CommentRetentionRemove
+	default:
+		return ""
+	}
+}
+
+// sendDataExport 以附件形式返回导出数据，默认 JSON，format=zip 时打包
+func sendDataExport(c *gin.Context, export *model.UserDataExport, format string) {
+	payload, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "序列化个人数据失败: "+err.Error())
+		return
+	}
+
+	filename := "marku-data-" + time.Now().Format("20060102150405")
+	if !strings.EqualFold(strings.TrimSpace(format), "zip") {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		c.Data(http.StatusOK, "application/json; charset=utf-8", payload)
+		return
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: "marku-data.json", Method: zip.Deflate, Modified: export.ExportedAt})
+	if err == nil {
+		_, err = file.Write(payload)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "打包个人数据失败: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".zip"))
+	c.Data(http.StatusOK, "application/zip", buffer.Bytes())
+}
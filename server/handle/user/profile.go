@@ -0,0 +1,312 @@
+package user
+
+import (
+	"errors"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UpdateProfileRequest struct {
+	Username *string `json:"username"`
+	URL      *string `json:"url"`
+	Avatar   *string `json:"avatar"`
+	Bio      *string `json:"bio"`
+	// 是否接收评论中被 @提及 的邮件通知
+	MentionEmails *bool `json:"mentionEmails"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword" binding:"required,min=6"`
+}
+
+type ChangeEmailCodeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ChangeEmailRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	EmailCode string `json:"emailCode" binding:"required"`
+}
+
+type profileResponse struct {
+	ID               uint      `json:"id"`
+	Username         string    `json:"username"`
+	Email            *string   `json:"email,omitempty"`
+	Role             int       `json:"role"`
+	URL              *string   `json:"url,omitempty"`
+	Avatar           *string   `json:"avatar,omitempty"`
+	Bio              *string   `json:"bio,omitempty"`
+	HasPassword      bool      `json:"has_password"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	MentionEmails    bool      `json:"mention_emails"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// GetProfile 获取当前用户资料
+func GetProfile(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	utils.SendResponse(c, http.StatusOK, "获取用户资料成功", buildProfileResponse(user))
+}
+
+// UpdateProfile 更新当前用户的用户名、主页、头像、简介与提及通知偏好，未提供的字段保持不变，空字符串表示清空
+func UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	updates := make(map[string]interface{})
+
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if length := utf8.RuneCountInString(username); length < 2 || length > 100 {
+			utils.SendError(c, http.StatusBadRequest, "用户名长度需在 2 到 100 个字符之间")
+			return
+		}
+		if username != user.Username {
+			// 登录时按邮箱或用户名查找账户，用户名不能与邮箱形式重叠
+			if strings.Contains(username, "@") {
+				utils.SendError(c, http.StatusBadRequest, "用户名不能包含 @")
+				return
+			}
+			if existing, err := model.GetUserByName(username); err == nil && existing.ID != user.ID {
+				utils.SendError(c, http.StatusConflict, "用户名已存在")
+				return
+			}
+			updates["username"] = username
+		}
+	}
+
+	if req.URL != nil {
+		value, err := normalizeProfileURL(*req.URL)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "主页地址无效: "+err.Error())
+			return
+		}
+		updates["url"] = value
+	}
+
+	if req.Avatar != nil {
+		value, err := normalizeProfileURL(*req.Avatar)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "头像地址无效: "+err.Error())
+			return
+		}
+		updates["avatar"] = value
+	}
+
+	if req.Bio != nil {
+		bio := strings.TrimSpace(*req.Bio)
+		if utf8.RuneCountInString(bio) > 500 {
+			utils.SendError(c, http.StatusBadRequest, "简介不能超过 500 个字符")
+			return
+		}
+		if bio == "" {
+			updates["bio"] = nil
+		} else {
+			updates["bio"] = bio
+		}
+	}
+
+	if req.MentionEmails != nil {
+		updates["mention_email_opt_out"] = !*req.MentionEmails
+	}
+
+	if err := model.UpdateUserProfile(user.ID, updates); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "更新用户资料失败: "+err.Error())
+		return
+	}
+
+	updated, err := model.GetUserByID(user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询用户失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "更新用户资料成功", buildProfileResponse(updated))
+}
+
+// UnsubscribeMentions 通过提及邮件中的退订链接关闭提及通知，无需登录
+func UnsubscribeMentions(c *gin.Context) {
+	userID, err := utils.ParseUnsubscribeToken(c.Query("token"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "退订链接无效或已过期")
+		return
+	}
+
+	if _, err := model.GetUserByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "查询用户失败: "+err.Error())
+		return
+	}
+
+	if err := model.SetMentionEmailOptOut(userID, true); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "退订失败: "+err.Error())
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "已退订提及通知邮件，可在个人资料中重新开启", gin.H{"unsubscribed": true})
+}
+
+// ChangePassword 修改密码；已设置密码的用户需提供旧密码，通过邮箱或第三方登录创建的账户可直接设置
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if user.Password != nil {
+		attemptKeys := accountAttemptKeys(c, "", user)
+		if rejectIfLocked(c, attemptKeys) {
+			return
+		}
+		if !model.VerifyUserPassword(user, req.OldPassword) {
+			recordAttemptFailure(attemptKeys)
+			utils.SendError(c, http.StatusUnauthorized, "旧密码错误")
+			return
+		}
+		clearAttemptFailures(attemptKeys)
+	}
+
+	hashedPassword, err := utils.SetPasswordEncrypt(req.NewPassword)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "密码加密失败: "+err.Error())
+		return
+	}
+
+	if err := model.UpdateUserPassword(user.ID, hashedPassword); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "更新密码失败: "+err.Error())
+		return
+	}
+
+	// 保留当前会话，其余设备需要重新登录
+	if err := model.RevokeUserSessions(user.ID, middleware.CurrentSessionID(c)); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "吊销其他登录会话失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "密码修改成功", gin.H{"updated": true})
+}
+
+// SendChangeEmailCode 向新邮箱发送更换邮箱验证码
+func SendChangeEmailCode(c *gin.Context) {
+	var req ChangeEmailCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if !checkNewEmailAvailable(c, middleware.CurrentUser(c), email) {
+		return
+	}
+
+	deliverEmailCode(c, email, model.EmailPurposeChangeEmail)
+}
+
+// ChangeEmail 校验新邮箱收到的验证码后更换邮箱
+func ChangeEmail(c *gin.Context) {
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	email := strings.TrimSpace(req.Email)
+	if !checkNewEmailAvailable(c, user, email) {
+		return
+	}
+
+	attemptKeys := emailCodeAttemptKeys(c, email, model.EmailPurposeChangeEmail)
+	if rejectIfLocked(c, attemptKeys) {
+		return
+	}
+
+	if err := model.VerifyEmailVerificationCode(email, model.EmailPurposeChangeEmail, strings.TrimSpace(req.EmailCode)); err != nil {
+		recordAttemptFailure(attemptKeys)
+		utils.SendError(c, http.StatusBadRequest, "邮箱验证码校验失败: "+err.Error())
+		return
+	}
+	clearAttemptFailures(attemptKeys)
+
+	if err := model.UpdateUserEmail(user.ID, email); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "更换邮箱失败: "+err.Error())
+		return
+	}
+
+	user.Email = &email
+	utils.SendResponse(c, http.StatusOK, "邮箱更换成功", buildProfileResponse(user))
+}
+
+// checkNewEmailAvailable 校验新邮箱与当前邮箱不同且未被其他用户占用
+func checkNewEmailAvailable(c *gin.Context, user *model.User, email string) bool {
+	if user.Email != nil && strings.EqualFold(*user.Email, email) {
+		utils.SendError(c, http.StatusBadRequest, "新邮箱与当前邮箱相同")
+		return false
+	}
+
+	existing, err := model.GetUserByEmail(email)
+	if err == nil && existing.ID != user.ID {
+		utils.SendError(c, http.StatusConflict, "邮箱已存在")
+		return false
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendError(c, http.StatusInternalServerError, "查询用户失败: "+err.Error())
+		return false
+	}
+	// 早期注册的用户名可能是邮箱形式，与之相同的邮箱会让登录匹配到两个账户
+	if existing, err := model.GetUserByName(email); err == nil && existing.ID != user.ID {
+		utils.SendError(c, http.StatusConflict, "邮箱已被其他用户用作用户名")
+		return false
+	}
+	return true
+}
+
+// normalizeProfileURL 校验资料中的链接，空字符串返回 nil 表示清空
+func normalizeProfileURL(value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if len(value) > 500 {
+		return nil, errors.New("长度不能超过 500 个字符")
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, errors.New("仅支持 http 或 https 链接")
+	}
+	return value, nil
+}
+
+func buildProfileResponse(user *model.User) profileResponse {
+	return profileResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Role:             user.Role,
+		URL:              user.URL,
+		Avatar:           user.Avatar,
+		Bio:              user.Bio,
+		HasPassword:      user.Password != nil,
+		TwoFactorEnabled: model.IsTwoFactorEnabled(user.ID),
+		MentionEmails:    !user.MentionEmailOptOut,
+		CreatedAt:        user.CreatedAt,
+	}
+}
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"marku-server/middleware"
+	"marku-server/model"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupProfileTest(t *testing.T) *gin.Engine {
+	t.Helper()
+	testutil.SetupDB(t, &model.DB, accountModels...)
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "profile-test-app-key-0123456789abcdef"
+	useTestConfig(t, cfg)
+
+	router := gin.New()
+	authed := router.Group("", middleware.AuthRequired())
+	authed.PUT("/me", UpdateProfile)
+	authed.POST("/me/email/code", SendChangeEmailCode)
+	return router
+}
+
+func TestUpdateProfileRejectsEmailLikeUsernames(t *testing.T) {
+	router := setupProfileTest(t)
+	bobEmail := "bob@example.com"
+	if err := model.DB.Create(&model.User{Username: "bob", Email: &bobEmail}).Error; err != nil {
+		t.Fatal(err)
+	}
+	alice, token := createTestUser(t, "alice")
+	headers := map[string]string{"Authorization": "Bearer " + token}
+
+	// 改名为他人的邮箱后，按邮箱登录会同时匹配两个账户
+	for _, username := range []string{bobEmail, "alice@example.com", "a@b"} {
+		response := doJSON(t, router, http.MethodPut, "/me", gin.H{"username": username}, headers)
+		if response.Code != http.StatusBadRequest {
+			t.Fatalf("username %q: %d %s", username, response.Code, response.Message)
+		}
+	}
+	if user, err := model.GetUserByEmailOrName(bobEmail); err != nil || user.Username != "bob" {
+		t.Fatalf("login by bob's email resolved to %+v, %v", user, err)
+	}
+
+	if response := doJSON(t, router, http.MethodPut, "/me", gin.H{"username": "alice2"}, headers); response.Code != http.StatusOK {
+		t.Fatalf("plain rename: %d %s", response.Code, response.Message)
+	}
+	if user, err := model.GetUserByID(alice.ID); err != nil || user.Username != "alice2" {
+		t.Fatalf("rename not stored: %+v, %v", user, err)
+	}
+}
+
+func TestChangeEmailRejectsAnotherUsersUsername(t *testing.T) {
+	router := setupProfileTest(t)
+	// 早期注册接口允许邮箱形式的用户名
+	if err := model.DB.Create(&model.User{Username: "carol@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, token := createTestUser(t, "alice")
+	headers := map[string]string{"Authorization": "Bearer " + token}
+
+	response := doJSON(t, router, http.MethodPost, "/me/email/code", gin.H{"email": "carol@example.com"}, headers)
+	if response.Code != http.StatusConflict {
+		t.Fatalf("email matching another username: %d %s", response.Code, response.Message)
+	}
+}
@@ -0,0 +1,142 @@
+package user
+
+import (
+	"errors"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type sessionResponse struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IP         *string   `json:"ip,omitempty"`
+	UA         *string   `json:"ua,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌与刷新令牌
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	session, refreshToken, err := model.RotateSessionRefreshToken(strings.TrimSpace(req.RefreshToken), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "刷新登录状态失败: "+err.Error())
+		return
+	}
+
+	user, err := model.GetUserByID(session.UserID)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "用户不存在")
+		return
+	}
+
+	response, err := buildAuthResponse(c, user, session, refreshToken)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "刷新成功", response)
+}
+
+// Logout 退出当前会话
+func Logout(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	session := middleware.CurrentSession(c)
+	if session == nil {
+		// 不绑定会话的旧令牌无法单独吊销，只能使该用户的全部旧令牌失效
+		if err := model.RevokeLegacyTokens(user.ID); err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "退出登录失败: "+err.Error())
+			return
+		}
+		utils.SendResponse(c, http.StatusOK, "已退出登录", gin.H{"revoked": true})
+		return
+	}
+
+	if err := model.RevokeSession(user.ID, session.ID); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "退出登录失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "已退出登录", gin.H{"revoked": true})
+}
+
+// ListSessions 列出当前用户的登录会话
+func ListSessions(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	current := middleware.CurrentSession(c)
+
+	sessions, err := model.ListUserSessions(user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "查询登录会话失败: "+err.Error())
+		return
+	}
+
+	responses := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, sessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IP:         session.IP,
+			UA:         session.UA,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    current != nil && current.ID == session.ID,
+		})
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取登录会话成功", responses)
+}
+
+// RevokeSession 吊销当前用户的指定会话
+func RevokeSession(c *gin.Context) {
+	var uri types.UriID
+	if err := c.ShouldBindUri(&uri); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的会话ID")
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if err := model.RevokeSession(user.ID, uri.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "会话不存在或已失效")
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, "吊销会话失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "会话已吊销", gin.H{"revoked": true})
+}
+
+// RevokeOtherSessions 吊销当前用户除当前会话外的全部会话
+func RevokeOtherSessions(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	if err := model.RevokeUserSessions(user.ID, middleware.CurrentSessionID(c)); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "吊销会话失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "其他会话已吊销", gin.H{"revoked": true})
+}
@@ -0,0 +1,182 @@
+package user
+
+import (
+	"marku-server/config"
+	"marku-server/middleware"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// LoginTwoFactor 登录第二步：校验挑战令牌与动态码后签发登录令牌
+func LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	userID, err := utils.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "验证已失效，请重新登录: "+err.Error())
+		return
+	}
+
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "用户不存在")
+		return
+	}
+
+	attemptKeys := accountAttemptKeys(c, "", user)
+	if rejectIfLocked(c, attemptKeys) {
+		return
+	}
+
+	if err := model.VerifyTwoFactorCode(user.ID, req.Code); err != nil {
+		recordAttemptFailure(attemptKeys)
+		utils.SendError(c, http.StatusUnauthorized, "两步验证失败: "+err.Error())
+		return
+	}
+	clearAttemptFailures(attemptKeys)
+
+	sendAuthResponse(c, "登录成功", user)
+}
+
+// GetTwoFactorStatus 查询当前用户的两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	enabled := model.IsTwoFactorEnabled(user.ID)
+
+	remaining := int64(0)
+	if enabled {
+		remaining = model.CountRemainingRecoveryCodes(user.ID)
+	}
+
+	utils.SendResponse(c, http.StatusOK, "获取两步验证状态成功", gin.H{
+		"enabled":                  enabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTwoFactor 生成待确认的 TOTP 密钥与二维码地址
+func SetupTwoFactor(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	twoFactor, err := model.BeginTwoFactorSetup(user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "生成两步验证密钥失败: "+err.Error())
+		return
+	}
+
+	account := user.Username
+	if user.Email != nil && strings.TrimSpace(*user.Email) != "" {
+		account = *user.Email
+	}
+
+	utils.SendResponse(c, http.StatusOK, "请使用认证器应用扫描二维码", gin.H{
+		"secret":           twoFactor.Secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(config.GetTOTPIssuer(), account, twoFactor.Secret),
+	})
+}
+
+// ConfirmTwoFactor 校验首个动态码并启用两步验证
+func ConfirmTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	codes, err := model.ConfirmTwoFactor(user.ID, req.Code)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "启用两步验证失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "两步验证已启用，请妥善保存恢复码", gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor 校验动态码或恢复码后停用两步验证
+func DisableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if err := model.VerifyTwoFactorCode(user.ID, req.Code); err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "两步验证失败: "+err.Error())
+		return
+	}
+
+	if err := model.DisableTwoFactor(user.ID); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "停用两步验证失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "两步验证已停用", gin.H{"enabled": false})
+}
+
+// RegenerateRecoveryCodes 校验动态码后重新生成恢复码
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if err := model.VerifyTwoFactorCode(user.ID, req.Code); err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "两步验证失败: "+err.Error())
+		return
+	}
+
+	codes, err := model.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "生成恢复码失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "恢复码已重新生成", gin.H{"recovery_codes": codes})
+}
+
+// completeLogin 首要凭证校验通过后，已启用两步验证的用户先返回挑战令牌
+func completeLogin(c *gin.Context, message string, user *model.User) {
+	if !model.IsTwoFactorEnabled(user.ID) {
+		sendAuthResponse(c, message, user)
+		return
+	}
+
+	challenge, expiresAt, err := utils.GenerateChallengeToken(user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "生成两步验证令牌失败: "+err.Error())
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "需要两步验证", twoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+		ExpiresAt:         expiresAt,
+	})
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"fmt"
 	"marku-server/config"
 	"marku-server/model"
@@ -8,8 +9,10 @@ import (
 	"marku-server/utils"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type SendEmailCodeRequest struct {
@@ -35,6 +38,11 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type EmailLoginRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	EmailCode string `json:"emailCode" binding:"required"`
+}
+
 type RecoverPasswordRequest struct {
 	Email       string `json:"email" binding:"required,email"`
 	EmailCode   string `json:"emailCode" binding:"required"`
@@ -42,11 +50,16 @@ type RecoverPasswordRequest struct {
 }
 
 type authUserResponse struct {
-	ID       uint    `json:"id"`
-	Username string  `json:"username"`
-	Email    *string `json:"email,omitempty"`
-	Role     int     `json:"role"`
-	Token    string  `json:"token,omitempty"`
+	ID           uint      `json:"id"`
+	Username     string    `json:"username"`
+	Email        *string   `json:"email,omitempty"`
+	Role         int       `json:"role"`
+	Token        string    `json:"token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	SessionID    uint      `json:"session_id"`
+	// 管理员被要求启用两步验证但尚未启用时为 true
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
 }
 
 type verifyResponse struct {
@@ -67,7 +80,12 @@ func SendEmailCode(c *gin.Context) {
 		return
 	}
 
-	record, err := model.GenerateEmailVerificationCode(strings.TrimSpace(req.Email), purpose)
+	deliverEmailCode(c, strings.TrimSpace(req.Email), purpose)
+}
+
+// deliverEmailCode 生成验证码并通过邮件发送，发送失败时删除验证码记录
+func deliverEmailCode(c *gin.Context, email, purpose string) {
+	record, err := model.GenerateEmailVerificationCode(email, purpose)
 	if err != nil {
 		utils.SendError(c, http.StatusInternalServerError, "生成验证码失败: "+err.Error())
 		return
@@ -121,10 +139,18 @@ func VerifyEmailCode(c *gin.Context) {
 		return
 	}
 
-	if err := model.VerifyEmailVerificationCode(strings.TrimSpace(req.Email), purpose, strings.TrimSpace(req.Code)); err != nil {
+	email := strings.TrimSpace(req.Email)
+	attemptKeys := emailCodeAttemptKeys(c, email, purpose)
+	if rejectIfLocked(c, attemptKeys) {
+		return
+	}
+
+	if err := model.VerifyEmailVerificationCode(email, purpose, strings.TrimSpace(req.Code)); err != nil {
+		recordAttemptFailure(attemptKeys)
 		utils.SendError(c, http.StatusBadRequest, "验证码校验失败: "+err.Error())
 		return
 	}
+	clearAttemptFailures(attemptKeys)
 
 	utils.SendResponse(c, http.StatusOK, "验证码校验成功", verifyResponse{Verified: true})
 }
@@ -144,10 +170,17 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	attemptKeys := emailCodeAttemptKeys(c, email, model.EmailPurposeRegister)
+	if rejectIfLocked(c, attemptKeys) {
+		return
+	}
+
 	if err := model.VerifyEmailVerificationCode(email, model.EmailPurposeRegister, strings.TrimSpace(req.EmailCode)); err != nil {
+		recordAttemptFailure(attemptKeys)
 		utils.SendError(c, http.StatusBadRequest, "邮箱验证码校验失败: "+err.Error())
 		return
 	}
+	clearAttemptFailures(attemptKeys)
 
 	if _, err := model.GetUserByName(username); err == nil {
 		utils.SendError(c, http.StatusConflict, "用户名已存在")
@@ -183,17 +216,81 @@ func Login(c *gin.Context) {
 
 	account := strings.TrimSpace(req.Account)
 	user, err := model.FindRegisteredUserByAccount(account)
-	if err != nil || user == nil || user.Role == types.RoleGuest || user.Password == nil {
+	if err != nil {
+		user = nil
+	}
+
+	attemptKeys := accountAttemptKeys(c, account, user)
+	if rejectIfLocked(c, attemptKeys) {
+		return
+	}
+
+	if user == nil || user.Role == types.RoleGuest || user.Password == nil {
+		recordAttemptFailure(attemptKeys)
 		utils.SendError(c, http.StatusUnauthorized, "账号或密码错误")
 		return
 	}
 
 	if err := utils.CheckPasswordEncrypt(*user.Password, req.Password); err != nil {
+		recordAttemptFailure(attemptKeys)
 		utils.SendError(c, http.StatusUnauthorized, "账号或密码错误")
 		return
 	}
+	clearAttemptFailures(attemptKeys)
+
+	completeLogin(c, "登录成功", user)
+}
+
+// LoginByEmailCode 邮箱验证码登录
+func LoginByEmailCode(c *gin.Context) {
+	var req EmailLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "无效的请求体: "+err.Error())
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	attemptKeys := emailCodeAttemptKeys(c, email, model.EmailPurposeLogin)
+	if rejectIfLocked(c, attemptKeys) {
+		return
+	}
+
+	if err := model.VerifyEmailVerificationCode(email, model.EmailPurposeLogin, strings.TrimSpace(req.EmailCode)); err != nil {
+		recordAttemptFailure(attemptKeys)
+		utils.SendError(c, http.StatusBadRequest, "邮箱验证码校验失败: "+err.Error())
+		return
+	}
+	clearAttemptFailures(attemptKeys)
 
-	sendAuthResponse(c, "登录成功", user)
+	user, err := model.GetUserByEmail(email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusInternalServerError, "查询用户失败: "+err.Error())
+			return
+		}
+		if !config.IsEmailLoginAutoRegisterEnabled() {
+			utils.SendError(c, http.StatusNotFound, "用户不存在")
+			return
+		}
+
+		user, err = model.CreateEmailUser(email)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "创建用户失败: "+err.Error())
+			return
+		}
+		sendAuthResponse(c, "注册并登录成功", user)
+		return
+	}
+
+	// 游客已通过验证码证明邮箱归属，直接升级为注册用户
+	if user.Role == types.RoleGuest {
+		if err := model.UpgradeGuestUser(user); err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "升级游客账户失败: "+err.Error())
+			return
+		}
+	}
+
+	completeLogin(c, "登录成功", user)
 }
 
 // RecoverPassword 密码找回
@@ -205,10 +302,17 @@ func RecoverPassword(c *gin.Context) {
 	}
 
 	email := strings.TrimSpace(req.Email)
+	attemptKeys := emailCodeAttemptKeys(c, email, model.EmailPurposeRecover)
+	if rejectIfLocked(c, attemptKeys) {
+		return
+	}
+
 	if err := model.VerifyEmailVerificationCode(email, model.EmailPurposeRecover, strings.TrimSpace(req.EmailCode)); err != nil {
+		recordAttemptFailure(attemptKeys)
 		utils.SendError(c, http.StatusBadRequest, "邮箱验证码校验失败: "+err.Error())
 		return
 	}
+	clearAttemptFailures(attemptKeys)
 
 	user, err := model.GetUserByEmail(email)
 	if err != nil {
@@ -227,12 +331,18 @@ func RecoverPassword(c *gin.Context) {
 		return
 	}
 
+	// 密码变更后旧的登录会话全部失效
+	if err := model.RevokeUserSessions(user.ID, 0); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "吊销登录会话失败: "+err.Error())
+		return
+	}
+
 	utils.SendResponse(c, http.StatusOK, "密码找回成功", gin.H{"updated": true})
 }
 
 func normalizePurpose(purpose string) string {
 	switch strings.ToLower(strings.TrimSpace(purpose)) {
-	case model.EmailPurposeRegister, model.EmailPurposeRecover, model.EmailPurposeLogin:
+	case model.EmailPurposeRegister, model.EmailPurposeRecover, model.EmailPurposeLogin, model.EmailPurposeDataRequest:
 		return strings.ToLower(strings.TrimSpace(purpose))
 	default:
 		return ""
@@ -240,24 +350,57 @@ func normalizePurpose(purpose string) string {
 }
 
 func sendAuthResponse(c *gin.Context, message string, user *model.User) {
-	if user == nil {
-		utils.SendError(c, http.StatusInternalServerError, "用户信息缺失")
+	response, err := issueAuthTokens(c, user)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	token, err := utils.GenerateAuthToken(user.ID)
+	utils.SendResponse(c, http.StatusOK, message, response)
+}
+
+// issueAuthTokens 为用户创建新会话并签发访问令牌与刷新令牌
+func issueAuthTokens(c *gin.Context, user *model.User) (*authUserResponse, error) {
+	if user == nil {
+		return nil, fmt.Errorf("用户信息缺失")
+	}
+
+	session, refreshToken, err := model.CreateSession(user.ID, c.GetHeader("X-Marku-Device"), c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, "生成登录令牌失败: "+err.Error())
-		return
+		return nil, fmt.Errorf("创建登录会话失败: %w", err)
 	}
 
-	utils.SendResponse(c, http.StatusOK, message, authUserResponse{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		Token:    token,
-	})
+	return buildAuthResponse(c, user, session, refreshToken)
+}
+
+// requestSiteID 请求所属的站点，取自 X-Marku-Site 头或 siteId 查询参数
+func requestSiteID(c *gin.Context) string {
+	if siteID := strings.TrimSpace(c.GetHeader("X-Marku-Site")); siteID != "" {
+		return siteID
+	}
+	return strings.TrimSpace(c.Query("siteId"))
+}
+
+func buildAuthResponse(c *gin.Context, user *model.User, session *model.Session, refreshToken string) (*authUserResponse, error) {
+	token, expiresAt, err := utils.GenerateAuthToken(user.ID, session.ID, user.Role, requestSiteID(c))
+	if err != nil {
+		return nil, fmt.Errorf("生成登录令牌失败: %w", err)
+	}
+
+	response := &authUserResponse{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		Role:         user.Role,
+		Token:        token,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		SessionID:    session.ID,
+	}
+	if user.Role == types.RoleAdmin && config.IsAdmin2FARequired() {
+		response.TwoFactorSetupRequired = !model.IsTwoFactorEnabled(user.ID)
+	}
+	return response, nil
 }
 
 // SendAuthSuccess 返回带 token 的登录态响应
@@ -267,14 +410,18 @@ func SendAuthSuccess(c *gin.Context, message string, user *model.User) {
 
 func buildVerificationEmailContent(purpose, code string) (string, string) {
 	subjectMap := map[string]string{
-		model.EmailPurposeRegister: "Marku 注册验证码",
-		model.EmailPurposeRecover:  "Marku 密码找回验证码",
-		model.EmailPurposeLogin:    "Marku 登录验证码",
+		model.EmailPurposeRegister:    "Marku 注册验证码",
+		model.EmailPurposeRecover:     "Marku 密码找回验证码",
+		model.EmailPurposeLogin:       "Marku 登录验证码",
+		model.EmailPurposeChangeEmail: "Marku 更换邮箱验证码",
+		model.EmailPurposeDataRequest: "Marku 个人数据请求验证码",
 	}
 	descMap := map[string]string{
-		model.EmailPurposeRegister: "用于完成注册验证",
-		model.EmailPurposeRecover:  "用于完成密码找回",
-		model.EmailPurposeLogin:    "用于完成登录验证",
+		model.EmailPurposeRegister:    "用于完成注册验证",
+		model.EmailPurposeRecover:     "用于完成密码找回",
+		model.EmailPurposeLogin:       "用于完成登录验证",
+		model.EmailPurposeChangeEmail: "用于确认新的邮箱地址",
+		model.EmailPurposeDataRequest: "用于导出或删除你的个人数据",
 	}
 
 	subject := subjectMap[purpose]
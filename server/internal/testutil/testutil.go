@@ -0,0 +1,49 @@
+// Package testutil 各包测试共用的数据库与配置夹具
+package testutil
+
+import (
+	"marku-server/config"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// SetupDB 为当前测试创建独立的 SQLite 数据库并迁移指定模型，
+// 替换 target 指向的全局连接（通常为 &model.DB），测试结束后恢复并关闭
+func SetupDB(t *testing.T, target **gorm.DB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	previous := *target
+	*target = db
+	t.Cleanup(func() {
+		*target = previous
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// UseConfig 替换全局配置并在测试结束后恢复
+func UseConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	previousConfig, previousAppKey := config.GlobalConfig, config.AppKey
+	config.GlobalConfig = cfg
+	config.AppKey = cfg.Site.AppKey
+	t.Cleanup(func() {
+		config.GlobalConfig, config.AppKey = previousConfig, previousAppKey
+	})
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"log"
 	"marku-server/config"
 	"marku-server/model"
 	"marku-server/routes"
 	"marku-server/logs"
+	"marku-server/storage"
+	"marku-server/utils"
+	"os"
+	"time"
 )
 
 
@@ -13,8 +19,74 @@ func main() {
 	config.InitConfigFile()
 	// 初始化日志系统
 	logs.InitLogger()
+	if config.GetPublicBaseURL() == "" {
+		log.Println("未配置 site.public_base_url，邮件与回调链接将按请求的 Host 生成，生产环境请配置")
+	}
+	// 初始化令牌签名密钥
+	if err := utils.InitJWTKeys(); err != nil {
+		log.Fatalln("令牌密钥初始化失败：", err.Error())
+	}
+	// 加载 User-Agent 解析规则
+	if err := utils.LoadUARules(config.GetUARulesPath()); err != nil {
+		log.Fatalln("UA 规则加载失败：", err.Error())
+	}
 	// 初始化数据库
 	model.InitDatabase()
+	// 命令行子命令：backfill-ua [--force] 为已有评论补充 UA 解析结果
+	if len(os.Args) > 1 && os.Args[1] == "backfill-ua" {
+		force := len(os.Args) > 2 && os.Args[2] == "--force"
+		updated, err := model.BackfillCommentUserAgents(force, 500)
+		if err != nil {
+			log.Fatalln("UA 回填失败：", err.Error())
+		}
+		log.Printf("UA 回填完成，共更新 %d 条评论\n", updated)
+		return
+	}
+	// 初始化图片上传存储
+	if config.GetUploadConfig().Enabled {
+		if err := storage.Init(config.GetUploadConfig().Storage); err != nil {
+			log.Fatalln("上传存储初始化失败：", err.Error())
+		}
+	}
+	// 命令行子命令：cleanup-uploads 删除上传超过 24 小时仍未被评论引用（或所属评论已删除）的图片
+	if len(os.Args) > 1 && os.Args[1] == "cleanup-uploads" {
+		if storage.Default == nil {
+			log.Fatalln("图片上传未开启")
+		}
+		deleted, err := cleanupOrphanUploads(time.Now().Add(-24*time.Hour), 500)
+		if err != nil {
+			log.Fatalln("清理未引用图片失败：", err.Error())
+		}
+		log.Printf("清理完成，共删除 %d 张未引用图片\n", deleted)
+		return
+	}
 	// 初始化路由
 	routes.InitRouter()
-}
\ No newline at end of file
+}
+
+// cleanupOrphanUploads 分批删除指定时间之前上传且未被评论引用的图片及其记录
+func cleanupOrphanUploads(before time.Time, batchSize int) (int, error) {
+	ctx := context.Background()
+	deleted := 0
+	for {
+		attachments, err := model.ListOrphanAttachments(before, batchSize)
+		if err != nil {
+			return deleted, err
+		}
+		for _, attachment := range attachments {
+			if err := storage.Default.Delete(ctx, attachment.StorageKey); err != nil {
+				return deleted, err
+			}
+			if err := storage.Default.Delete(ctx, attachment.ThumbnailKey); err != nil {
+				return deleted, err
+			}
+			if err := model.DeleteAttachment(attachment.ID); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if len(attachments) < batchSize {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APIKeyHeader 服务端调用时携带 API 密钥的请求头，不在 CORS 允许列表内，浏览器无法发送
+	APIKeyHeader     = "X-Marku-Key"
+	contextAPIKeyKey = "marku_api_key"
+)
+
+// APIKeyAuth 识别请求携带的站点 API 密钥并在请求结束后记录审计日志；
+// 未携带密钥时按普通请求处理，密钥无效时直接拒绝
+func APIKeyAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plain := strings.TrimSpace(c.GetHeader(APIKeyHeader))
+		if plain == "" {
+			c.Next()
+			return
+		}
+
+		key, err := model.AuthenticateAPIKey(plain, c.ClientIP())
+		if err != nil {
+			if errors.Is(err, model.ErrInvalidAPIKey) {
+				utils.SendError(c, http.StatusUnauthorized, err.Error())
+			} else {
+				utils.SendError(c, http.StatusInternalServerError, "校验 API 密钥失败: "+err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set(contextAPIKeyKey, key)
+		c.Next()
+
+		status := c.GetInt(utils.ResponseCodeKey)
+		if status == 0 {
+			status = c.Writer.Status()
+		}
+		if err := model.RecordAPIKeyAudit(key, c.Request.Method, c.Request.URL.Path, status, c.ClientIP()); err != nil {
+			log.Printf("记录 API 密钥审计日志失败: %v", err)
+		}
+	}
+}
+
+// CurrentAPIKey 获取当前请求使用的 API 密钥，未携带时返回 nil
+func CurrentAPIKey(c *gin.Context) *model.APIKey {
+	value, exists := c.Get(contextAPIKeyKey)
+	if !exists {
+		return nil
+	}
+	key, _ := value.(*model.APIKey)
+	return key
+}
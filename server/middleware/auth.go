@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"marku-server/config"
+	"marku-server/model"
+	"marku-server/types"
+	"marku-server/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contextUserKey    = "marku_user"
+	contextSessionKey = "marku_session"
+)
+
+// AuthRequired 要求请求携带有效的访问令牌
+func AuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := utils.ExtractBearerToken(c.GetHeader("Authorization"))
+		if token == "" {
+			utils.SendError(c, http.StatusUnauthorized, "请先登录")
+			c.Abort()
+			return
+		}
+
+		user, session, err := model.AuthenticateAccessToken(token)
+		if err != nil {
+			utils.SendError(c, http.StatusUnauthorized, "登录状态无效: "+err.Error())
+			c.Abort()
+			return
+		}
+
+		c.Set(contextUserKey, user)
+		c.Set(contextSessionKey, session)
+		c.Next()
+	}
+}
+
+// OptionalAuth 携带有效访问令牌时识别当前用户，未登录或令牌无效时按匿名访问处理
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := utils.ExtractBearerToken(c.GetHeader("Authorization")); token != "" {
+			if user, session, err := model.AuthenticateAccessToken(token); err == nil {
+				c.Set(contextUserKey, user)
+				c.Set(contextSessionKey, session)
+			}
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser 获取当前登录用户，未登录时返回 nil
+func CurrentUser(c *gin.Context) *model.User {
+	value, exists := c.Get(contextUserKey)
+	if !exists {
+		return nil
+	}
+	user, _ := value.(*model.User)
+	return user
+}
+
+// CurrentSession 获取当前登录会话，未登录或使用不绑定会话的旧令牌时返回 nil
+func CurrentSession(c *gin.Context) *model.Session {
+	value, exists := c.Get(contextSessionKey)
+	if !exists {
+		return nil
+	}
+	session, _ := value.(*model.Session)
+	return session
+}
+
+// CurrentSessionID 获取当前登录会话ID，没有会话时返回 0
+func CurrentSessionID(c *gin.Context) uint {
+	if session := CurrentSession(c); session != nil {
+		return session.ID
+	}
+	return 0
+}
+
+// AdminRequired 要求当前用户为管理员，需在 AuthRequired 之后使用
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil || user.Role != types.RoleAdmin {
+			utils.SendError(c, http.StatusForbidden, "需要管理员权限")
+			c.Abort()
+			return
+		}
+
+		if config.IsAdmin2FARequired() && !model.IsTwoFactorEnabled(user.ID) {
+			utils.SendError(c, http.StatusForbidden, "管理员需要先启用两步验证")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
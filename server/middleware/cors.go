@@ -3,9 +3,11 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"marku-server/config"
+	"marku-server/model"
 )
 
 // CORS中间件
@@ -25,6 +27,12 @@ func Cors() gin.HandlerFunc {
 			}
 		}
 
+		// 已登记站点各自的来源：请求通过 X-Marku-Site 头或 siteId 查询参数指明站点时只认该站点的列表；
+		// 预检请求与站点ID在请求体中的接口无法在此确定站点，由处理函数中的 ResolveSite 校验来源
+		if !allowed && origin != "" && model.IsOriginAllowedBySites(corsSiteID(c), origin) {
+			allowed = true
+		}
+
 		// 如果是本地文件访问，也允许
 		if origin == "" || (len(origin) >= 7 && origin[:7] == "file://") {
 			allowed = true
@@ -35,7 +43,7 @@ func Cors() gin.HandlerFunc {
 		}
 
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Marku-Device, X-Marku-Site")
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -46,3 +54,11 @@ func Cors() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// corsSiteID 请求指明的站点，取自 X-Marku-Site 头或 siteId 查询参数
+func corsSiteID(c *gin.Context) string {
+	if siteID := strings.TrimSpace(c.GetHeader("X-Marku-Site")); siteID != "" {
+		return siteID
+	}
+	return strings.TrimSpace(c.Query("siteId"))
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"marku-server/config"
+	"marku-server/model"
+	"marku-server/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResolveSite 校验站点已登记且请求来源在该站点的允许列表内，失败时直接返回错误响应。
+// 携带 API 密钥的请求不校验来源，改为要求密钥属于该站点并拥有 scope 权限。
+// 尚未登记任何站点时返回 (nil, true)，调用方按全局配置处理
+func ResolveSite(c *gin.Context, siteID, scope string) (*model.Site, bool) {
+	site, err := model.ResolveSite(siteID)
+	if err != nil {
+		if errors.Is(err, model.ErrUnknownSite) {
+			utils.SendError(c, http.StatusNotFound, err.Error())
+		} else {
+			utils.SendError(c, http.StatusServiceUnavailable, model.ErrSiteRegistryUnavailable.Error())
+		}
+		return nil, false
+	}
+
+	if key := CurrentAPIKey(c); key != nil {
+		if !key.HasScope(siteID, scope) {
+			utils.SendError(c, http.StatusForbidden, "API 密钥无权执行该操作: 需要 "+scope)
+			return nil, false
+		}
+		return site, true
+	}
+	if site == nil {
+		return nil, true
+	}
+
+	origin := c.GetHeader("Origin")
+	if origin == "" || strings.HasPrefix(origin, "file://") || site.AllowsOrigin(origin) || isGlobalOrigin(origin) {
+		return site, true
+	}
+
+	utils.SendError(c, http.StatusForbidden, "请求来源不在该站点的允许列表内")
+	return nil, false
+}
+
+func isGlobalOrigin(origin string) bool {
+	for _, allowedOrigin := range config.GetAllowedOrigins() {
+		if origin == allowedOrigin {
+			return true
+		}
+	}
+	return false
+}
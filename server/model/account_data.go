@@ -0,0 +1,332 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"marku-server/storage"
+	"marku-server/types"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 注销账户时评论的处理方式
+const (
+	CommentRetentionAnonymize = "anonymize" // 保留评论内容，清除作者信息
+	CommentRetentionRemove    = "remove"    // 删除评论
+)
+
+// DeletedUserDisplayName 匿名化后评论显示的作者名
+const DeletedUserDisplayName = "已注销用户"
+
+// UserDataExport 个人数据导出内容
+type UserDataExport struct {
+	ExportedAt       time.Time            `json:"exported_at"`
+	Email            string               `json:"email,omitempty"`
+	Profile          *User                `json:"profile,omitempty"`
+	TwoFactorEnabled bool                 `json:"two_factor_enabled"`
+	Comments         []Comment            `json:"comments"`
+	Sessions         []Session            `json:"sessions"`
+	Identities       []Identity           `json:"identities"`
+	Passkeys         []WebAuthnCredential `json:"passkeys"`
+	Reactions        []Reaction           `json:"reactions"`
+	Reports          []Report             `json:"reports"`
+	Mentions         []Mention            `json:"mentions"`    // 提及该用户或出自该用户评论的 @提及
+	Attachments      []Attachment         `json:"attachments"` // 该用户上传或其评论引用的图片
+	LoginAttempts    []LoginAttempt       `json:"login_attempts"`
+}
+
+// newUserDataExport 创建各列表均为空数组的导出结构，保证 JSON 中不出现 null
+func newUserDataExport(email string) *UserDataExport {
+	return &UserDataExport{
+		ExportedAt:    time.Now(),
+		Email:         email,
+		Comments:      []Comment{},
+		Sessions:      []Session{},
+		Identities:    []Identity{},
+		Passkeys:      []WebAuthnCredential{},
+		Reactions:     []Reaction{},
+		Reports:       []Report{},
+		Mentions:      []Mention{},
+		Attachments:   []Attachment{},
+		LoginAttempts: []LoginAttempt{},
+	}
+}
+
+// CollectUserData 汇总注册用户的个人数据
+func CollectUserData(user *User) (*UserDataExport, error) {
+	export := newUserDataExport("")
+	export.Profile = user
+	export.TwoFactorEnabled = IsTwoFactorEnabled(user.ID)
+	if user.Email != nil {
+		export.Email = *user.Email
+	}
+
+	if err := DB.Where("user_id = ?", strconv.FormatUint(uint64(user.ID), 10)).Order("created_at ASC").Find(&export.Comments).Error; err != nil {
+		return nil, err
+	}
+	for _, list := range []interface{}{&export.Sessions, &export.Identities, &export.Passkeys} {
+		if err := DB.Where("user_id = ?", user.ID).Order("created_at ASC").Find(list).Error; err != nil {
+			return nil, err
+		}
+	}
+	reactor := UserReactor(user.ID)
+	if err := DB.Where("reactor = ?", reactor).Order("created_at ASC").Find(&export.Reactions).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("reporter = ?", reactor).Order("created_at ASC").Find(&export.Reports).Error; err != nil {
+		return nil, err
+	}
+
+	commentIDs := commentIDsOf(export.Comments)
+	if err := DB.Where("user_id = ? OR comment_id IN ?", user.ID, commentIDs).Order("created_at ASC").Find(&export.Mentions).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("uploader = ? OR comment_id IN ?", reactor, commentIDs).Order("created_at ASC").Find(&export.Attachments).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("scope = ? AND attempt_key = ?", LoginScopeAccount, reactor).Find(&export.LoginAttempts).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// CollectGuestData 汇总游客以指定邮箱发表的评论及评论中的提及与图片；
+// 游客的表情回应与举报只记录匿名指纹，无法按邮箱识别，不在导出范围内
+func CollectGuestData(email string) (*UserDataExport, error) {
+	export := newUserDataExport(email)
+	if err := guestCommentsQuery(DB, email).Order("created_at ASC").Find(&export.Comments).Error; err != nil {
+		return nil, err
+	}
+	commentIDs := commentIDsOf(export.Comments)
+	if len(commentIDs) == 0 {
+		return export, nil
+	}
+	if err := DB.Where("comment_id IN ?", commentIDs).Order("created_at ASC").Find(&export.Mentions).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("comment_id IN ?", commentIDs).Order("created_at ASC").Find(&export.Attachments).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// DeleteUserAccount 注销注册用户：按 retention 处理评论，删除会话、第三方账户、两步验证、通行密钥、提及、表情回应、举报、
+// 失败计数、上传的图片（含存储中的文件）与用户记录，并解除其创建的 API 密钥与账户的关联
+func DeleteUserAccount(user *User, retention string) error {
+	var attachments []Attachment
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		comments := tx.Model(&Comment{}).Where("user_id = ?", strconv.FormatUint(uint64(user.ID), 10))
+		removed, err := applyCommentRetention(tx, comments, retention)
+		if err != nil {
+			return err
+		}
+
+		reactor := UserReactor(user.ID)
+		attachmentQuery := tx.Where("uploader = ?", reactor)
+		if len(removed) > 0 {
+			attachmentQuery = attachmentQuery.Or("comment_id IN ?", removed)
+		}
+		if err := attachmentQuery.Find(&attachments).Error; err != nil {
+			return err
+		}
+		if err := deleteAttachmentRecords(tx, attachments); err != nil {
+			return err
+		}
+
+		for _, record := range []interface{}{&Session{}, &Identity{}, &TwoFactor{}, &RecoveryCode{}, &WebAuthnCredential{}, &WebAuthnChallenge{}, &Mention{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(record).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("link_user_id = ?", user.ID).Delete(&OAuthState{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("reactor = ?", reactor).Delete(&Reaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("reporter = ?", reactor).Delete(&Report{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope = ? AND attempt_key = ?", LoginScopeAccount, reactor).Delete(&LoginAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&APIKey{}).Where("created_by = ?", user.ID).Update("created_by", 0).Error; err != nil {
+			return err
+		}
+		if user.Email != nil {
+			if err := deleteEmailRecords(tx, *user.Email); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&User{}, user.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+	removeAttachmentFiles(attachments)
+	return nil
+}
+
+// DeleteGuestData 删除游客以指定邮箱留下的数据：按 retention 处理评论，删除同邮箱的验证码、失败计数与游客用户记录
+func DeleteGuestData(email, retention string) (int64, error) {
+	var affected int64
+	var attachments []Attachment
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		comments := guestCommentsQuery(tx.Model(&Comment{}), email).Session(&gorm.Session{})
+		if err := comments.Count(&affected).Error; err != nil {
+			return err
+		}
+		removed, err := applyCommentRetention(tx, comments, retention)
+		if err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			if err := tx.Where("comment_id IN ?", removed).Find(&attachments).Error; err != nil {
+				return err
+			}
+			if err := deleteAttachmentRecords(tx, attachments); err != nil {
+				return err
+			}
+		}
+		if err := deleteEmailRecords(tx, email); err != nil {
+			return err
+		}
+		return tx.Where("email = ? AND role = ?", email, types.RoleGuest).Delete(&User{}).Error
+	})
+	if err != nil {
+		return affected, err
+	}
+	removeAttachmentFiles(attachments)
+	return affected, nil
+}
+
+// deleteEmailRecords 删除按邮箱记录的验证码与验证码失败计数
+func deleteEmailRecords(tx *gorm.DB, email string) error {
+	if err := tx.Where("email = ?", email).Delete(&EmailVerificationCode{}).Error; err != nil {
+		return err
+	}
+	// 验证码失败计数的键为 "<邮箱>:<用途>"
+	return tx.Where("scope = ? AND attempt_key LIKE ? ESCAPE '!'", LoginScopeEmailCode, escapeLikePattern(email)+":%").Delete(&LoginAttempt{}).Error
+}
+
+// deleteAttachmentRecords 删除附件记录，存储中的文件在事务提交后由 removeAttachmentFiles 删除
+func deleteAttachmentRecords(tx *gorm.DB, attachments []Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(attachments))
+	for _, attachment := range attachments {
+		ids = append(ids, attachment.ID)
+	}
+	return tx.Delete(&Attachment{}, ids).Error
+}
+
+// removeAttachmentFiles 删除附件在存储中的原图与缩略图，失败时只记录日志
+func removeAttachmentFiles(attachments []Attachment) {
+	if storage.Default == nil {
+		return
+	}
+	ctx := context.Background()
+	for _, attachment := range attachments {
+		for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+			if key == "" {
+				continue
+			}
+			if err := storage.Default.Delete(ctx, key); err != nil {
+				log.Printf("删除附件文件 %s 失败: %v", key, err)
+			}
+		}
+	}
+}
+
+// guestCommentsQuery 游客评论没有关联用户，仅能通过邮箱快照识别
+func guestCommentsQuery(db *gorm.DB, email string) *gorm.DB {
+	return db.Where("email = ? AND (user_id = '' OR user_id IS NULL)", email)
+}
+
+// applyCommentRetention 按 retention 处理评论，返回被删除的评论ID；
+// 删除时其他人的回复改挂到被删评论最近的未删除祖先下，并清理被删评论的提及、表情回应与举报
+func applyCommentRetention(tx *gorm.DB, comments *gorm.DB, retention string) ([]uint, error) {
+	switch retention {
+	case CommentRetentionRemove:
+		var rows []struct {
+			ID     uint
+			Parent int
+		}
+		if err := comments.Session(&gorm.Session{}).Select("id, parent").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		parents := make(map[uint]int, len(rows))
+		ids := make([]uint, 0, len(rows))
+		for _, row := range rows {
+			parents[row.ID] = row.Parent
+			ids = append(ids, row.ID)
+		}
+
+		for _, id := range ids {
+			ancestor := parents[id]
+			for steps := 0; ancestor > 0 && steps < len(ids); steps++ {
+				parent, removed := parents[uint(ancestor)]
+				if !removed {
+					break
+				}
+				ancestor = parent
+			}
+			if _, removed := parents[uint(ancestor)]; removed {
+				ancestor = 0
+			}
+			err := tx.Model(&Comment{}).Where("parent = ? AND id NOT IN ?", id, ids).Update("parent", ancestor).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, record := range []interface{}{&Mention{}, &Reaction{}, &Report{}} {
+			if err := tx.Where("comment_id IN ?", ids).Delete(record).Error; err != nil {
+				return nil, err
+			}
+		}
+		if err := tx.Model(&Mention{}).Where("target_comment_id IN ?", ids).Update("target_comment_id", 0).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Delete(&Comment{}, ids).Error; err != nil {
+			return nil, err
+		}
+		return ids, nil
+	case CommentRetentionAnonymize:
+		return nil, comments.Updates(map[string]interface{}{
+			"user_id":  "",
+			"username": DeletedUserDisplayName,
+			"email":    nil,
+			"url":      nil,
+			"avatar":   nil,
+			"ip":       nil,
+			"ua":       nil,
+			"location": nil,
+			// 由 UA 解析出的字段同样可用于识别作者
+			"browser":         "",
+			"browser_version": "",
+			"os":              "",
+			"os_version":      "",
+			"device":          "",
+		}).Error
+	default:
+		return nil, fmt.Errorf("无效的评论处理方式: %s", retention)
+	}
+}
+
+// commentIDsOf 提取评论ID列表
+func commentIDsOf(comments []Comment) []uint {
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	return ids
+}
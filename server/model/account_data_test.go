@@ -0,0 +1,239 @@
+package model
+
+import (
+	"context"
+	"marku-server/internal/testutil"
+	"marku-server/storage"
+	"sync"
+	"testing"
+)
+
+// memoryStorage 记录被删除键的内存存储
+type memoryStorage struct {
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (m *memoryStorage) Put(context.Context, string, []byte, string) error { return nil }
+
+func (m *memoryStorage) Get(context.Context, string) (*storage.Object, error) {
+	return nil, storage.ErrNotFound
+}
+
+func (m *memoryStorage) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleted = append(m.deleted, key)
+	return nil
+}
+
+func useMemoryStorage(t *testing.T) *memoryStorage {
+	t.Helper()
+	previous := storage.Default
+	store := &memoryStorage{}
+	storage.Default = store
+	t.Cleanup(func() { storage.Default = previous })
+	return store
+}
+
+func setupAccountDataDB(t *testing.T) {
+	t.Helper()
+	testutil.SetupDB(t, &DB, &User{}, &Comment{}, &EmailVerificationCode{}, &Session{}, &Identity{}, &OAuthState{}, &TwoFactor{}, &RecoveryCode{},
+		&WebAuthnCredential{}, &WebAuthnChallenge{}, &LoginAttempt{}, &APIKey{}, &Mention{}, &Reaction{}, &Attachment{}, &Report{})
+}
+
+func createComment(t *testing.T, userID string, parent int) Comment {
+	t.Helper()
+	comment := Comment{SiteID: "blog", Mark: "post", Content: "hi", Status: 1, UserID: userID, Parent: parent}
+	if err := DB.Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return comment
+}
+
+func TestCollectUserData(t *testing.T) {
+	setupAccountDataDB(t)
+	user := User{Username: "alice"}
+	DB.Create(&user)
+	reactor := UserReactor(user.ID)
+
+	own := createComment(t, "1", 0)
+	other := createComment(t, "2", 0)
+	DB.Create(&LoginAttempt{Scope: LoginScopeAccount, Key: reactor, Failures: 1})
+	DB.Create(&Reaction{SiteID: "blog", Mark: "post", CommentID: other.ID, Reactor: reactor, Emoji: "👍"})
+	DB.Create(&Report{CommentID: other.ID, SiteID: "blog", Reporter: reactor, Reason: ReportReasonSpam})
+	DB.Create(&Mention{CommentID: other.ID, SiteID: "blog", Mark: "post", Username: "alice", UserID: user.ID})
+	DB.Create(&Mention{CommentID: own.ID, SiteID: "blog", Mark: "post", Username: "bob"})
+	DB.Create(&Mention{CommentID: other.ID, SiteID: "blog", Mark: "post", Username: "carol"})
+	DB.Create(&Attachment{SiteID: "blog", StorageKey: "a.png", Uploader: reactor})
+	DB.Create(&Attachment{SiteID: "blog", StorageKey: "b.png", Uploader: VisitorReactor("x")})
+
+	export, err := CollectUserData(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Comments) != 1 || len(export.LoginAttempts) != 1 {
+		t.Fatalf("comments %d login attempts %d", len(export.Comments), len(export.LoginAttempts))
+	}
+	if len(export.Reactions) != 1 {
+		t.Fatalf("reactions = %d, want 1", len(export.Reactions))
+	}
+	if len(export.Reports) != 1 {
+		t.Fatalf("reports = %d, want 1", len(export.Reports))
+	}
+	if len(export.Mentions) != 2 {
+		t.Fatalf("mentions = %d, want 2", len(export.Mentions))
+	}
+	if len(export.Attachments) != 1 || export.Attachments[0].StorageKey != "a.png" {
+		t.Fatalf("attachments = %+v", export.Attachments)
+	}
+}
+
+func TestDeleteUserAccountRemovesComments(t *testing.T) {
+	setupAccountDataDB(t)
+	store := useMemoryStorage(t)
+	user := User{Username: "alice"}
+	DB.Create(&user)
+	reactor := UserReactor(user.ID)
+
+	// a(用户) ← b(他人) ← c(用户) ← d(他人)，以及 e(用户) ← f(用户) ← g(他人)
+	a := createComment(t, "1", 0)
+	b := createComment(t, "2", int(a.ID))
+	c := createComment(t, "1", int(b.ID))
+	d := createComment(t, "2", int(c.ID))
+	e := createComment(t, "1", 0)
+	f := createComment(t, "1", int(e.ID))
+	g := createComment(t, "2", int(f.ID))
+
+	DB.Create(&Reaction{SiteID: "blog", Mark: "post", CommentID: a.ID, Reactor: VisitorReactor("x"), Emoji: "👍"})
+	DB.Create(&Report{CommentID: c.ID, SiteID: "blog", Reporter: VisitorReactor("x"), Reason: ReportReasonSpam})
+	DB.Create(&Mention{CommentID: d.ID, SiteID: "blog", Mark: "post", Username: "alice", TargetCommentID: c.ID})
+	DB.Create(&Attachment{SiteID: "blog", StorageKey: "own.png", ThumbnailKey: "own_thumb.png", Uploader: reactor})
+	DB.Create(&Attachment{SiteID: "blog", StorageKey: "kept.png", ThumbnailKey: "kept_thumb.png", Uploader: VisitorReactor("x"), CommentID: b.ID})
+	DB.Create(&APIKey{SiteID: "blog", Name: "k", Prefix: "p", KeyHash: "h", CreatedBy: user.ID})
+
+	if err := DeleteUserAccount(&user, CommentRetentionRemove); err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining []Comment
+	DB.Order("id ASC").Find(&remaining)
+	parents := map[uint]int{}
+	for _, comment := range remaining {
+		parents[comment.ID] = comment.Parent
+	}
+	want := map[uint]int{b.ID: 0, d.ID: int(b.ID), g.ID: 0}
+	if len(parents) != len(want) {
+		t.Fatalf("remaining comments = %v, want %v", parents, want)
+	}
+	for id, parent := range want {
+		if parents[id] != parent {
+			t.Fatalf("comment %d parent = %d, want %d (all: %v)", id, parents[id], parent, parents)
+		}
+	}
+
+	var orphans int64
+	DB.Model(&Comment{}).Where("parent <> 0 AND parent NOT IN (?)", DB.Model(&Comment{}).Select("id")).Count(&orphans)
+	if orphans != 0 {
+		t.Fatalf("%d replies point at deleted parents", orphans)
+	}
+
+	var count int64
+	if DB.Model(&Reaction{}).Count(&count); count != 0 {
+		t.Fatalf("%d reactions on removed comments remain", count)
+	}
+	if DB.Model(&Report{}).Count(&count); count != 0 {
+		t.Fatalf("%d reports on removed comments remain", count)
+	}
+	var mention Mention
+	DB.First(&mention)
+	if mention.TargetCommentID != 0 {
+		t.Fatalf("mention still targets removed comment %d", mention.TargetCommentID)
+	}
+
+	var attachments []Attachment
+	DB.Find(&attachments)
+	if len(attachments) != 1 || attachments[0].StorageKey != "kept.png" {
+		t.Fatalf("attachments = %+v", attachments)
+	}
+	if len(store.deleted) != 2 || store.deleted[0] != "own.png" || store.deleted[1] != "own_thumb.png" {
+		t.Fatalf("deleted blobs = %v", store.deleted)
+	}
+
+	var key APIKey
+	DB.First(&key)
+	if key.CreatedBy != 0 {
+		t.Fatalf("api key still references deleted user %d", key.CreatedBy)
+	}
+	if DB.Model(&User{}).Where("id = ?", user.ID).Count(&count); count != 0 {
+		t.Fatal("user record not deleted")
+	}
+}
+
+func TestDeleteUserAccountAnonymizesComments(t *testing.T) {
+	setupAccountDataDB(t)
+	user := User{Username: "alice"}
+	DB.Create(&user)
+
+	email, ip, ua := "alice@example.com", "203.0.113.7", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0"
+	comment := Comment{SiteID: "blog", Mark: "post", Content: "kept", Status: 1, UserID: "1", Username: "alice", Email: &email, IP: &ip, UA: &ua,
+		Browser: "Chrome", BrowserVersion: "120.0", OS: "Windows", OSVersion: "10", Device: "desktop"}
+	if err := DB.Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteUserAccount(&user, CommentRetentionAnonymize); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored Comment
+	if err := DB.First(&stored, comment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Content != "kept" || stored.UserID != "" || stored.Username != DeletedUserDisplayName {
+		t.Fatalf("comment not anonymized: %+v", stored)
+	}
+	if stored.Email != nil || stored.IP != nil || stored.UA != nil {
+		t.Fatalf("author contact or network details kept: %+v", stored)
+	}
+	if stored.Browser != "" || stored.BrowserVersion != "" || stored.OS != "" || stored.OSVersion != "" || stored.Device != "" {
+		t.Fatalf("UA-derived fields kept: %+v", stored)
+	}
+}
+
+func TestDeleteGuestData(t *testing.T) {
+	setupAccountDataDB(t)
+	store := useMemoryStorage(t)
+	email := "guest_1@example.com"
+	comment := Comment{SiteID: "blog", Mark: "post", Content: "hi", Email: &email}
+	DB.Create(&comment)
+	DB.Create(&Attachment{SiteID: "blog", StorageKey: "g.png", ThumbnailKey: "g_thumb.png", Uploader: VisitorReactor("x"), CommentID: comment.ID})
+	DB.Create(&LoginAttempt{Scope: LoginScopeEmailCode, Key: email + ":data_request", Failures: 2})
+	DB.Create(&LoginAttempt{Scope: LoginScopeEmailCode, Key: "guestX1@example.com:data_request", Failures: 2})
+
+	export, err := CollectGuestData(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Comments) != 1 || len(export.Attachments) != 1 {
+		t.Fatalf("export comments %d attachments %d", len(export.Comments), len(export.Attachments))
+	}
+
+	affected, err := DeleteGuestData(email, CommentRetentionRemove)
+	if err != nil || affected != 1 {
+		t.Fatalf("DeleteGuestData = %d, %v", affected, err)
+	}
+	var count int64
+	if DB.Model(&Attachment{}).Count(&count); count != 0 {
+		t.Fatal("attachment record not deleted")
+	}
+	if len(store.deleted) != 2 {
+		t.Fatalf("deleted blobs = %v", store.deleted)
+	}
+	// LIKE 通配符需转义，不能误删其他邮箱的计数
+	var attempts []LoginAttempt
+	DB.Find(&attempts)
+	if len(attempts) != 1 || attempts[0].Key != "guestX1@example.com:data_request" {
+		t.Fatalf("login attempts = %+v", attempts)
+	}
+}
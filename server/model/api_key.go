@@ -0,0 +1,186 @@
+package model
+
+import (
+	"errors"
+	"marku-server/types"
+	"marku-server/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// API 密钥权限范围
+const (
+	ScopeCountersRead     = "counters:read"
+	ScopeCountersWrite    = "counters:write"
+	ScopeCommentsRead     = "comments:read"
+	ScopeCommentsWrite    = "comments:write"
+	ScopeCommentsModerate = "comments:moderate"
+)
+
+// APIKeyScopes 全部可分配的权限范围
+var APIKeyScopes = []string{ScopeCountersRead, ScopeCountersWrite, ScopeCommentsRead, ScopeCommentsWrite, ScopeCommentsModerate}
+
+const (
+	apiKeyPrefix     = "mk_"
+	apiKeyByteLength = 32
+)
+
+// ErrInvalidAPIKey API 密钥不存在、已吊销或已过期
+var ErrInvalidAPIKey = errors.New("API 密钥无效或已失效")
+
+// APIKey 站点级 API 密钥，仅保存摘要
+type APIKey struct {
+	ID         uint             `gorm:"primaryKey" json:"id"`
+	SiteID     string           `gorm:"size:100;not null;index" json:"site_id"`
+	Name       string           `gorm:"size:100;not null" json:"name"`
+	Prefix     string           `gorm:"size:16;not null" json:"prefix"` // 明文前若干位，便于在列表中辨认
+	KeyHash    string           `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     types.StringList `gorm:"type:text" json:"scopes"`
+	CreatedBy  uint             `gorm:"default:0" json:"created_by"`
+	LastUsedAt *time.Time       `json:"last_used_at,omitempty"`
+	LastUsedIP *string          `gorm:"size:45" json:"last_used_ip,omitempty"`
+	ExpiresAt  *time.Time       `gorm:"index" json:"expires_at,omitempty"`
+	RevokedAt  *time.Time       `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// APIKeyAudit API 密钥调用审计记录
+type APIKeyAudit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	APIKeyID  uint      `gorm:"not null;index" json:"api_key_id"`
+	SiteID    string    `gorm:"size:100;not null" json:"site_id"`
+	Method    string    `gorm:"size:10;not null" json:"method"`
+	Path      string    `gorm:"size:500;not null" json:"path"`
+	Status    int       `gorm:"not null" json:"status"`
+	IP        *string   `gorm:"size:45" json:"ip,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// IsActive 判断密钥是否可用
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
+
+// HasScope 判断密钥是否拥有指定站点的某项权限
+func (k *APIKey) HasScope(siteID, scope string) bool {
+	if k == nil || k.SiteID != siteID {
+		return false
+	}
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidAPIKeyScope 判断权限范围是否合法
+func IsValidAPIKeyScope(scope string) bool {
+	for _, valid := range APIKeyScopes {
+		if valid == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKey 创建 API 密钥并返回明文，明文只在创建时返回一次
+func CreateAPIKey(siteID, name string, scopes []string, expiresAt *time.Time, createdBy uint) (*APIKey, string, error) {
+	token, err := utils.GenerateRandomToken(apiKeyByteLength)
+	if err != nil {
+		return nil, "", err
+	}
+	plain := apiKeyPrefix + token
+
+	key := &APIKey{
+		SiteID:    siteID,
+		Name:      name,
+		Prefix:    plain[:len(apiKeyPrefix)+6],
+		KeyHash:   utils.HashToken(plain),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+	}
+	if err := DB.Create(key).Error; err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+// AuthenticateAPIKey 校验明文密钥并记录最近使用时间
+func AuthenticateAPIKey(plain, ip string) (*APIKey, error) {
+	var key APIKey
+	if err := DB.Where("key_hash = ?", utils.HashToken(plain)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if !key.IsActive() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	key.LastUsedAt = &now
+	key.LastUsedIP = optionalString(truncateString(ip, 45))
+	_ = DB.Model(&APIKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": key.LastUsedIP,
+	}).Error
+	return &key, nil
+}
+
+// ListAPIKeys 查询 API 密钥，siteID 为空时返回全部
+func ListAPIKeys(siteID string) ([]APIKey, error) {
+	query := DB.Order("id DESC")
+	if siteID != "" {
+		query = query.Where("site_id = ?", siteID)
+	}
+	var keys []APIKey
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey 吊销 API 密钥
+func RevokeAPIKey(id uint) error {
+	result := DB.Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RecordAPIKeyAudit 记录一次 API 密钥调用
+func RecordAPIKeyAudit(key *APIKey, method, path string, status int, ip string) error {
+	return DB.Create(&APIKeyAudit{
+		APIKeyID: key.ID,
+		SiteID:   key.SiteID,
+		Method:   method,
+		Path:     truncateString(path, 500),
+		Status:   status,
+		IP:       optionalString(truncateString(ip, 45)),
+	}).Error
+}
+
+// ListAPIKeyAudits 分页查询某个密钥的调用记录
+func ListAPIKeyAudits(keyID uint, page, pageSize int) ([]APIKeyAudit, int64, error) {
+	query := DB.Model(&APIKeyAudit{}).Where("api_key_id = ?", keyID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var audits []APIKeyAudit
+	if err := query.Order("id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&audits).Error; err != nil {
+		return nil, 0, err
+	}
+	return audits, total, nil
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+)
+
+// Attachment 评论图片附件，上传时 CommentID 为 0，提交引用它的评论后关联
+type Attachment struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       string    `gorm:"size:100;not null;index" json:"site_id"`
+	StorageKey   string    `gorm:"size:255;not null;uniqueIndex" json:"storage_key"`
+	ThumbnailKey string    `gorm:"size:255;not null" json:"thumbnail_key"`
+	ContentType  string    `gorm:"size:50;not null" json:"content_type"`
+	Size         int       `gorm:"not null" json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Uploader     string    `gorm:"size:64;not null;index" json:"-"` // user:<ID> 或 visitor:<指纹>
+	UploaderIP   string    `gorm:"size:64;index" json:"-"`          // 上传者 IP 的匿名标识
+	CommentID    uint      `gorm:"default:0;index" json:"comment_id"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// CreateAttachment 保存附件记录
+func CreateAttachment(attachment *Attachment) error {
+	return DB.Create(attachment).Error
+}
+
+// CountRecentAttachments 统计上传者在指定时间之后的上传数量
+func CountRecentAttachments(uploader string, since time.Time) (int64, error) {
+	var count int64
+	err := DB.Model(&Attachment{}).Where("uploader = ? AND created_at >= ?", uploader, since).Count(&count).Error
+	return count, err
+}
+
+// CountRecentAttachmentsByIP 统计同一 IP 在指定时间之后的上传数量
+func CountRecentAttachmentsByIP(ipHash string, since time.Time) (int64, error) {
+	var count int64
+	err := DB.Model(&Attachment{}).Where("uploader_ip = ? AND created_at >= ?", ipHash, since).Count(&count).Error
+	return count, err
+}
+
+// CountUnlinkedAttachmentsByIP 统计同一 IP 上传后尚未被评论引用的附件数量
+func CountUnlinkedAttachmentsByIP(ipHash string) (int64, error) {
+	var count int64
+	err := DB.Model(&Attachment{}).Where("uploader_ip = ? AND comment_id = 0", ipHash).Count(&count).Error
+	return count, err
+}
+
+// LinkAttachments 将评论中引用的同站点未关联附件关联到评论
+func LinkAttachments(commentID uint, siteID string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return DB.Model(&Attachment{}).
+		Where("site_id = ? AND comment_id = 0 AND storage_key IN ?", siteID, keys).
+		Update("comment_id", commentID).Error
+}
+
+// ListAttachmentsByCommentIDs 批量查询评论关联的附件，按评论ID与存储键索引
+func ListAttachmentsByCommentIDs(commentIDs []uint) (map[uint]map[string]Attachment, error) {
+	grouped := make(map[uint]map[string]Attachment)
+	if len(commentIDs) == 0 {
+		return grouped, nil
+	}
+	var attachments []Attachment
+	if err := DB.Where("comment_id IN ?", commentIDs).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		if grouped[attachment.CommentID] == nil {
+			grouped[attachment.CommentID] = make(map[string]Attachment)
+		}
+		grouped[attachment.CommentID][attachment.StorageKey] = attachment
+	}
+	return grouped, nil
+}
+
+// ListOrphanAttachments 查询上传后一直未被评论引用，或所属评论已删除的附件
+func ListOrphanAttachments(before time.Time, limit int) ([]Attachment, error) {
+	var attachments []Attachment
+	err := DB.Where("created_at < ? AND (comment_id = 0 OR comment_id NOT IN (?))", before, DB.Model(&Comment{}).Select("id")).Order("id ASC").Limit(limit).Find(&attachments).Error
+	return attachments, err
+}
+
+// DeleteAttachment 删除附件记录
+func DeleteAttachment(id uint) error {
+	return DB.Delete(&Attachment{}, id).Error
+}
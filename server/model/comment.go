@@ -1,7 +1,10 @@
 package model
 
 import (
+	"marku-server/config"
 	"marku-server/types"
+	"marku-server/utils"
+	"time"
 )
 
 // Comment 评论数据模型
@@ -23,5 +26,191 @@ type Comment struct {
 	Email    *string `gorm:"size:255" json:"email,omitempty"`       // 评论作者快照：邮箱
 	URL      *string `gorm:"size:500" json:"url,omitempty"`        // 评论作者快照：网址
 	Avatar   *string `gorm:"size:500" json:"avatar,omitempty"`      // 评论作者快照：头像
+	Browser        string `gorm:"size:50" json:"browser,omitempty"`         // 由 UA 解析的浏览器
+	BrowserVersion string `gorm:"size:50" json:"browser_version,omitempty"` // 浏览器版本
+	OS             string `gorm:"size:50" json:"os,omitempty"`              // 操作系统
+	OSVersion      string `gorm:"size:50" json:"os_version,omitempty"`      // 操作系统版本
+	Device         string `gorm:"size:20" json:"device,omitempty"`          // 设备类型：desktop / mobile / tablet / bot
+	Pinned         bool   `gorm:"default:false;index" json:"pinned"`        // 是否置顶，置顶评论固定显示在第一页顶部
+	PinOrder       int    `gorm:"default:0" json:"pin_order"`               // 置顶顺序，数值小的在前
 	types.BaseModel
-}
\ No newline at end of file
+}
+
+// commentThreadIndex 评论列表游标分页使用的组合索引，created_at 来自内嵌的 BaseModel，无法用结构体标签声明
+const commentThreadIndex = "idx_comment_thread"
+
+// initCommentIndexes 建立 (site_id, mark, status, created_at) 组合索引
+func initCommentIndexes() error {
+	if DB.Migrator().HasIndex(&Comment{}, commentThreadIndex) {
+		return nil
+	}
+	return DB.Exec("CREATE INDEX " + commentThreadIndex + " ON comments (site_id, mark, status, created_at)").Error
+}
+
+// ApplyUserAgent 按评论的 UA 填充浏览器、操作系统与设备类型
+func (c *Comment) ApplyUserAgent() {
+	ua := ""
+	if c.UA != nil {
+		ua = *c.UA
+	}
+	info := utils.ParseUserAgent(ua)
+	c.Browser = truncateString(info.Browser, 50)
+	c.BrowserVersion = truncateString(info.BrowserVersion, 50)
+	c.OS = truncateString(info.OS, 50)
+	c.OSVersion = truncateString(info.OSVersion, 50)
+	c.Device = info.Device
+}
+
+// BackfillCommentUserAgents 为已有评论补充 UA 解析结果，force 为 true 时重新解析全部评论（用于规则更新后）
+func BackfillCommentUserAgents(force bool, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	updated := 0
+	lastID := uint(0)
+	for {
+		query := DB.Where("id > ? AND ua IS NOT NULL AND ua <> ''", lastID)
+		if !force {
+			query = query.Where("device = '' OR device IS NULL")
+		}
+
+		var comments []Comment
+		if err := query.Order("id ASC").Limit(batchSize).Find(&comments).Error; err != nil {
+			return updated, err
+		}
+		if len(comments) == 0 {
+			return updated, nil
+		}
+
+		for i := range comments {
+			comments[i].ApplyUserAgent()
+			if err := DB.Model(&Comment{}).Where("id = ?", comments[i].ID).Updates(map[string]interface{}{
+				"browser":         comments[i].Browser,
+				"browser_version": comments[i].BrowserVersion,
+				"os":              comments[i].OS,
+				"os_version":      comments[i].OSVersion,
+				"device":          comments[i].Device,
+			}).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		lastID = comments[len(comments)-1].ID
+	}
+}
+
+// GetCommentByID 根据主键查询评论
+func GetCommentByID(id uint) (*Comment, error) {
+	var comment Comment
+	if err := DB.First(&comment, id).Error; err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdateCommentStatus 更新评论审核状态
+func UpdateCommentStatus(comment *Comment, status int) error {
+	if err := DB.Model(comment).Update("status", status).Error; err != nil {
+		return err
+	}
+	comment.Status = status
+	return nil
+}
+
+// SetCommentPinned 置顶或取消置顶评论
+func SetCommentPinned(comment *Comment, pinned bool, order int) error {
+	if !pinned {
+		order = 0
+	}
+	if err := DB.Model(comment).Updates(map[string]interface{}{"pinned": pinned, "pin_order": order}).Error; err != nil {
+		return err
+	}
+	comment.Pinned = pinned
+	comment.PinOrder = order
+	return nil
+}
+
+// SetCommentFeatured 设置或取消精选评论
+func SetCommentFeatured(comment *Comment, featured bool) error {
+	if err := DB.Model(comment).Update("featured", featured).Error; err != nil {
+		return err
+	}
+	comment.Featured = featured
+	return nil
+}
+
+// CommentCount 单个页面的评论统计
+type CommentCount struct {
+	Mark     string     `json:"mark"`
+	Count    int64      `json:"count"`             // 已通过的评论总数（含回复）
+	Replies  *int64     `json:"replies,omitempty"` // 其中的回复数
+	LatestAt *time.Time `json:"latest_at,omitempty"`
+}
+
+// BatchCountApprovedComments 批量统计页面的已通过评论数，未出现的页面计为 0。
+// includeLatest 为 true 时额外查询每个页面最新一条评论的时间
+func BatchCountApprovedComments(siteID string, marks []string, includeReplies, includeLatest bool) ([]CommentCount, error) {
+	var rows []struct {
+		Mark     string
+		Total    int64
+		Replies  int64
+		LatestID uint
+	}
+	err := DB.Model(&Comment{}).
+		Select("mark, COUNT(*) AS total, SUM(CASE WHEN parent <> 0 THEN 1 ELSE 0 END) AS replies, MAX(id) AS latest_id").
+		Where("site_id = ? AND mark IN ? AND status = ?", siteID, marks, config.GetApprovedCommentStatusValue()).
+		Group("mark").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 自增 ID 与创建顺序一致，用最大 ID 定位最新评论，避免不同数据库对 MAX(created_at) 的类型差异
+	latestByID := make(map[uint]time.Time)
+	if includeLatest && len(rows) > 0 {
+		ids := make([]uint, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.LatestID)
+		}
+		var latest []Comment
+		if err := DB.Select("id, created_at").Where("id IN ?", ids).Find(&latest).Error; err != nil {
+			return nil, err
+		}
+		for _, comment := range latest {
+			latestByID[comment.ID] = comment.CreatedAt
+		}
+	}
+
+	counts := make(map[string]CommentCount, len(rows))
+	for _, row := range rows {
+		count := CommentCount{Mark: row.Mark, Count: row.Total}
+		if includeReplies {
+			replies := row.Replies
+			count.Replies = &replies
+		}
+		if latestAt, ok := latestByID[row.LatestID]; ok {
+			count.LatestAt = &latestAt
+		}
+		counts[row.Mark] = count
+	}
+
+	result := make([]CommentCount, 0, len(marks))
+	seen := make(map[string]struct{}, len(marks))
+	for _, mark := range marks {
+		if _, ok := seen[mark]; ok {
+			continue
+		}
+		seen[mark] = struct{}{}
+		count, ok := counts[mark]
+		if !ok {
+			count = CommentCount{Mark: mark}
+			if includeReplies {
+				var zero int64
+				count.Replies = &zero
+			}
+		}
+		result = append(result, count)
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"marku-server/config"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CommenterStat 评论者在统计窗口内的评论数，登录用户按 UserID 聚合，游客按邮箱聚合
+type CommenterStat struct {
+	UserID   string
+	Email    string
+	Count    int64
+	LatestID uint // 该评论者最新一条评论，用于取展示用的昵称与头像
+}
+
+// ListRecentApprovedComments 查询最新的已通过评论，mark 为空时查询整个站点
+func ListRecentApprovedComments(siteID, mark string, limit int) ([]Comment, error) {
+	query := DB.Where("site_id = ? AND status = ?", siteID, config.GetApprovedCommentStatusValue())
+	if mark != "" {
+		query = query.Where("mark = ?", mark)
+	}
+
+	var comments []Comment
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// ListTopCommenters 统计站点内评论最多的评论者，since 为零值时统计全部时间；
+// 登录用户与游客分别在数据库中排序截取前 limit 名后再合并
+func ListTopCommenters(siteID string, since time.Time, limit int) ([]CommenterStat, error) {
+	query := DB.Model(&Comment{}).Where("site_id = ? AND status = ?", siteID, config.GetApprovedCommentStatusValue())
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+
+	var users []CommenterStat
+	err := query.Session(&gorm.Session{}).
+		Select("user_id, COUNT(*) AS count, MAX(id) AS latest_id").
+		Where("user_id <> ''").
+		Group("user_id").
+		Order("count DESC, latest_id DESC").
+		Limit(limit).
+		Scan(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var guests []CommenterStat
+	err = query.Session(&gorm.Session{}).
+		Select("LOWER(email) AS email, COUNT(*) AS count, MAX(id) AS latest_id").
+		Where("(user_id = '' OR user_id IS NULL) AND email IS NOT NULL AND email <> ''").
+		Group("LOWER(email)").
+		Order("count DESC, latest_id DESC").
+		Limit(limit).
+		Scan(&guests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := append(users, guests...)
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].LatestID > stats[j].LatestID
+	})
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
+
+// GetCommentsByIDs 按主键批量查询评论
+func GetCommentsByIDs(ids []uint) ([]Comment, error) {
+	var comments []Comment
+	if len(ids) == 0 {
+		return comments, nil
+	}
+	if err := DB.Where("id IN ?", ids).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"marku-server/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestListTopCommenters(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Comment{})
+
+	create := func(userID, email string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			comment := Comment{SiteID: "blog", Mark: "post", Content: "hi", Status: 1, UserID: userID}
+			if email != "" {
+				value := email
+				comment.Email = &value
+			}
+			if err := DB.Create(&comment).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// 登录用户与游客各有大量评论者，合并前每边只应取前 limit 名
+	for i := 1; i <= 20; i++ {
+		create(fmt.Sprint(i), "", 1)
+		create("", fmt.Sprintf("guest%d@example.com", i), 1)
+	}
+	create("100", "", 5)
+	create("", "Top@Example.com", 3)
+	create("", "top@example.com", 1)
+	pending := Comment{SiteID: "blog", Mark: "post", Content: "hi", Status: 0, UserID: "200"}
+	for i := 0; i < 10; i++ {
+		pending.ID = 0
+		if err := DB.Create(&pending).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := ListTopCommenters("blog", time.Time{}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("got %d commenters, want 3", len(stats))
+	}
+	if stats[0].UserID != "100" || stats[0].Count != 5 {
+		t.Fatalf("first = %+v, want user 100 with 5", stats[0])
+	}
+	if stats[1].Email != "top@example.com" || stats[1].Count != 4 {
+		t.Fatalf("second = %+v, want top@example.com with 4", stats[1])
+	}
+	if stats[2].Count != 1 {
+		t.Fatalf("third = %+v, want count 1", stats[2])
+	}
+
+	stats, err = ListTopCommenters("blog", time.Now().Add(time.Hour), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 0 {
+		t.Fatalf("future window returned %d commenters", len(stats))
+	}
+}
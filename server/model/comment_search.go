@@ -0,0 +1,160 @@
+package model
+
+import (
+	"log"
+	"marku-server/config"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 评论搜索方式
+const (
+	SearchModeFTS5     = "fts5"     // SQLite FTS5 + trigram 分词
+	SearchModeFulltext = "fulltext" // MySQL FULLTEXT + ngram 分词
+	SearchModeLike     = "like"     // 不支持全文索引时退回 LIKE
+)
+
+// 全文索引对检索词长度的下限：trigram 需要至少 3 个字符，MySQL ngram 默认分词长度为 2
+const (
+	fts5MinQueryLength     = 3
+	fulltextMinQueryLength = 2
+)
+
+var commentSearchMode = SearchModeLike
+
+// CommentSearchParams 评论搜索条件，零值字段表示不过滤
+type CommentSearchParams struct {
+	Query    string
+	SiteID   string
+	Mark     string
+	Status   *int
+	From     time.Time
+	To       time.Time
+	Page     int
+	PageSize int
+}
+
+// GetCommentSearchMode 当前数据库使用的搜索方式
+func GetCommentSearchMode() string {
+	return commentSearchMode
+}
+
+// initCommentSearch 尝试为评论建立全文索引，数据库不支持时记录日志并退回 LIKE 搜索
+func initCommentSearch() {
+	switch DB.Dialector.Name() {
+	case "sqlite":
+		if err := initSQLiteCommentSearch(); err != nil {
+			log.Printf("SQLite 全文索引不可用，评论搜索退回 LIKE: %v", err)
+			return
+		}
+		commentSearchMode = SearchModeFTS5
+	case "mysql":
+		if err := initMySQLCommentSearch(); err != nil {
+			log.Printf("MySQL 全文索引不可用，评论搜索退回 LIKE: %v", err)
+			return
+		}
+		commentSearchMode = SearchModeFulltext
+	}
+}
+
+// initSQLiteCommentSearch 以 comments 为外部内容表建立 FTS5 索引，并用触发器保持同步
+func initSQLiteCommentSearch() error {
+	if config.DropTable {
+		if err := DB.Exec("DROP TABLE IF EXISTS comments_fts").Error; err != nil {
+			return err
+		}
+	}
+
+	var existing int64
+	if err := DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'comments_fts'").Scan(&existing).Error; err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(content, username, content='comments', content_rowid='id', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_insert AFTER INSERT ON comments BEGIN
+			INSERT INTO comments_fts(rowid, content, username) VALUES (new.id, new.content, new.username);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_delete AFTER DELETE ON comments BEGIN
+			INSERT INTO comments_fts(comments_fts, rowid, content, username) VALUES ('delete', old.id, old.content, old.username);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_update AFTER UPDATE OF content, username ON comments BEGIN
+			INSERT INTO comments_fts(comments_fts, rowid, content, username) VALUES ('delete', old.id, old.content, old.username);
+			INSERT INTO comments_fts(rowid, content, username) VALUES (new.id, new.content, new.username);
+		END`,
+	}
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	// 首次建立索引时导入已有评论
+	if existing == 0 {
+		return DB.Exec("INSERT INTO comments_fts(comments_fts) VALUES ('rebuild')").Error
+	}
+	return nil
+}
+
+// initMySQLCommentSearch 为评论内容与昵称建立使用 ngram 分词的 FULLTEXT 索引
+func initMySQLCommentSearch() error {
+	if DB.Migrator().HasIndex(&Comment{}, "idx_comment_fulltext") {
+		return nil
+	}
+	return DB.Exec("ALTER TABLE comments ADD FULLTEXT INDEX idx_comment_fulltext (content, username) WITH PARSER ngram").Error
+}
+
+// SearchComments 按内容与作者昵称搜索评论，检索词整体作为短语匹配，结果按时间倒序
+func SearchComments(params CommentSearchParams) ([]Comment, int64, error) {
+	query := DB.Model(&Comment{})
+	if params.SiteID != "" {
+		query = query.Where("site_id = ?", params.SiteID)
+	}
+	if params.Mark != "" {
+		query = query.Where("mark = ?", params.Mark)
+	}
+	if params.Status != nil {
+		query = query.Where("status = ?", *params.Status)
+	}
+	if !params.From.IsZero() {
+		query = query.Where("created_at >= ?", params.From)
+	}
+	if !params.To.IsZero() {
+		query = query.Where("created_at < ?", params.To)
+	}
+
+	keyword := strings.TrimSpace(params.Query)
+	length := utf8.RuneCountInString(keyword)
+	switch {
+	case commentSearchMode == SearchModeFTS5 && length >= fts5MinQueryLength:
+		query = query.Where("id IN (SELECT rowid FROM comments_fts WHERE comments_fts MATCH ?)", quoteSearchPhrase(keyword))
+	case commentSearchMode == SearchModeFulltext && length >= fulltextMinQueryLength:
+		query = query.Where("MATCH(content, username) AGAINST (? IN BOOLEAN MODE)", `"`+strings.ReplaceAll(keyword, `"`, " ")+`"`)
+	default:
+		pattern := "%" + escapeLikePattern(keyword) + "%"
+		query = query.Where("(content LIKE ? ESCAPE '!' OR username LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var comments []Comment
+	offset := (params.Page - 1) * params.PageSize
+	if err := query.Order("created_at DESC, id DESC").Limit(params.PageSize).Offset(offset).Find(&comments).Error; err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+// quoteSearchPhrase 将检索词包装为 FTS5 短语，避免用户输入被当作检索语法
+func quoteSearchPhrase(keyword string) string {
+	return `"` + strings.ReplaceAll(keyword, `"`, `""`) + `"`
+}
+
+// escapeLikePattern 转义 LIKE 通配符，转义符使用 SQLite 与 MySQL 都无需额外转义的 "!"
+func escapeLikePattern(keyword string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(keyword)
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"marku-server/internal/testutil"
+	"testing"
+	"time"
+)
+
+// useSearchMode 切换评论搜索方式并在测试结束后恢复
+func useSearchMode(t *testing.T, mode string) {
+	t.Helper()
+	previous := commentSearchMode
+	commentSearchMode = mode
+	t.Cleanup(func() { commentSearchMode = previous })
+}
+
+// seedSearchComments 按内容创建评论，返回内容到ID的映射
+func seedSearchComments(t *testing.T, comments ...Comment) map[string]uint {
+	t.Helper()
+	ids := make(map[string]uint, len(comments))
+	for i := range comments {
+		if comments[i].SiteID == "" {
+			comments[i].SiteID = "blog"
+		}
+		if comments[i].Mark == "" {
+			comments[i].Mark = "post"
+		}
+		if err := DB.Create(&comments[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[comments[i].Content] = comments[i].ID
+	}
+	return ids
+}
+
+// searchContents 执行搜索并返回命中评论的内容
+func searchContents(t *testing.T, params CommentSearchParams) []string {
+	t.Helper()
+	if params.Page == 0 {
+		params.Page, params.PageSize = 1, 50
+	}
+	comments, total, err := SearchComments(params)
+	if err != nil {
+		t.Fatalf("search %q: %v", params.Query, err)
+	}
+	if int(total) != len(comments) {
+		t.Fatalf("search %q: total %d, got %d rows", params.Query, total, len(comments))
+	}
+	contents := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		contents = append(contents, comment.Content)
+	}
+	return contents
+}
+
+func assertContents(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSearchCommentsFTS5(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Comment{})
+	if err := initSQLiteCommentSearch(); err != nil {
+		t.Fatalf("fts5 unavailable: %v", err)
+	}
+	useSearchMode(t, SearchModeFTS5)
+	seedSearchComments(t,
+		Comment{Content: `she said "hello world" twice`},
+		Comment{Content: "hello there"},
+		Comment{Content: "ok 好的"},
+		Comment{Content: "AND OR NOT"},
+	)
+
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "hello"}), "hello there", `she said "hello world" twice`)
+	// 双引号按 FTS5 规则转义后整体作为短语匹配
+	assertContents(t, searchContents(t, CommentSearchParams{Query: `"hello world"`}), `she said "hello world" twice`)
+	// 检索语法关键字与未闭合的引号只作为普通文本
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "AND OR"}), "AND OR NOT")
+	assertContents(t, searchContents(t, CommentSearchParams{Query: `"hel`}), `she said "hello world" twice`)
+	// 不足 3 个字符时 trigram 无法匹配，退回 LIKE 仍能搜到
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "好的"}), "ok 好的")
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "ok"}), "ok 好的")
+
+	// 更新与删除通过触发器同步到索引
+	if err := DB.Model(&Comment{}).Where("content = ?", "hello there").Update("content", "goodbye").Error; err != nil {
+		t.Fatal(err)
+	}
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "hello"}), `she said "hello world" twice`)
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "goodbye"}), "goodbye")
+}
+
+func TestSearchCommentsLikeEscaping(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Comment{})
+	useSearchMode(t, SearchModeLike)
+	seedSearchComments(t,
+		Comment{Content: "100% sure"},
+		Comment{Content: "1000 sure"},
+		Comment{Content: "snake_case"},
+		Comment{Content: "snakeXcase"},
+		Comment{Content: "wow!"},
+		Comment{Content: "wow"},
+	)
+
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "0%"}), "100% sure")
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "e_c"}), "snake_case")
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "w!"}), "wow!")
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "%"}), "100% sure")
+}
+
+func TestSearchCommentsFilters(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Comment{})
+	useSearchMode(t, SearchModeLike)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	comments := []Comment{
+		{Content: "note approved old", Status: 1},
+		{Content: "note approved new", Status: 1},
+		{Content: "note pending", Status: 0},
+		{Content: "note other site", Status: 1, SiteID: "docs"},
+		{Content: "note other page", Status: 1, Mark: "about"},
+	}
+	for i := range comments {
+		comments[i].CreatedAt = base.Add(time.Duration(i) * 24 * time.Hour)
+	}
+	comments[1].CreatedAt = base.Add(10 * 24 * time.Hour)
+	seedSearchComments(t, comments...)
+
+	approved := 1
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "note", SiteID: "blog", Mark: "post", Status: &approved}),
+		"note approved new", "note approved old")
+	pending := 0
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "note", Status: &pending}), "note pending")
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "note", SiteID: "docs"}), "note other site")
+
+	// From 含边界，To 不含边界
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "note", From: base.Add(2 * 24 * time.Hour), To: base.Add(4 * 24 * time.Hour)}),
+		"note other site", "note pending")
+	assertContents(t, searchContents(t, CommentSearchParams{Query: "note", From: base.Add(5 * 24 * time.Hour)}), "note approved new")
+}
+
+func TestSearchQueryEscaping(t *testing.T) {
+	if got := quoteSearchPhrase(`say "hi"`); got != `"say ""hi"""` {
+		t.Fatalf("quoteSearchPhrase = %s", got)
+	}
+	if got := escapeLikePattern(`50%_off!`); got != `50!%!_off!!` {
+		t.Fatalf("escapeLikePattern = %s", got)
+	}
+}
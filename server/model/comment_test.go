@@ -0,0 +1,31 @@
+package model
+
+import (
+	"marku-server/internal/testutil"
+	"testing"
+)
+
+func TestCommentThreadIndex(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Comment{})
+
+	for i := 0; i < 2; i++ {
+		if err := initCommentIndexes(); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	if !DB.Migrator().HasIndex(&Comment{}, commentThreadIndex) {
+		t.Fatal("composite index not created")
+	}
+
+	comment := Comment{SiteID: "blog", Mark: "post-1", Content: "hello"}
+	if err := DB.Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+	var loaded Comment
+	if err := DB.First(&loaded, comment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if loaded.BaseModel.CreatedAt.IsZero() {
+		t.Fatal("BaseModel.CreatedAt not populated")
+	}
+}
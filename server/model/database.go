@@ -39,17 +39,21 @@ func InitDatabase() {
 
 	if config.DropTable {
 		//清空表
-		err = DB.Migrator().DropTable(&User{}, &Count{}, &Comment{}, &EmailVerificationCode{})
+		err = DB.Migrator().DropTable(&User{}, &Count{}, &Comment{}, &EmailVerificationCode{}, &Session{}, &Identity{}, &OAuthState{}, &TwoFactor{}, &RecoveryCode{}, &WebAuthnCredential{}, &WebAuthnChallenge{}, &LoginAttempt{}, &Site{}, &APIKey{}, &APIKeyAudit{}, &ThreadSetting{}, &Mention{}, &Reaction{}, &Attachment{}, &Report{})
 		if err != nil {
 			log.Fatalln("清空表失败！")
 		}
 	}
 
 	// 自动迁移数据库
-	err = DB.AutoMigrate(&User{}, &Count{}, &Comment{}, &EmailVerificationCode{})
+	err = DB.AutoMigrate(&User{}, &Count{}, &Comment{}, &EmailVerificationCode{}, &Session{}, &Identity{}, &OAuthState{}, &TwoFactor{}, &RecoveryCode{}, &WebAuthnCredential{}, &WebAuthnChallenge{}, &LoginAttempt{}, &Site{}, &APIKey{}, &APIKeyAudit{}, &ThreadSetting{}, &Mention{}, &Reaction{}, &Attachment{}, &Report{})
 	if err != nil {
 		log.Fatalln("数据库迁移失败！")
 	}
+	if err := initCommentIndexes(); err != nil {
+		log.Fatalf("创建评论索引失败: %v", err)
+	}
+	initCommentSearch()
 
 	if config.DropTable {
 		// 初始化管理员账户
@@ -2,15 +2,21 @@ package model
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
+	"marku-server/config"
 	"math/big"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 const (
-	EmailPurposeRegister = "register"
-	EmailPurposeRecover  = "recover"
-	EmailPurposeLogin    = "login"
+	EmailPurposeRegister    = "register"
+	EmailPurposeRecover     = "recover"
+	EmailPurposeLogin       = "login"
+	EmailPurposeChangeEmail = "change_email"
+	EmailPurposeDataRequest = "data_request" // 导出或删除个人数据
 )
 
 // EmailVerificationCode 邮箱验证码记录
@@ -19,6 +25,7 @@ type EmailVerificationCode struct {
 	Email     string     `gorm:"size:255;not null;index:idx_email_purpose,priority:1" json:"email"`
 	Purpose   string     `gorm:"size:32;not null;index:idx_email_purpose,priority:2" json:"purpose"`
 	Code      string     `gorm:"size:16;not null" json:"code"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"` // 错误尝试次数，达到上限后验证码作废
 	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
 	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
@@ -52,10 +59,10 @@ func GenerateEmailVerificationCode(email, purpose string) (*EmailVerificationCod
 	return record, nil
 }
 
-// VerifyEmailVerificationCode 校验邮箱验证码
+// VerifyEmailVerificationCode 校验邮箱验证码，错误次数达到上限后验证码作废
 func VerifyEmailVerificationCode(email, purpose, code string) error {
 	var record EmailVerificationCode
-	if err := DB.Where("email = ? AND purpose = ? AND code = ?", email, purpose, code).Order("created_at DESC").First(&record).Error; err != nil {
+	if err := DB.Where("email = ? AND purpose = ?", email, purpose).Order("created_at DESC").First(&record).Error; err != nil {
 		return err
 	}
 
@@ -69,9 +76,29 @@ func VerifyEmailVerificationCode(email, purpose, code string) error {
 		return fmt.Errorf("验证码已使用")
 	}
 
-	record.VerifiedAt = &now
-	if err := DB.Delete(&record).Error; err != nil {
-		return err
+	if subtle.ConstantTimeCompare([]byte(record.Code), []byte(code)) != 1 {
+		// 计数在数据库中原子累加，并发的错误尝试无法读到同一个旧值而超出上限；
+		// 第 CodeMaxAttempts 次错误时条件不再满足，验证码作废
+		result := DB.Model(&EmailVerificationCode{}).
+			Where("id = ? AND attempts < ?", record.ID, config.GetLockoutConfig().CodeMaxAttempts-1).
+			UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			_ = DB.Delete(&EmailVerificationCode{}, record.ID).Error
+			return fmt.Errorf("验证码错误次数过多，请重新获取")
+		}
+		return fmt.Errorf("验证码错误")
+	}
+
+	// 删除成功才算使用，并发提交同一验证码时只有一个请求通过
+	result := DB.Delete(&EmailVerificationCode{}, record.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("验证码已失效，请重新获取")
 	}
 	return nil
 }
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"sync"
+	"testing"
+)
+
+func TestVerifyEmailCodeLimitsParallelGuesses(t *testing.T) {
+	testutil.SetupDB(t, &DB, &EmailVerificationCode{})
+	// 多个连接让各 goroutine 的读写真正交错
+	if sqlDB, err := DB.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(8)
+	}
+	cfg := &config.Config{}
+	cfg.Auth.Lockout = config.LockoutConfig{CodeMaxAttempts: 5}
+	testutil.UseConfig(t, cfg)
+
+	record, err := GenerateEmailVerificationCode("alice@example.com", EmailPurposeLogin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := "000000"
+	if record.Code == wrong {
+		wrong = "111111"
+	}
+
+	const guesses = 50
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		rejected int
+	)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := VerifyEmailVerificationCode("alice@example.com", EmailPurposeLogin, wrong); err != nil && err.Error() == "验证码错误" {
+				mu.Lock()
+				rejected++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 第 5 次错误即作废，最多只有 4 次普通的“验证码错误”
+	if rejected > 4 {
+		t.Fatalf("%d wrong guesses counted, want at most 4", rejected)
+	}
+	// 个别请求可能因 SQLite 写锁失败，但每次“验证码错误”都必须对应一次计数
+	var stored EmailVerificationCode
+	if err := DB.First(&stored, record.ID).Error; err == nil && stored.Attempts != rejected {
+		t.Fatalf("stored %d attempts for %d wrong guesses", stored.Attempts, rejected)
+	}
+}
+
+func TestVerifyEmailCodeAttempts(t *testing.T) {
+	testutil.SetupDB(t, &DB, &EmailVerificationCode{})
+	cfg := &config.Config{}
+	cfg.Auth.Lockout = config.LockoutConfig{CodeMaxAttempts: 3}
+	testutil.UseConfig(t, cfg)
+
+	record, err := GenerateEmailVerificationCode("bob@example.com", EmailPurposeLogin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := "000000"
+	if record.Code == wrong {
+		wrong = "111111"
+	}
+
+	for i, want := range []string{"验证码错误", "验证码错误", "验证码错误次数过多，请重新获取"} {
+		err := VerifyEmailVerificationCode("bob@example.com", EmailPurposeLogin, wrong)
+		if err == nil || err.Error() != want {
+			t.Fatalf("guess %d: %v, want %q", i+1, err, want)
+		}
+	}
+	if err := VerifyEmailVerificationCode("bob@example.com", EmailPurposeLogin, record.Code); err == nil {
+		t.Fatal("code still valid after exhausting attempts")
+	}
+
+	// 正确的验证码只能使用一次
+	record, err = GenerateEmailVerificationCode("bob@example.com", EmailPurposeLogin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyEmailVerificationCode("bob@example.com", EmailPurposeLogin, record.Code); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyEmailVerificationCode("bob@example.com", EmailPurposeLogin, record.Code); err == nil {
+		t.Fatal("code accepted twice")
+	}
+}
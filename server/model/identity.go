@@ -0,0 +1,203 @@
+package model
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"marku-server/types"
+	"marku-server/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+// Identity 第三方登录账户与本地用户的绑定关系
+type Identity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_identity_subject,priority:1" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_identity_subject,priority:2" json:"subject"`
+	Username  string    `gorm:"size:100" json:"username"`
+	Email     *string   `gorm:"size:255" json:"email,omitempty"`
+	Avatar    *string   `gorm:"size:500" json:"avatar,omitempty"`
+	URL       *string   `gorm:"size:500" json:"url,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// OAuthState 授权流程中的一次性状态，保存 PKCE 校验值与回跳地址
+type OAuthState struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	State        string    `gorm:"size:64;not null;uniqueIndex" json:"state"`
+	Provider     string    `gorm:"size:50;not null" json:"provider"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"`
+	BrowserHash  string    `gorm:"size:64;not null;default:''" json:"-"` // 发起流程的浏览器所持 Cookie 的摘要
+	CallbackURL  string    `gorm:"size:500;not null" json:"callback_url"`
+	RedirectURL  string    `gorm:"size:500" json:"redirect_url"`
+	LinkUserID   uint      `gorm:"default:0" json:"link_user_id"` // 非 0 表示为已登录用户绑定新账户
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// CreateOAuthState 创建授权状态，browserNonce 同时写入发起流程的浏览器的 Cookie
+func CreateOAuthState(provider, codeVerifier, callbackURL, redirectURL, browserNonce string, linkUserID uint) (*OAuthState, error) {
+	state, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+
+	// 顺带清理过期状态
+	_ = DB.Where("expires_at < ?", time.Now()).Delete(&OAuthState{}).Error
+
+	record := &OAuthState{
+		State:        state,
+		Provider:     provider,
+		CodeVerifier: codeVerifier,
+		CallbackURL:  callbackURL,
+		RedirectURL:  redirectURL,
+		BrowserHash:  utils.HashToken(browserNonce),
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if err := DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ConsumeOAuthState 校验回调来自发起流程的浏览器后取出并删除授权状态，确保只能使用一次；
+// 浏览器不匹配时不消耗状态，防止他人诱导受害者完成攻击者发起的登录或绑定
+func ConsumeOAuthState(provider, state, browserNonce string) (*OAuthState, error) {
+	var record OAuthState
+	if err := DB.Where("state = ? AND provider = ?", state, provider).First(&record).Error; err != nil {
+		return nil, fmt.Errorf("授权状态无效")
+	}
+	if browserNonce == "" || subtle.ConstantTimeCompare([]byte(record.BrowserHash), []byte(utils.HashToken(browserNonce))) != 1 {
+		return nil, fmt.Errorf("授权状态与当前浏览器不匹配")
+	}
+
+	result := DB.Delete(&OAuthState{}, record.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("授权状态已使用")
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, fmt.Errorf("授权状态已过期")
+	}
+	return &record, nil
+}
+
+// ListUserIdentities 列出用户绑定的第三方账户
+func ListUserIdentities(userID uint) ([]Identity, error) {
+	var identities []Identity
+	err := DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// ResolveOAuthUser 根据第三方账户信息找到或创建本地用户，并维护绑定关系
+func ResolveOAuthUser(provider string, info *utils.OAuthUserInfo, linkUserID uint) (*User, error) {
+	var identity Identity
+	err := DB.Where("provider = ? AND subject = ?", provider, info.Subject).First(&identity).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var user *User
+	if err == nil {
+		if linkUserID != 0 && identity.UserID != linkUserID {
+			return nil, fmt.Errorf("该第三方账户已绑定其他用户")
+		}
+		user, err = GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		user, err = findOrCreateOAuthUser(info, linkUserID)
+		if err != nil {
+			return nil, err
+		}
+		identity = Identity{UserID: user.ID, Provider: provider, Subject: info.Subject}
+	}
+
+	identity.Username = truncateString(info.Username, 100)
+	identity.Email = optionalString(info.Email)
+	identity.Avatar = optionalString(truncateString(info.AvatarURL, 500))
+	identity.URL = optionalString(truncateString(oauthUserURL(info), 500))
+	if err := DB.Save(&identity).Error; err != nil {
+		return nil, err
+	}
+
+	if err := importOAuthProfile(user, &identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// findOrCreateOAuthUser 为首次登录的第三方账户确定本地用户
+func findOrCreateOAuthUser(info *utils.OAuthUserInfo, linkUserID uint) (*User, error) {
+	if linkUserID != 0 {
+		return GetUserByID(linkUserID)
+	}
+
+	// 仅在提供方确认邮箱已验证时按邮箱合并账户，避免冒用他人邮箱
+	email := strings.TrimSpace(info.Email)
+	if email != "" && info.EmailVerified {
+		user, err := GetUserByEmail(email)
+		if err == nil {
+			if user.Role == types.RoleGuest {
+				if err := UpgradeGuestUser(user); err != nil {
+					return nil, err
+				}
+			}
+			return user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	base := strings.TrimSpace(info.Username)
+	if base == "" {
+		base = strings.TrimSpace(info.Name)
+	}
+	user := &User{
+		Username: generateAvailableUsername(base),
+		Role:     types.RoleUser,
+	}
+	if email != "" && info.EmailVerified {
+		user.Email = &email
+	}
+	if err := DB.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// importOAuthProfile 用第三方资料补全用户头像与网址，不覆盖用户已填写的内容
+func importOAuthProfile(user *User, identity *Identity) error {
+	updates := map[string]interface{}{}
+	if user.Avatar == nil && identity.Avatar != nil {
+		updates["avatar"] = *identity.Avatar
+		user.Avatar = identity.Avatar
+	}
+	if user.URL == nil && identity.URL != nil {
+		updates["url"] = *identity.URL
+		user.URL = identity.URL
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return DB.Model(&User{}).Where("id = ?", user.ID).Updates(updates).Error
+}
+
+func oauthUserURL(info *utils.OAuthUserInfo) string {
+	if strings.TrimSpace(info.WebsiteURL) != "" {
+		return strings.TrimSpace(info.WebsiteURL)
+	}
+	return strings.TrimSpace(info.ProfileURL)
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"marku-server/config"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 失败计数维度
+const (
+	LoginScopeAccount   = "account"    // 单个账户（用户ID或登录名）
+	LoginScopeEmailCode = "email_code" // 邮箱 + 验证码用途
+	LoginScopeIP        = "ip"         // 来源 IP
+)
+
+// 长时间没有失败记录后，累计锁定次数清零，锁定时长重新从基础值开始
+const loginLockoutDecay = 24 * time.Hour
+
+// LoginAttempt 登录与验证码失败计数，按维度记录连续失败次数与锁定状态
+type LoginAttempt struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Scope        string     `gorm:"size:20;not null;uniqueIndex:idx_login_attempt_scope_key,priority:1" json:"scope"`
+	Key          string     `gorm:"column:attempt_key;size:255;not null;uniqueIndex:idx_login_attempt_scope_key,priority:2" json:"key"`
+	Failures     int        `gorm:"not null;default:0" json:"failures"`      // 当前窗口内的连续失败次数
+	LockoutCount int        `gorm:"not null;default:0" json:"lockout_count"` // 累计锁定次数，决定下次锁定时长
+	LastFailedAt time.Time  `gorm:"not null" json:"last_failed_at"`
+	LockedUntil  *time.Time `gorm:"index" json:"locked_until,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// LoginAttemptKey 失败计数的维度与键
+type LoginAttemptKey struct {
+	Scope string
+	Key   string
+}
+
+// IsLocked 判断当前是否处于锁定状态
+func (a *LoginAttempt) IsLocked() bool {
+	return a.LockedUntil != nil && time.Now().Before(*a.LockedUntil)
+}
+
+// CheckLoginLockout 返回给定维度中最晚的锁定截止时间，均未锁定时返回零值
+func CheckLoginLockout(keys ...LoginAttemptKey) time.Time {
+	var lockedUntil time.Time
+	for _, key := range keys {
+		if key.Key == "" {
+			continue
+		}
+		var attempt LoginAttempt
+		if err := DB.Where("scope = ? AND attempt_key = ?", key.Scope, key.Key).First(&attempt).Error; err != nil {
+			continue
+		}
+		if attempt.IsLocked() && attempt.LockedUntil.After(lockedUntil) {
+			lockedUntil = *attempt.LockedUntil
+		}
+	}
+	return lockedUntil
+}
+
+// RecordLoginFailure 为各维度累加一次失败，达到阈值时锁定，锁定时长随累计锁定次数翻倍。
+// 计数与锁定均为条件更新，并发的失败请求不会互相覆盖计数。
+// 返回本次触发的最晚锁定截止时间，未触发锁定时返回零值
+func RecordLoginFailure(keys ...LoginAttemptKey) (time.Time, error) {
+	lockout := config.GetLockoutConfig()
+	window := time.Duration(lockout.FailureWindowSeconds) * time.Second
+	now := time.Now()
+
+	var lockedUntil time.Time
+	for _, key := range keys {
+		if key.Key == "" {
+			continue
+		}
+		attemptKey := truncateString(key.Key, 255)
+
+		// 首次失败时建立记录，并发插入同一维度时由唯一索引去重
+		record := LoginAttempt{Scope: key.Scope, Key: attemptKey, LastFailedAt: now}
+		if err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+			return lockedUntil, err
+		}
+
+		// 超过失败窗口重新计数，长时间未失败则累计锁定次数清零；
+		// last_failed_at 最后赋值，MySQL 按顺序求值时前两列仍读取旧值
+		err := DB.Exec(`UPDATE login_attempts SET
+			failures = CASE WHEN last_failed_at < ? THEN 1 ELSE failures + 1 END,
+			lockout_count = CASE WHEN last_failed_at < ? THEN 0 ELSE lockout_count END,
+			last_failed_at = ?, updated_at = ?
+			WHERE scope = ? AND attempt_key = ?`,
+			now.Add(-window), now.Add(-loginLockoutDecay), now, now, key.Scope, attemptKey).Error
+		if err != nil {
+			return lockedUntil, err
+		}
+
+		var attempt LoginAttempt
+		if err := DB.Where("scope = ? AND attempt_key = ?", key.Scope, attemptKey).First(&attempt).Error; err != nil {
+			return lockedUntil, err
+		}
+		threshold := lockout.MaxFailures
+		if key.Scope == LoginScopeIP {
+			threshold = lockout.IPMaxFailures
+		}
+		if attempt.Failures < threshold {
+			continue
+		}
+
+		// 以读到的累计锁定次数为条件加锁，并发达到阈值的请求只有一个生效；
+		// 扣除阈值而非清零，加锁前后并发记下的失败仍计入下一轮
+		until := now.Add(lockoutDuration(lockout, attempt.LockoutCount))
+		result := DB.Model(&LoginAttempt{}).
+			Where("id = ? AND failures >= ? AND lockout_count = ?", attempt.ID, threshold, attempt.LockoutCount).
+			Updates(map[string]interface{}{
+				"failures":      gorm.Expr("failures - ?", threshold),
+				"lockout_count": gorm.Expr("lockout_count + 1"),
+				"locked_until":  until,
+			})
+		if result.Error != nil {
+			return lockedUntil, result.Error
+		}
+		if result.RowsAffected == 1 && until.After(lockedUntil) {
+			lockedUntil = until
+		}
+	}
+	return lockedUntil, nil
+}
+
+// ClearLoginFailures 验证成功后清除对应维度的失败记录
+func ClearLoginFailures(keys ...LoginAttemptKey) error {
+	for _, key := range keys {
+		if key.Key == "" {
+			continue
+		}
+		if err := DB.Where("scope = ? AND attempt_key = ?", key.Scope, key.Key).Delete(&LoginAttempt{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListLoginAttempts 分页查询失败记录，lockedOnly 为 true 时仅返回锁定中的记录
+func ListLoginAttempts(scope string, lockedOnly bool, page, pageSize int) ([]LoginAttempt, int64, error) {
+	db := DB.Model(&LoginAttempt{})
+	if scope != "" {
+		db = db.Where("scope = ?", scope)
+	}
+	if lockedOnly {
+		db = db.Where("locked_until > ?", time.Now())
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []LoginAttempt
+	if err := db.Order("last_failed_at DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+// UnlockLoginAttempt 管理员手动解除锁定并清空失败计数
+func UnlockLoginAttempt(id uint) error {
+	result := DB.Delete(&LoginAttempt{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// lockoutDuration 第 n 次锁定的时长：基础时长按 2^n 递增，不超过上限
+func lockoutDuration(lockout config.LockoutConfig, lockoutCount int) time.Duration {
+	seconds := lockout.BaseLockoutSeconds
+	for i := 0; i < lockoutCount && seconds < lockout.MaxLockoutSeconds; i++ {
+		seconds *= 2
+	}
+	if seconds > lockout.MaxLockoutSeconds {
+		seconds = lockout.MaxLockoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
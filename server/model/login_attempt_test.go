@@ -0,0 +1,239 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func setupLockoutTest(t *testing.T) config.LockoutConfig {
+	t.Helper()
+	testutil.SetupDB(t, &DB, &LoginAttempt{})
+	cfg := &config.Config{}
+	cfg.Auth.Lockout = config.LockoutConfig{
+		MaxFailures:          3,
+		IPMaxFailures:        5,
+		FailureWindowSeconds: 600,
+		BaseLockoutSeconds:   60,
+		MaxLockoutSeconds:    300,
+	}
+	testutil.UseConfig(t, cfg)
+	return config.GetLockoutConfig()
+}
+
+// failTimes 连续记录 n 次失败，返回最后一次的锁定截止时间
+func failTimes(t *testing.T, n int, keys ...LoginAttemptKey) time.Time {
+	t.Helper()
+	var lockedUntil time.Time
+	for i := 0; i < n; i++ {
+		until, err := RecordLoginFailure(keys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lockedUntil = until
+	}
+	return lockedUntil
+}
+
+// shiftAttempt 将失败记录的时间整体前移，模拟时间流逝
+func shiftAttempt(t *testing.T, key LoginAttemptKey, elapsed time.Duration) {
+	t.Helper()
+	var attempt LoginAttempt
+	if err := DB.Where("scope = ? AND attempt_key = ?", key.Scope, key.Key).First(&attempt).Error; err != nil {
+		t.Fatal(err)
+	}
+	attempt.LastFailedAt = attempt.LastFailedAt.Add(-elapsed)
+	if attempt.LockedUntil != nil {
+		until := attempt.LockedUntil.Add(-elapsed)
+		attempt.LockedUntil = &until
+	}
+	if err := DB.Save(&attempt).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertLockedFor(t *testing.T, lockedUntil time.Time, want time.Duration) {
+	t.Helper()
+	if got := time.Until(lockedUntil); got < want-5*time.Second || got > want {
+		t.Fatalf("locked for %v, want %v", got.Round(time.Second), want)
+	}
+}
+
+func TestLoginLockoutEscalates(t *testing.T) {
+	setupLockoutTest(t)
+	account := LoginAttemptKey{Scope: LoginScopeAccount, Key: "alice"}
+
+	if until := failTimes(t, 2, account); !until.IsZero() || !CheckLoginLockout(account).IsZero() {
+		t.Fatal("locked before reaching the threshold")
+	}
+	until := failTimes(t, 1, account)
+	assertLockedFor(t, until, time.Minute)
+	if !CheckLoginLockout(account).Equal(until) {
+		t.Fatal("CheckLoginLockout does not report the lockout")
+	}
+
+	// 每次锁定到期后再次达到阈值，锁定时长翻倍直至上限
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		shiftAttempt(t, account, 5*time.Minute)
+		if !CheckLoginLockout(account).IsZero() {
+			t.Fatal("lockout did not expire")
+		}
+		if until := failTimes(t, 2, account); !until.IsZero() {
+			t.Fatal("failure count not reset after lockout")
+		}
+		assertLockedFor(t, failTimes(t, 1, account), want)
+	}
+}
+
+func TestLoginFailuresCountedUnderConcurrency(t *testing.T) {
+	setupLockoutTest(t)
+	// 多个连接让各 goroutine 的读写真正交错
+	if sqlDB, err := DB.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(8)
+	}
+
+	// 每个账户的首次失败同时到达，既竞争建立记录，也竞争累加与加锁
+	const accounts, guesses = 20, 10
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		lockouts = map[string]int{}
+		errs     []error
+		start    = make(chan struct{})
+	)
+	for i := 0; i < accounts; i++ {
+		key := LoginAttemptKey{Scope: LoginScopeAccount, Key: fmt.Sprintf("user%d", i)}
+		for j := 0; j < guesses; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				until, err := RecordLoginFailure(key)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+				} else if !until.IsZero() {
+					lockouts[key.Key]++
+				}
+			}()
+		}
+	}
+	close(start)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		t.Fatalf("%d of %d failures not recorded: %v", len(errs), accounts*guesses, errs[0])
+	}
+	// 阈值为 3：每次锁定扣除 3 次失败，其余留在当前计数中，一次失败都不能丢
+	var attempts []LoginAttempt
+	if err := DB.Find(&attempts).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != accounts {
+		t.Fatalf("stored %d records for %d accounts", len(attempts), accounts)
+	}
+	for _, attempt := range attempts {
+		if attempt.LockoutCount == 0 || attempt.LockoutCount != lockouts[attempt.Key] {
+			t.Fatalf("%s: %d lockouts reported, stored lockout_count %d", attempt.Key, lockouts[attempt.Key], attempt.LockoutCount)
+		}
+		if counted := attempt.Failures + attempt.LockoutCount*3; counted != guesses {
+			t.Fatalf("%s: stored %d failures for %d guesses", attempt.Key, counted, guesses)
+		}
+	}
+}
+
+func TestLoginLockoutResets(t *testing.T) {
+	setupLockoutTest(t)
+	account := LoginAttemptKey{Scope: LoginScopeAccount, Key: "alice"}
+
+	// 超过失败窗口后重新计数
+	failTimes(t, 2, account)
+	shiftAttempt(t, account, 11*time.Minute)
+	if until := failTimes(t, 2, account); !until.IsZero() {
+		t.Fatal("failures outside the window still counted")
+	}
+	assertLockedFor(t, failTimes(t, 1, account), time.Minute)
+
+	// 长时间没有失败后，累计锁定次数清零，锁定时长回到基础值
+	shiftAttempt(t, account, 5*time.Minute)
+	assertLockedFor(t, failTimes(t, 3, account), 2*time.Minute)
+	shiftAttempt(t, account, loginLockoutDecay+time.Minute)
+	assertLockedFor(t, failTimes(t, 3, account), time.Minute)
+
+	// 验证成功后清除失败记录
+	if err := ClearLoginFailures(account, LoginAttemptKey{Scope: LoginScopeIP}); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckLoginLockout(account).IsZero() {
+		t.Fatal("lockout survived ClearLoginFailures")
+	}
+	if until := failTimes(t, 2, account); !until.IsZero() {
+		t.Fatal("failure count survived ClearLoginFailures")
+	}
+}
+
+func TestLoginLockoutScopes(t *testing.T) {
+	setupLockoutTest(t)
+	account := LoginAttemptKey{Scope: LoginScopeAccount, Key: "alice"}
+	ip := LoginAttemptKey{Scope: LoginScopeIP, Key: "203.0.113.7"}
+	empty := LoginAttemptKey{Scope: LoginScopeEmailCode, Key: ""}
+
+	// 账户维度先达到阈值，IP 维度使用独立的更高阈值
+	assertLockedFor(t, failTimes(t, 3, account, ip, empty), time.Minute)
+	if !CheckLoginLockout(ip).IsZero() {
+		t.Fatal("ip locked at the account threshold")
+	}
+	failTimes(t, 2, ip)
+	if CheckLoginLockout(ip).IsZero() {
+		t.Fatal("ip not locked at its own threshold")
+	}
+	if !CheckLoginLockout(LoginAttemptKey{Scope: LoginScopeAccount, Key: "bob"}).IsZero() {
+		t.Fatal("lockout leaked to another account")
+	}
+
+	var count int64
+	DB.Model(&LoginAttempt{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("stored %d attempts, want 2 (empty keys are skipped)", count)
+	}
+
+	attempts, total, err := ListLoginAttempts(LoginScopeIP, true, 1, 10)
+	if err != nil || total != 1 || len(attempts) != 1 {
+		t.Fatalf("ListLoginAttempts: %d %v", total, err)
+	}
+	if err := UnlockLoginAttempt(attempts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckLoginLockout(ip).IsZero() {
+		t.Fatal("ip still locked after unlock")
+	}
+	if err := UnlockLoginAttempt(attempts[0].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("unlocking a missing record: %v", err)
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	lockout := config.LockoutConfig{BaseLockoutSeconds: 60, MaxLockoutSeconds: 300}
+	tests := []struct {
+		count int
+		want  time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{2, 4 * time.Minute},
+		{3, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := lockoutDuration(lockout, tt.count); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", tt.count, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"marku-server/config"
+	"marku-server/types"
+	"strconv"
+	"time"
+)
+
+// Mention 评论中的 @提及记录
+type Mention struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	CommentID       uint       `gorm:"not null;index" json:"comment_id"`
+	SiteID          string     `gorm:"size:100;not null" json:"site_id"`
+	Mark            string     `gorm:"size:500;not null" json:"mark"`
+	Username        string     `gorm:"size:100;not null" json:"username"`
+	UserID          uint       `gorm:"default:0;index" json:"user_id"`     // 被提及的注册用户，0 表示仅在同页面评论过的游客
+	TargetCommentID uint       `gorm:"default:0" json:"target_comment_id"` // 被提及者在同页面的最近一条评论，用于生成链接
+	NotifiedAt      *time.Time `json:"notified_at,omitempty"`              // 邮件通知发送时间
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ResolveMentions 将用户名解析为提及记录：优先匹配注册用户，其次匹配同页面已通过评论的作者，均未命中的用户名忽略
+func ResolveMentions(siteID, mark string, names []string) ([]Mention, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var users []User
+	if err := DB.Where("username IN ? AND role <> ?", names, types.RoleGuest).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usersByName := make(map[string]User, len(users))
+	userIDs := make([]string, 0, len(users))
+	for _, user := range users {
+		usersByName[user.Username] = user
+		userIDs = append(userIDs, strconv.FormatUint(uint64(user.ID), 10))
+	}
+
+	// 同页面评论者的最近一条评论，注册用户按 user_id 归并，游客按昵称归并
+	var rows []struct {
+		ID       uint
+		UserID   string
+		Username string
+	}
+	query := DB.Model(&Comment{}).
+		Select("MAX(id) AS id, user_id, username").
+		Where("site_id = ? AND mark = ? AND status = ?", siteID, mark, config.GetApprovedCommentStatusValue())
+	if len(userIDs) > 0 {
+		query = query.Where("username IN ? OR user_id IN ?", names, userIDs)
+	} else {
+		query = query.Where("username IN ?", names)
+	}
+	if err := query.Group("user_id, username").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	latestByUserID := make(map[string]uint)
+	latestByGuest := make(map[string]uint)
+	for _, row := range rows {
+		if row.UserID != "" {
+			if row.ID > latestByUserID[row.UserID] {
+				latestByUserID[row.UserID] = row.ID
+			}
+		} else if row.ID > latestByGuest[row.Username] {
+			latestByGuest[row.Username] = row.ID
+		}
+	}
+
+	mentions := make([]Mention, 0, len(names))
+	for _, name := range names {
+		if user, ok := usersByName[name]; ok {
+			mentions = append(mentions, Mention{
+				SiteID:          siteID,
+				Mark:            mark,
+				Username:        user.Username,
+				UserID:          user.ID,
+				TargetCommentID: latestByUserID[strconv.FormatUint(uint64(user.ID), 10)],
+			})
+			continue
+		}
+		if target, ok := latestByGuest[name]; ok {
+			mentions = append(mentions, Mention{
+				SiteID:          siteID,
+				Mark:            mark,
+				Username:        name,
+				TargetCommentID: target,
+			})
+		}
+	}
+	return mentions, nil
+}
+
+// CreateMentions 保存评论的提及记录
+func CreateMentions(commentID uint, mentions []Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+	for i := range mentions {
+		mentions[i].CommentID = commentID
+	}
+	return DB.Create(&mentions).Error
+}
+
+// ListMentionsByCommentIDs 批量查询评论的提及记录，按评论ID分组
+func ListMentionsByCommentIDs(commentIDs []uint) (map[uint][]Mention, error) {
+	grouped := make(map[uint][]Mention)
+	if len(commentIDs) == 0 {
+		return grouped, nil
+	}
+	var mentions []Mention
+	if err := DB.Where("comment_id IN ?", commentIDs).Order("id ASC").Find(&mentions).Error; err != nil {
+		return nil, err
+	}
+	for _, mention := range mentions {
+		grouped[mention.CommentID] = append(grouped[mention.CommentID], mention)
+	}
+	return grouped, nil
+}
+
+// ListPendingMentionNotifications 查询评论中尚未通知、且被提及用户未退订的注册用户提及
+func ListPendingMentionNotifications(comment *Comment) ([]Mention, map[uint]User, error) {
+	var mentions []Mention
+	query := DB.Where("comment_id = ? AND user_id > 0 AND notified_at IS NULL", comment.ID)
+	if comment.UserID != "" {
+		query = query.Where("user_id <> ?", comment.UserID)
+	}
+	if err := query.Find(&mentions).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(mentions) == 0 {
+		return nil, nil, nil
+	}
+
+	userIDs := make([]uint, 0, len(mentions))
+	for _, mention := range mentions {
+		userIDs = append(userIDs, mention.UserID)
+	}
+	var users []User
+	if err := DB.Where("id IN ? AND email IS NOT NULL AND mention_email_opt_out = ?", userIDs, false).Find(&users).Error; err != nil {
+		return nil, nil, err
+	}
+	usersByID := make(map[uint]User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+	return mentions, usersByID, nil
+}
+
+// MarkMentionNotified 记录提及通知已处理，避免审核状态反复变更时重复发送
+func MarkMentionNotified(id uint) error {
+	return DB.Model(&Mention{}).Where("id = ?", id).Update("notified_at", time.Now()).Error
+}
+
+// SetMentionEmailOptOut 设置用户是否退订提及邮件
+func SetMentionEmailOptOut(userID uint, optOut bool) error {
+	return DB.Model(&User{}).Where("id = ?", userID).Update("mention_email_opt_out", optOut).Error
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 站点表情回应配置的上限
+const (
+	MaxReactionSetSize = 20
+	MaxReactionLength  = 32
+)
+
+// Reaction 评论或页面上的表情回应，CommentID 为 0 表示回应整个页面
+type Reaction struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SiteID    string    `gorm:"size:100;not null;uniqueIndex:idx_reaction_unique,priority:1" json:"site_id"`
+	Mark      string    `gorm:"size:255;not null;uniqueIndex:idx_reaction_unique,priority:2" json:"mark"`
+	CommentID uint      `gorm:"default:0;uniqueIndex:idx_reaction_unique,priority:3" json:"comment_id"`
+	Reactor   string    `gorm:"size:64;not null;uniqueIndex:idx_reaction_unique,priority:4;index" json:"-"` // user:<ID> 或 visitor:<指纹>
+	Emoji     string    `gorm:"size:32;not null;uniqueIndex:idx_reaction_unique,priority:5" json:"emoji"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ReactionCount 单个表情的回应数量
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+}
+
+// UserReactor 注册用户的回应者标识
+func UserReactor(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// VisitorReactor 未登录访客的回应者标识
+func VisitorReactor(fingerprint string) string {
+	return "visitor:" + fingerprint
+}
+
+// ToggleReaction 切换回应：已存在则取消，否则添加；返回操作后是否处于已回应状态
+func ToggleReaction(siteID, mark string, commentID uint, reactor, emoji string) (bool, error) {
+	match := func() *gorm.DB {
+		return DB.Model(&Reaction{}).Where("site_id = ? AND mark = ? AND comment_id = ? AND reactor = ? AND emoji = ?", siteID, mark, commentID, reactor, emoji)
+	}
+
+	var existing Reaction
+	err := match().First(&existing).Error
+	if err == nil {
+		return false, DB.Delete(&existing).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	if err := DB.Create(&Reaction{SiteID: siteID, Mark: mark, CommentID: commentID, Reactor: reactor, Emoji: emoji}).Error; err != nil {
+		// 并发重复提交时唯一索引冲突，视为已回应
+		var count int64
+		if countErr := match().Count(&count).Error; countErr == nil && count > 0 {
+			return true, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CountReactions 按评论ID汇总页面内的回应数量，commentIDs 包含 0 时同时统计页面回应
+func CountReactions(siteID, mark string, commentIDs []uint) (map[uint][]ReactionCount, error) {
+	grouped := make(map[uint][]ReactionCount)
+	if len(commentIDs) == 0 {
+		return grouped, nil
+	}
+
+	var rows []struct {
+		CommentID uint
+		Emoji     string
+		Count     int64
+	}
+	if err := DB.Model(&Reaction{}).
+		Select("comment_id, emoji, COUNT(*) AS count").
+		Where("site_id = ? AND mark = ? AND comment_id IN ?", siteID, mark, commentIDs).
+		Group("comment_id, emoji").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		grouped[row.CommentID] = append(grouped[row.CommentID], ReactionCount{Emoji: row.Emoji, Count: row.Count})
+	}
+	return grouped, nil
+}
+
+// ListReactorReactions 查询回应者在页面内的回应，按评论ID分组
+func ListReactorReactions(siteID, mark, reactor string, commentIDs []uint) (map[uint][]string, error) {
+	grouped := make(map[uint][]string)
+	if len(commentIDs) == 0 || reactor == "" {
+		return grouped, nil
+	}
+
+	var reactions []Reaction
+	if err := DB.Where("site_id = ? AND mark = ? AND reactor = ? AND comment_id IN ?", siteID, mark, reactor, commentIDs).
+		Order("id ASC").
+		Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+	for _, reaction := range reactions {
+		grouped[reaction.CommentID] = append(grouped[reaction.CommentID], reaction.Emoji)
+	}
+	return grouped, nil
+}
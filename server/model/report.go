@@ -0,0 +1,228 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 举报原因分类
+const (
+	ReportReasonSpam       = "spam"
+	ReportReasonAbuse      = "abuse"
+	ReportReasonHarassment = "harassment"
+	ReportReasonIllegal    = "illegal"
+	ReportReasonOffTopic   = "off_topic"
+	ReportReasonOther      = "other"
+)
+
+// ReportReasons 可选的举报原因
+var ReportReasons = []string{ReportReasonSpam, ReportReasonAbuse, ReportReasonHarassment, ReportReasonIllegal, ReportReasonOffTopic, ReportReasonOther}
+
+// 举报处理结果
+const (
+	ReportResolutionDismiss = "dismiss" // 忽略举报，评论状态不变
+	ReportResolutionApprove = "approve" // 恢复评论为已通过
+	ReportResolutionReject  = "reject"  // 拒绝评论
+)
+
+// Report 访客对评论的举报，同一举报者对同一评论只记录一次
+type Report struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CommentID  uint       `gorm:"not null;uniqueIndex:idx_report_unique,priority:1" json:"comment_id"`
+	SiteID     string     `gorm:"size:100;not null;index" json:"site_id"`
+	Reporter   string     `gorm:"size:64;not null;uniqueIndex:idx_report_unique,priority:2;index" json:"-"` // user:<ID> 或 visitor:<指纹>
+	IPHash     string     `gorm:"size:64;index" json:"-"`                                                   // 游客举报时的 IP 匿名标识，登录用户为空
+	Reason     string     `gorm:"size:20;not null" json:"reason"`
+	Detail     string     `gorm:"size:500" json:"detail"`
+	Resolved   bool       `gorm:"default:false;index" json:"resolved"`
+	Resolution string     `gorm:"size:20" json:"resolution,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ReportReasonCount 单个举报原因的数量
+type ReportReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// ReportedComment 待处理举报队列中的评论与其未处理的举报
+type ReportedComment struct {
+	Comment     Comment             `json:"comment"`
+	ReportCount int64               `json:"report_count"`
+	Reasons     []ReportReasonCount `json:"reasons"`
+	Reports     []Report            `json:"reports"`
+}
+
+// IsValidReportReason 举报原因是否在可选范围内
+func IsValidReportReason(reason string) bool {
+	for _, value := range ReportReasons {
+		if value == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidReportResolution 举报处理结果是否有效
+func IsValidReportResolution(resolution string) bool {
+	switch resolution {
+	case ReportResolutionDismiss, ReportResolutionApprove, ReportResolutionReject:
+		return true
+	}
+	return false
+}
+
+// CreateReport 保存举报；同一举报者已举报过该评论时不重复记录，返回是否新建
+func CreateReport(report *Report) (bool, error) {
+	var count int64
+	match := func() *gorm.DB {
+		return DB.Model(&Report{}).Where("comment_id = ? AND reporter = ?", report.CommentID, report.Reporter)
+	}
+	if err := match().Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	if err := DB.Create(report).Error; err != nil {
+		// 并发重复提交时唯一索引冲突，视为已举报
+		if countErr := match().Count(&count).Error; countErr == nil && count > 0 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CountOpenReporters 统计评论未处理举报中计入自动待审核阈值的举报者数量：
+// 登录用户按账户计数，游客按 IP 计数，同一 IP 更换 User-Agent 重复举报只算一次；其余举报仍会保存供管理员查看
+func CountOpenReporters(commentID uint) (int64, error) {
+	open := func() *gorm.DB {
+		return DB.Model(&Report{}).Where("comment_id = ? AND resolved = ?", commentID, false)
+	}
+
+	var users, visitors int64
+	if err := open().Where("reporter LIKE ?", "user:%").Distinct("reporter").Count(&users).Error; err != nil {
+		return 0, err
+	}
+	if err := open().Where("reporter NOT LIKE ? AND ip_hash <> ''", "user:%").Distinct("ip_hash").Count(&visitors).Error; err != nil {
+		return 0, err
+	}
+	return users + visitors, nil
+}
+
+// ListReportedComments 分页查询存在未处理举报的评论，按举报数量与最近举报时间排序，可按 siteId 过滤
+func ListReportedComments(siteID string, page, pageSize int) ([]ReportedComment, int64, error) {
+	query := func() *gorm.DB {
+		q := DB.Model(&Report{}).
+			Joins("JOIN comments ON comments.id = reports.comment_id").
+			Where("reports.resolved = ?", false)
+		if siteID != "" {
+			q = q.Where("reports.site_id = ?", siteID)
+		}
+		return q
+	}
+
+	var total int64
+	if err := query().Distinct("reports.comment_id").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		CommentID   uint
+		ReportCount int64
+	}
+	err := query().
+		Select("reports.comment_id AS comment_id, COUNT(*) AS report_count").
+		Group("reports.comment_id").
+		Order("report_count DESC, MAX(reports.id) DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(rows) == 0 {
+		return []ReportedComment{}, total, nil
+	}
+
+	commentIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		commentIDs = append(commentIDs, row.CommentID)
+	}
+	var comments []Comment
+	if err := DB.Where("id IN ?", commentIDs).Find(&comments).Error; err != nil {
+		return nil, 0, err
+	}
+	commentsByID := make(map[uint]Comment, len(comments))
+	for _, comment := range comments {
+		commentsByID[comment.ID] = comment
+	}
+	var reports []Report
+	if err := DB.Where("comment_id IN ? AND resolved = ?", commentIDs, false).Order("id ASC").Find(&reports).Error; err != nil {
+		return nil, 0, err
+	}
+	reportsByComment := make(map[uint][]Report, len(rows))
+	for _, report := range reports {
+		reportsByComment[report.CommentID] = append(reportsByComment[report.CommentID], report)
+	}
+
+	result := make([]ReportedComment, 0, len(rows))
+	for _, row := range rows {
+		comment, ok := commentsByID[row.CommentID]
+		if !ok {
+			continue
+		}
+		item := ReportedComment{
+			Comment:     comment,
+			ReportCount: row.ReportCount,
+			Reasons:     make([]ReportReasonCount, 0),
+			Reports:     reportsByComment[row.CommentID],
+		}
+		for _, reason := range ReportReasons {
+			var count int64
+			for _, report := range item.Reports {
+				if report.Reason == reason {
+					count++
+				}
+			}
+			if count > 0 {
+				item.Reasons = append(item.Reasons, ReportReasonCount{Reason: reason, Count: count})
+			}
+		}
+		result = append(result, item)
+	}
+	return result, total, nil
+}
+
+// ResolveReports 将评论的全部未处理举报标记为已处理，并按处理结果更新评论状态；没有未处理举报时返回 gorm.ErrRecordNotFound
+func ResolveReports(comment *Comment, resolution string, status *int) (int64, error) {
+	var resolved int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&Report{}).
+			Where("comment_id = ? AND resolved = ?", comment.ID, false).
+			Updates(map[string]interface{}{"resolved": true, "resolution": resolution, "resolved_at": &now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		resolved = result.RowsAffected
+		if status != nil {
+			return tx.Model(comment).Update("status", *status).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if status != nil {
+		comment.Status = *status
+	}
+	return resolved, nil
+}
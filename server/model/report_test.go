@@ -0,0 +1,49 @@
+package model
+
+import (
+	"marku-server/internal/testutil"
+	"testing"
+)
+
+func TestCountOpenReporters(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Report{})
+
+	reports := []Report{
+		{Reporter: UserReactor(1)},
+		{Reporter: UserReactor(2)},
+		// 同一 IP 更换 User-Agent 得到不同指纹，只计一次
+		{Reporter: VisitorReactor("fp-a"), IPHash: "ip-1"},
+		{Reporter: VisitorReactor("fp-b"), IPHash: "ip-1"},
+		{Reporter: VisitorReactor("fp-c"), IPHash: "ip-1"},
+		{Reporter: VisitorReactor("fp-d"), IPHash: "ip-2"},
+		// 升级前没有 IP 标识的游客举报不计入阈值
+		{Reporter: VisitorReactor("fp-e")},
+		{Reporter: UserReactor(3), Resolved: true},
+	}
+	for i := range reports {
+		reports[i].CommentID = 10
+		reports[i].SiteID = "blog"
+		reports[i].Reason = ReportReasonSpam
+		created, err := CreateReport(&reports[i])
+		if err != nil || !created {
+			t.Fatalf("create report %d: created=%v err=%v", i, created, err)
+		}
+	}
+	if created, err := CreateReport(&Report{CommentID: 10, SiteID: "blog", Reporter: UserReactor(1), Reason: ReportReasonSpam}); err != nil || created {
+		t.Fatalf("duplicate report: created=%v err=%v", created, err)
+	}
+
+	count, err := CountOpenReporters(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("CountOpenReporters = %d, want 4", count)
+	}
+
+	var stored int64
+	DB.Model(&Report{}).Where("comment_id = ?", 10).Count(&stored)
+	if stored != int64(len(reports)) {
+		t.Fatalf("stored %d reports, want %d", stored, len(reports))
+	}
+}
@@ -0,0 +1,236 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"marku-server/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	refreshTokenTTL        = 30 * 24 * time.Hour
+	sessionTouchInterval   = time.Minute
+	refreshTokenByteLength = 32
+)
+
+// Session 登录会话记录，保存刷新令牌摘要与设备信息
+type Session struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	UserID            uint       `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"size:64;index" json:"-"` // 上一次轮换前的刷新令牌摘要，用于发现令牌重放
+	Device            string     `gorm:"size:100" json:"device"`
+	IP                *string    `gorm:"size:45" json:"ip,omitempty"`
+	UA                *string    `gorm:"size:500" json:"ua,omitempty"`
+	LastSeenAt        time.Time  `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt         time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt         *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// IsActive 判断会话是否仍然有效
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// CreateSession 创建登录会话并返回明文刷新令牌
+func CreateSession(userID uint, device, ip, ua string) (*Session, string, error) {
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenByteLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &Session{
+		UserID:           userID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		Device:           truncateString(strings.TrimSpace(device), 100),
+		IP:               optionalString(truncateString(ip, 45)),
+		UA:               optionalString(truncateString(ua, 500)),
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL),
+	}
+
+	if err := DB.Create(session).Error; err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// RotateSessionRefreshToken 使用刷新令牌换取新的刷新令牌，旧令牌随即失效
+func RotateSessionRefreshToken(refreshToken, ip, ua string) (*Session, string, error) {
+	hash := utils.HashToken(strings.TrimSpace(refreshToken))
+
+	var session Session
+	if err := DB.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", err
+		}
+		// 已轮换过的令牌再次出现，说明令牌可能被盗用，直接吊销整个会话
+		var reused Session
+		if DB.Where("previous_token_hash = ?", hash).First(&reused).Error == nil {
+			_ = RevokeSession(reused.UserID, reused.ID)
+		}
+		return nil, "", fmt.Errorf("刷新令牌无效")
+	}
+
+	if !session.IsActive() {
+		return nil, "", fmt.Errorf("会话已失效")
+	}
+
+	newToken, err := utils.GenerateRandomToken(refreshTokenByteLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"refresh_token_hash":  utils.HashToken(newToken),
+		"previous_token_hash": hash,
+		"last_seen_at":        now,
+		"expires_at":          now.Add(refreshTokenTTL),
+	}
+	if ip != "" {
+		updates["ip"] = truncateString(ip, 45)
+	}
+	if ua != "" {
+		updates["ua"] = truncateString(ua, 500)
+	}
+
+	result := DB.Model(&Session{}).Where("id = ? AND refresh_token_hash = ?", session.ID, hash).Updates(updates)
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, "", fmt.Errorf("刷新令牌已被使用")
+	}
+
+	if err := DB.First(&session, session.ID).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, newToken, nil
+}
+
+// GetActiveSession 获取仍然有效的会话
+func GetActiveSession(id uint) (*Session, error) {
+	var session Session
+	if err := DB.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	if !session.IsActive() {
+		return nil, fmt.Errorf("会话已失效")
+	}
+	return &session, nil
+}
+
+// TouchSession 刷新会话最后活跃时间，避免每个请求都写库
+func TouchSession(session *Session) {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+	if err := DB.Model(&Session{}).Where("id = ?", session.ID).Update("last_seen_at", now).Error; err == nil {
+		session.LastSeenAt = now
+	}
+}
+
+// ListUserSessions 列出用户当前有效的会话
+func ListUserSessions(userID uint) ([]Session, error) {
+	var sessions []Session
+	err := DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession 吊销用户的指定会话
+func RevokeSession(userID, sessionID uint) error {
+	result := DB.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions 吊销用户的全部会话，可保留一个会话（如当前会话）
+func RevokeUserSessions(userID uint, exceptSessionID uint) error {
+	db := DB.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptSessionID != 0 {
+		db = db.Where("id <> ?", exceptSessionID)
+	}
+	if err := db.Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return RevokeLegacyTokens(userID)
+}
+
+// RevokeLegacyTokens 使用户此前签发的不绑定会话的旧令牌全部失效
+func RevokeLegacyTokens(userID uint) error {
+	return DB.Model(&User{}).Where("id = ?", userID).Update("tokens_valid_after", time.Now()).Error
+}
+
+// AuthenticateAccessToken 校验访问令牌并返回对应用户与会话；不绑定会话的旧令牌返回的会话为 nil
+func AuthenticateAccessToken(token string) (*User, *Session, error) {
+	claims, err := utils.ParseAuthToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 升级前签发的旧令牌不绑定会话，迁移窗口内校验用户及其签发时间，会话返回 nil
+	if claims.SessionID == 0 {
+		user, err := GetUserByID(claims.UserID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("用户不存在")
+		}
+		if user.TokensValidAfter != nil && !time.Unix(claims.IssuedAt, 0).After(*user.TokensValidAfter) {
+			return nil, nil, fmt.Errorf("登录已失效，请重新登录")
+		}
+		return user, nil, nil
+	}
+
+	session, err := GetActiveSession(claims.SessionID)
+	if err != nil || session.UserID != claims.UserID {
+		return nil, nil, fmt.Errorf("会话已失效")
+	}
+
+	user, err := GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("用户不存在")
+	}
+
+	TouchSession(session)
+	return user, session, nil
+}
+
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// truncateString 按字节上限截断字符串，且不截断多字节字符
+func truncateString(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	end := 0
+	for i := range value {
+		if i > max {
+			break
+		}
+		end = i
+	}
+	return value[:end]
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"testing"
+	"time"
+)
+
+// signBaselineToken 按升级前的 userID:expiry:hexsig 格式签发令牌
+func signBaselineToken(appKey, payload string) string {
+	mac := hmac.New(sha256.New, []byte(appKey))
+	_, _ = mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload + ":" + hex.EncodeToString(mac.Sum(nil))))
+}
+
+func TestAuthenticateBaselineToken(t *testing.T) {
+	testutil.SetupDB(t, &DB, &User{}, &Session{})
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "baseline-app-key"
+	cfg.Auth.JWT.LegacyTokenUntil = time.Now().Add(time.Hour).Format(time.RFC3339)
+	testutil.UseConfig(t, cfg)
+
+	user := User{Username: "alice"}
+	if err := DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	expiry := time.Now().Add(24 * time.Hour).Unix()
+
+	got, session, err := AuthenticateAccessToken(signBaselineToken(cfg.Site.AppKey, fmt.Sprintf("%d:%d", user.ID, expiry)))
+	if err != nil {
+		t.Fatalf("baseline token rejected: %v", err)
+	}
+	if got.ID != user.ID || session != nil {
+		t.Fatalf("got user %d session %v, want user %d without session", got.ID, session, user.ID)
+	}
+
+	if _, _, err := AuthenticateAccessToken(signBaselineToken(cfg.Site.AppKey, fmt.Sprintf("%d:%d", user.ID+1, expiry))); err == nil {
+		t.Fatal("baseline token for a missing user accepted")
+	}
+}
+
+func TestPasswordChangeRevokesBaselineTokens(t *testing.T) {
+	testutil.SetupDB(t, &DB, &User{}, &Session{})
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "baseline-app-key"
+	cfg.Auth.JWT.LegacyTokenUntil = time.Now().Add(time.Hour).Format(time.RFC3339)
+	testutil.UseConfig(t, cfg)
+
+	user := User{Username: "alice"}
+	if err := DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 旧令牌有效期 30 天，剩余 29 天即一天前签发
+	expiry := time.Now().Add(29 * 24 * time.Hour).Unix()
+	token := signBaselineToken(cfg.Site.AppKey, fmt.Sprintf("%d:%d", user.ID, expiry))
+	if _, _, err := AuthenticateAccessToken(token); err != nil {
+		t.Fatalf("baseline token rejected before password change: %v", err)
+	}
+
+	// 与 RecoverPassword/ChangePassword 相同：更新密码后吊销全部会话
+	if err := UpdateUserPassword(user.ID, "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RevokeUserSessions(user.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := AuthenticateAccessToken(token); err == nil {
+		t.Fatal("baseline token accepted after password change")
+	}
+}
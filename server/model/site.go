@@ -0,0 +1,221 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"marku-server/config"
+	"marku-server/types"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownSite 站点未登记或已停用
+	ErrUnknownSite = errors.New("站点不存在或已停用")
+	// ErrSiteRegistryUnavailable 无法读取站点登记信息
+	ErrSiteRegistryUnavailable = errors.New("站点信息暂时无法读取")
+)
+
+// 站点缓存的刷新间隔，多实例部署时其他实例的修改最迟在该时长后生效
+const siteCacheTTL = time.Minute
+
+// Site 站点登记信息，评论相关字段为空时继承全局 comment 配置
+type Site struct {
+	ID                 uint             `gorm:"primaryKey" json:"id"`
+	SiteID             string           `gorm:"size:100;not null;uniqueIndex" json:"site_id"`
+	Name               string           `gorm:"size:100;not null" json:"name"`
+	AllowedOrigins     types.StringList `gorm:"type:text" json:"allowed_origins"`     // 允许调用接口的来源，空表示不限制
+	DefaultStatus      string           `gorm:"size:20" json:"default_status"`        // 新评论默认状态
+	RequireLogin       *bool            `json:"require_login"`                        // 评论是否需要登录
+	ModerationKeywords types.StringList `gorm:"type:text" json:"moderation_keywords"` // 命中关键词的评论进入待审核
+	MaxCommentLength   int              `gorm:"default:0" json:"max_comment_length"`  // 评论最大字符数，0 表示不限制
+	PublicFields       types.StringList `gorm:"type:text" json:"public_fields"`       // 评论列表对非管理员公开的作者字段
+	Reactions          types.StringList `gorm:"type:text" json:"reactions"`           // 可用的表情回应，空表示使用全局配置
+	Disabled           bool             `gorm:"default:false;index" json:"disabled"`  // 停用后拒绝该站点的所有请求
+	CreatedAt          time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// CommentStatus 新评论的默认状态值
+func (s *Site) CommentStatus() int {
+	if s != nil && strings.TrimSpace(s.DefaultStatus) != "" {
+		return config.GetCommentStatusValue(s.DefaultStatus)
+	}
+	return config.GetDefaultCommentStatusValue()
+}
+
+// LoginRequired 评论是否需要登录
+func (s *Site) LoginRequired() bool {
+	if s != nil && s.RequireLogin != nil {
+		return *s.RequireLogin
+	}
+	return config.IsCommentLoginRequired()
+}
+
+// NeedsModeration 评论内容是否命中站点的审核关键词
+func (s *Site) NeedsModeration(content string) bool {
+	if s == nil {
+		return false
+	}
+	lowered := strings.ToLower(content)
+	for _, keyword := range s.ModerationKeywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" && strings.Contains(lowered, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommentPublicFields 评论列表对非管理员公开的作者字段
+func (s *Site) CommentPublicFields(siteID string) []string {
+	if s != nil && len(s.PublicFields) > 0 {
+		return s.PublicFields
+	}
+	return config.GetCommentPublicFields(siteID)
+}
+
+// ReactionSet 站点可用的表情回应
+func (s *Site) ReactionSet() []string {
+	if s != nil && len(s.Reactions) > 0 {
+		return s.Reactions
+	}
+	return config.GetCommentReactions()
+}
+
+// AllowsOrigin 判断请求来源是否在站点允许列表内，未配置来源时不限制
+func (s *Site) AllowsOrigin(origin string) bool {
+	if s == nil || len(s.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range s.AllowedOrigins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+var siteCache struct {
+	sync.RWMutex
+	sites    map[string]*Site
+	loadedAt time.Time
+}
+
+// cachedSites 返回按 SiteID 索引的站点缓存，过期后从数据库重新加载；
+// 重新加载失败时沿用旧缓存，从未加载成功时返回错误，避免把读取失败当作“未登记站点”而放开校验
+func cachedSites() (map[string]*Site, error) {
+	siteCache.RLock()
+	sites, loadedAt := siteCache.sites, siteCache.loadedAt
+	siteCache.RUnlock()
+	if sites != nil && time.Since(loadedAt) < siteCacheTTL {
+		return sites, nil
+	}
+
+	var records []Site
+	if err := DB.Find(&records).Error; err != nil {
+		if sites != nil {
+			return sites, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSiteRegistryUnavailable, err)
+	}
+
+	sites = make(map[string]*Site, len(records))
+	for i := range records {
+		sites[records[i].SiteID] = &records[i]
+	}
+
+	siteCache.Lock()
+	siteCache.sites, siteCache.loadedAt = sites, time.Now()
+	siteCache.Unlock()
+	return sites, nil
+}
+
+// invalidateSiteCache 使缓存过期，下次读取时重新加载；保留旧数据供加载失败时沿用
+func invalidateSiteCache() {
+	siteCache.Lock()
+	siteCache.loadedAt = time.Time{}
+	siteCache.Unlock()
+}
+
+// ResolveSite 查找已登记且启用的站点；尚未登记任何站点时不做限制并返回 nil，兼容旧部署
+func ResolveSite(siteID string) (*Site, error) {
+	sites, err := cachedSites()
+	if err != nil {
+		return nil, err
+	}
+	if len(sites) == 0 {
+		return nil, nil
+	}
+	site, ok := sites[strings.TrimSpace(siteID)]
+	if !ok || site.Disabled {
+		return nil, ErrUnknownSite
+	}
+	return site, nil
+}
+
+// IsOriginAllowedBySites 判断来源是否出现在启用站点的允许列表中；指定 siteID 时只检查该站点，
+// 避免站点 A 的来源借用站点 B 的跨域许可
+func IsOriginAllowedBySites(siteID, origin string) bool {
+	sites, err := cachedSites()
+	if err != nil {
+		return false
+	}
+	siteID = strings.TrimSpace(siteID)
+	for _, site := range sites {
+		if site.Disabled || (siteID != "" && site.SiteID != siteID) {
+			continue
+		}
+		for _, allowed := range site.AllowedOrigins {
+			if allowed == origin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ListSites 查询全部站点
+func ListSites() ([]Site, error) {
+	var sites []Site
+	if err := DB.Order("id ASC").Find(&sites).Error; err != nil {
+		return nil, err
+	}
+	return sites, nil
+}
+
+// GetSiteByID 根据主键查询站点
+func GetSiteByID(id uint) (*Site, error) {
+	var site Site
+	if err := DB.First(&site, id).Error; err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// CreateSite 登记站点
+func CreateSite(site *Site) error {
+	if err := DB.Create(site).Error; err != nil {
+		return err
+	}
+	invalidateSiteCache()
+	return nil
+}
+
+// UpdateSite 保存站点设置
+func UpdateSite(site *Site) error {
+	if err := DB.Save(site).Error; err != nil {
+		return err
+	}
+	invalidateSiteCache()
+	return nil
+}
+
+// DeleteSite 删除站点登记，已有的评论与计数不受影响
+func DeleteSite(id uint) error {
+	if err := DB.Delete(&Site{}, id).Error; err != nil {
+		return err
+	}
+	invalidateSiteCache()
+	return nil
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"marku-server/internal/testutil"
+	"testing"
+	"time"
+)
+
+func resetSiteCache(t *testing.T) {
+	t.Helper()
+	siteCache.Lock()
+	siteCache.sites, siteCache.loadedAt = nil, time.Time{}
+	siteCache.Unlock()
+	t.Cleanup(func() {
+		siteCache.Lock()
+		siteCache.sites, siteCache.loadedAt = nil, time.Time{}
+		siteCache.Unlock()
+	})
+}
+
+func TestResolveSite(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Site{})
+	resetSiteCache(t)
+
+	// 未登记任何站点时不做限制
+	if site, err := ResolveSite("blog"); site != nil || err != nil {
+		t.Fatalf("empty registry: site %v err %v", site, err)
+	}
+
+	if err := CreateSite(&Site{SiteID: "blog", Name: "Blog", AllowedOrigins: []string{"https://blog.example"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSite(&Site{SiteID: "old", Name: "Old", Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if site, err := ResolveSite("blog"); err != nil || site == nil || site.SiteID != "blog" {
+		t.Fatalf("registered site: site %v err %v", site, err)
+	}
+	for _, siteID := range []string{"unknown", "old"} {
+		if _, err := ResolveSite(siteID); !errors.Is(err, ErrUnknownSite) {
+			t.Fatalf("%s: err = %v, want ErrUnknownSite", siteID, err)
+		}
+	}
+	if err := CreateSite(&Site{SiteID: "docs", Name: "Docs", AllowedOrigins: []string{"https://docs.example"}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		siteID, origin string
+		want           bool
+	}{
+		{"", "https://blog.example", true},
+		{"", "https://evil.example", false},
+		{"blog", "https://blog.example", true},
+		// 站点 A 的来源不能获得站点 B 的跨域许可
+		{"docs", "https://blog.example", false},
+		{"unknown", "https://blog.example", false},
+	}
+	for _, tt := range tests {
+		if got := IsOriginAllowedBySites(tt.siteID, tt.origin); got != tt.want {
+			t.Errorf("IsOriginAllowedBySites(%q, %q) = %v, want %v", tt.siteID, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSiteFailsClosed(t *testing.T) {
+	testutil.SetupDB(t, &DB)
+	resetSiteCache(t)
+
+	// sites 表不存在，模拟数据库读取失败
+	if _, err := ResolveSite("blog"); !errors.Is(err, ErrSiteRegistryUnavailable) {
+		t.Fatalf("err = %v, want ErrSiteRegistryUnavailable", err)
+	}
+	if IsOriginAllowedBySites("", "https://blog.example") {
+		t.Fatal("origin allowed while registry is unavailable")
+	}
+}
+
+func TestResolveSiteKeepsStaleCacheOnError(t *testing.T) {
+	testutil.SetupDB(t, &DB, &Site{})
+	resetSiteCache(t)
+
+	if err := CreateSite(&Site{SiteID: "blog", Name: "Blog"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveSite("blog"); err != nil {
+		t.Fatal(err)
+	}
+
+	invalidateSiteCache()
+	if err := DB.Migrator().DropTable(&Site{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveSite("unknown"); !errors.Is(err, ErrUnknownSite) {
+		t.Fatalf("err = %v, want stale cache to keep rejecting unknown sites", err)
+	}
+}
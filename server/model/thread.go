@@ -0,0 +1,140 @@
+package model
+
+import (
+	"errors"
+	"marku-server/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ThreadSetting 单个页面（SiteID + Mark）的评论设置
+type ThreadSetting struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	SiteID          string    `gorm:"size:100;not null;uniqueIndex:idx_thread_site_mark,priority:1" json:"site_id"`
+	Mark            string    `gorm:"size:255;not null;uniqueIndex:idx_thread_site_mark,priority:2" json:"mark"`
+	Closed          bool      `gorm:"default:false" json:"closed"`           // 关闭后不再接受任何新评论
+	Locked          bool      `gorm:"default:false" json:"locked"`           // 锁定后不再接受回复，仍可发表顶级评论
+	AutoCloseDays   int       `gorm:"default:0" json:"auto_close_days"`      // 自第一条评论起 N 天后自动关闭，0 表示不自动关闭
+	ForceModeration bool      `gorm:"default:false" json:"force_moderation"` // 该页面的新评论一律进入待审核
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ThreadState 页面当前的评论状态，随评论列表返回给前端
+type ThreadState struct {
+	Closed          bool       `json:"closed"`
+	Locked          bool       `json:"locked"`
+	ForceModeration bool       `json:"force_moderation"`
+	ClosesAt        *time.Time `json:"closes_at,omitempty"` // 自动关闭的时间点
+}
+
+// 页面设置拒绝新评论的原因
+var (
+	ErrThreadClosed = errors.New("该页面已关闭评论")
+	ErrThreadLocked = errors.New("该页面已锁定，不再接受回复")
+)
+
+// CheckNewComment 判断页面是否接受新评论，parentID 大于 0 表示回复
+func (s *ThreadState) CheckNewComment(parentID int) error {
+	if s.Closed {
+		return ErrThreadClosed
+	}
+	if parentID > 0 && s.Locked {
+		return ErrThreadLocked
+	}
+	return nil
+}
+
+// CommentStatus 页面开启强制审核时，原本直接通过的评论转入待审核
+func (s *ThreadState) CommentStatus(status int) int {
+	if s.ForceModeration && status == config.GetApprovedCommentStatusValue() {
+		return config.GetCommentStatusValue("pending")
+	}
+	return status
+}
+
+// GetThreadSetting 查询页面设置，未设置时返回 nil
+func GetThreadSetting(siteID, mark string) (*ThreadSetting, error) {
+	var setting ThreadSetting
+	if err := DB.Where("site_id = ? AND mark = ?", siteID, mark).First(&setting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// GetThreadState 计算页面当前的评论状态，自动关闭以该页面第一条评论的时间为起点
+func GetThreadState(siteID, mark string) (*ThreadState, error) {
+	state := &ThreadState{}
+	setting, err := GetThreadSetting(siteID, mark)
+	if err != nil || setting == nil {
+		return state, err
+	}
+
+	state.Closed = setting.Closed
+	state.Locked = setting.Locked
+	state.ForceModeration = setting.ForceModeration
+
+	if setting.AutoCloseDays > 0 {
+		var first Comment
+		err := DB.Select("created_at").Where("site_id = ? AND mark = ?", siteID, mark).Order("created_at ASC").First(&first).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			closesAt := first.CreatedAt.AddDate(0, 0, setting.AutoCloseDays)
+			state.ClosesAt = &closesAt
+			if !time.Now().Before(closesAt) {
+				state.Closed = true
+			}
+		}
+	}
+	return state, nil
+}
+
+// ListThreadSettings 分页查询页面设置，siteID 为空时返回全部站点
+func ListThreadSettings(siteID string, page, pageSize int) ([]ThreadSetting, int64, error) {
+	query := DB.Model(&ThreadSetting{})
+	if siteID != "" {
+		query = query.Where("site_id = ?", siteID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var settings []ThreadSetting
+	if err := query.Order("updated_at DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&settings).Error; err != nil {
+		return nil, 0, err
+	}
+	return settings, total, nil
+}
+
+// SaveThreadSetting 按 SiteID + Mark 创建或更新页面设置
+func SaveThreadSetting(setting *ThreadSetting) error {
+	existing, err := GetThreadSetting(setting.SiteID, setting.Mark)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		setting.ID = existing.ID
+		setting.CreatedAt = existing.CreatedAt
+	}
+	return DB.Save(setting).Error
+}
+
+// DeleteThreadSetting 删除页面设置，页面恢复为站点默认行为
+func DeleteThreadSetting(id uint) error {
+	result := DB.Delete(&ThreadSetting{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package model
+
+import (
+	"errors"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"testing"
+	"time"
+)
+
+func setupThreadTest(t *testing.T) {
+	t.Helper()
+	testutil.SetupDB(t, &DB, &ThreadSetting{}, &Comment{})
+	testutil.UseConfig(t, &config.Config{})
+}
+
+// createThreadComment 在 blog 站点的页面下创建一条指定时间的评论
+func createThreadComment(t *testing.T, mark string, createdAt time.Time) {
+	t.Helper()
+	comment := Comment{SiteID: "blog", Mark: mark, Content: "hi", Status: 1}
+	comment.CreatedAt = createdAt
+	if err := DB.Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThreadStateDefaults(t *testing.T) {
+	setupThreadTest(t)
+
+	state, err := GetThreadState("blog", "post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Closed || state.Locked || state.ForceModeration || state.ClosesAt != nil {
+		t.Fatalf("page without settings: %+v", state)
+	}
+	if err := state.CheckNewComment(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.CheckNewComment(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := state.CommentStatus(1); got != 1 {
+		t.Fatalf("status = %d, want 1", got)
+	}
+}
+
+func TestThreadClosedAndLocked(t *testing.T) {
+	setupThreadTest(t)
+	if err := SaveThreadSetting(&ThreadSetting{SiteID: "blog", Mark: "closed", Closed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveThreadSetting(&ThreadSetting{SiteID: "blog", Mark: "locked", Locked: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	closed, err := GetThreadState("blog", "closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, parent := range []int{0, 1} {
+		if err := closed.CheckNewComment(parent); !errors.Is(err, ErrThreadClosed) {
+			t.Fatalf("closed thread, parent %d: err = %v", parent, err)
+		}
+	}
+
+	// 锁定只拒绝回复，顶级评论照常接受
+	locked, err := GetThreadState("blog", "locked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := locked.CheckNewComment(0); err != nil {
+		t.Fatalf("locked thread rejected a top-level comment: %v", err)
+	}
+	if err := locked.CheckNewComment(3); !errors.Is(err, ErrThreadLocked) {
+		t.Fatalf("locked thread reply: err = %v", err)
+	}
+
+	// 设置按 SiteID + Mark 区分，同名页面在其他站点不受影响
+	other, err := GetThreadState("docs", "closed")
+	if err != nil || other.Closed {
+		t.Fatalf("setting leaked to another site: %+v %v", other, err)
+	}
+
+	// 再次保存同一页面会更新原设置而不是新增
+	if err := SaveThreadSetting(&ThreadSetting{SiteID: "blog", Mark: "closed"}); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := GetThreadState("blog", "closed")
+	if err != nil || reopened.Closed {
+		t.Fatalf("reopened thread: %+v %v", reopened, err)
+	}
+	var count int64
+	DB.Model(&ThreadSetting{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("stored %d settings, want 2", count)
+	}
+}
+
+func TestThreadAutoClose(t *testing.T) {
+	setupThreadTest(t)
+	now := time.Now()
+	for _, mark := range []string{"old", "recent", "empty"} {
+		if err := SaveThreadSetting(&ThreadSetting{SiteID: "blog", Mark: mark, AutoCloseDays: 7}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 以第一条评论计时：8 天前的首条评论使页面关闭，之后的新评论不会顺延
+	first := now.AddDate(0, 0, -8)
+	createThreadComment(t, "old", now.Add(-time.Hour))
+	createThreadComment(t, "old", first)
+	// 3 天前开始的页面尚未关闭；其他页面更早的评论不计入
+	createThreadComment(t, "recent", now.AddDate(0, 0, -3))
+	createThreadComment(t, "other", now.AddDate(0, 0, -30))
+
+	tests := []struct {
+		mark       string
+		wantClosed bool
+		wantCloses time.Time
+	}{
+		{"old", true, first.AddDate(0, 0, 7)},
+		{"recent", false, now.AddDate(0, 0, 4)},
+		// 还没有评论时不开始计时
+		{"empty", false, time.Time{}},
+	}
+	for _, tt := range tests {
+		state, err := GetThreadState("blog", tt.mark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Closed != tt.wantClosed {
+			t.Errorf("%s: closed = %v, want %v", tt.mark, state.Closed, tt.wantClosed)
+		}
+		if tt.wantCloses.IsZero() {
+			if state.ClosesAt != nil {
+				t.Errorf("%s: closes at %v, want none", tt.mark, state.ClosesAt)
+			}
+			continue
+		}
+		if state.ClosesAt == nil || state.ClosesAt.Sub(tt.wantCloses).Abs() > time.Second {
+			t.Errorf("%s: closes at %v, want %v", tt.mark, state.ClosesAt, tt.wantCloses)
+		}
+		if tt.wantClosed && !errors.Is(state.CheckNewComment(0), ErrThreadClosed) {
+			t.Errorf("%s: auto-closed thread accepts comments", tt.mark)
+		}
+	}
+}
+
+func TestThreadForceModeration(t *testing.T) {
+	setupThreadTest(t)
+	if err := SaveThreadSetting(&ThreadSetting{SiteID: "blog", Mark: "post", ForceModeration: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := GetThreadState("blog", "post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.ForceModeration {
+		t.Fatal("force moderation not reported")
+	}
+	if err := state.CheckNewComment(1); err != nil {
+		t.Fatalf("force moderation rejected a reply: %v", err)
+	}
+
+	tests := []struct{ status, want int }{
+		{1, 0},   // 直接通过的评论转入待审核
+		{0, 0},   // 待审核保持不变
+		{-1, -1}, // 已拒绝的不会被改回待审核
+	}
+	for _, tt := range tests {
+		if got := state.CommentStatus(tt.status); got != tt.want {
+			t.Errorf("CommentStatus(%d) = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"marku-server/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactor 用户的 TOTP 两步验证配置
+type TwoFactor struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	Secret          string     `gorm:"size:64;not null" json:"-"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedCounter int64      `gorm:"default:0" json:"-"` // 最近一次通过校验的时间步，防止动态码重放
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// RecoveryCode 两步验证恢复码，仅保存摘要
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// GetTwoFactor 获取用户的两步验证配置
+func GetTwoFactor(userID uint) (*TwoFactor, error) {
+	var twoFactor TwoFactor
+	if err := DB.Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+// IsTwoFactorEnabled 判断用户是否已启用两步验证
+func IsTwoFactorEnabled(userID uint) bool {
+	twoFactor, err := GetTwoFactor(userID)
+	return err == nil && twoFactor.ConfirmedAt != nil
+}
+
+// BeginTwoFactorSetup 生成新的待确认密钥，已启用时需先停用
+func BeginTwoFactorSetup(userID uint) (*TwoFactor, error) {
+	existing, err := GetTwoFactor(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.ConfirmedAt != nil {
+		return nil, fmt.Errorf("两步验证已启用")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		existing.Secret = secret
+		existing.LastUsedCounter = 0
+		if err := DB.Save(existing).Error; err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	twoFactor := &TwoFactor{UserID: userID, Secret: secret}
+	if err := DB.Create(twoFactor).Error; err != nil {
+		return nil, err
+	}
+	return twoFactor, nil
+}
+
+// ConfirmTwoFactor 校验首个动态码以启用两步验证，并返回一次性展示的恢复码
+func ConfirmTwoFactor(userID uint, code string) ([]string, error) {
+	twoFactor, err := GetTwoFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("请先生成两步验证密钥")
+	}
+	if twoFactor.ConfirmedAt != nil {
+		return nil, fmt.Errorf("两步验证已启用")
+	}
+
+	counter, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("动态码错误")
+	}
+
+	now := time.Now()
+	if err := DB.Model(twoFactor).Updates(map[string]interface{}{
+		"confirmed_at":      now,
+		"last_used_counter": counter,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return RegenerateRecoveryCodes(userID)
+}
+
+// DisableTwoFactor 停用两步验证并清除恢复码
+func DisableTwoFactor(userID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&TwoFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// VerifyTwoFactorCode 校验动态码或恢复码，恢复码使用后作废
+func VerifyTwoFactorCode(userID uint, code string) error {
+	twoFactor, err := GetTwoFactor(userID)
+	if err != nil || twoFactor.ConfirmedAt == nil {
+		return fmt.Errorf("未启用两步验证")
+	}
+
+	code = strings.TrimSpace(code)
+	if counter, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+		// 条件更新保证同一时间步的动态码只能使用一次
+		result := DB.Model(&TwoFactor{}).
+			Where("id = ? AND last_used_counter < ?", twoFactor.ID, counter).
+			Update("last_used_counter", counter)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("动态码已使用")
+		}
+		return nil
+	}
+
+	result := DB.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("验证码错误")
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(raw[:5] + "-" + raw[5:10])
+		codes = append(codes, code)
+		records = append(records, RecoveryCode{UserID: userID, CodeHash: utils.HashToken(normalizeRecoveryCode(code))})
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CountRemainingRecoveryCodes 统计未使用的恢复码数量
+func CountRemainingRecoveryCodes(userID uint) int64 {
+	var count int64
+	DB.Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count)
+	return count
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
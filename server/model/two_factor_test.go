@@ -0,0 +1,146 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"marku-server/internal/testutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// totpCode 按 RFC 6238 计算指定时间偏移处的 6 位动态码
+func totpCode(t *testing.T, secret string, offset time.Duration) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(time.Now().Add(offset).Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	_, _ = mac.Write(message[:])
+	sum := mac.Sum(nil)
+	index := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[index:index+4])&0x7fffffff)%1000000)
+}
+
+// enableTwoFactor 为用户启用两步验证，返回密钥与恢复码
+func enableTwoFactor(t *testing.T, userID uint) (string, []string) {
+	t.Helper()
+	twoFactor, err := BeginTwoFactorSetup(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConfirmTwoFactor(userID, "000000x"); err == nil {
+		t.Fatal("malformed code confirmed two-factor setup")
+	}
+	codes, err := ConfirmTwoFactor(userID, totpCode(t, twoFactor.Secret, -30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsTwoFactorEnabled(userID) || len(codes) != recoveryCodeCount {
+		t.Fatalf("two-factor not enabled, %d recovery codes", len(codes))
+	}
+	return twoFactor.Secret, codes
+}
+
+func TestVerifyTwoFactorCodeRejectsReplay(t *testing.T) {
+	testutil.SetupDB(t, &DB, &TwoFactor{}, &RecoveryCode{})
+	secret, _ := enableTwoFactor(t, 1)
+
+	// 确认启用时使用的动态码不能再用于登录
+	if err := VerifyTwoFactorCode(1, totpCode(t, secret, -30*time.Second)); err == nil {
+		t.Fatal("setup code accepted again")
+	}
+
+	current := totpCode(t, secret, 0)
+	if err := VerifyTwoFactorCode(1, current); err != nil {
+		t.Fatalf("current code rejected: %v", err)
+	}
+	if err := VerifyTwoFactorCode(1, current); err == nil || !strings.Contains(err.Error(), "已使用") {
+		t.Fatalf("replayed code: %v", err)
+	}
+
+	// 下一个时间步的动态码仍在容差内，可以使用；之后更早的时间步全部作废
+	if err := VerifyTwoFactorCode(1, totpCode(t, secret, 30*time.Second)); err != nil {
+		t.Fatalf("next step code rejected: %v", err)
+	}
+	if err := VerifyTwoFactorCode(1, current); err == nil {
+		t.Fatal("earlier step code accepted after a later one")
+	}
+	if err := VerifyTwoFactorCode(2, current); err == nil {
+		t.Fatal("code accepted for user without two-factor")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	testutil.SetupDB(t, &DB, &TwoFactor{}, &RecoveryCode{})
+	_, codes := enableTwoFactor(t, 1)
+	enableTwoFactor(t, 2)
+
+	if err := VerifyTwoFactorCode(2, codes[0]); err == nil {
+		t.Fatal("recovery code accepted for another user")
+	}
+	if err := VerifyTwoFactorCode(1, codes[0]); err != nil {
+		t.Fatalf("recovery code rejected: %v", err)
+	}
+	if err := VerifyTwoFactorCode(1, codes[0]); err == nil {
+		t.Fatal("recovery code accepted twice")
+	}
+	// 恢复码不区分大小写，连字符可省略
+	if err := VerifyTwoFactorCode(1, " "+strings.ToUpper(strings.ReplaceAll(codes[1], "-", ""))+" "); err != nil {
+		t.Fatalf("normalized recovery code rejected: %v", err)
+	}
+	if remaining := CountRemainingRecoveryCodes(1); remaining != recoveryCodeCount-2 {
+		t.Fatalf("remaining = %d, want %d", remaining, recoveryCodeCount-2)
+	}
+
+	regenerated, err := RegenerateRecoveryCodes(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTwoFactorCode(1, codes[2]); err == nil {
+		t.Fatal("old recovery code accepted after regeneration")
+	}
+	if remaining := CountRemainingRecoveryCodes(1); remaining != recoveryCodeCount {
+		t.Fatalf("remaining after regeneration = %d", remaining)
+	}
+
+	if err := DisableTwoFactor(1); err != nil {
+		t.Fatal(err)
+	}
+	if IsTwoFactorEnabled(1) || CountRemainingRecoveryCodes(1) != 0 {
+		t.Fatal("two-factor data left after disable")
+	}
+	if err := VerifyTwoFactorCode(1, regenerated[0]); err == nil {
+		t.Fatal("recovery code accepted after disable")
+	}
+	if CountRemainingRecoveryCodes(2) != recoveryCodeCount {
+		t.Fatal("disabling one user removed another user's recovery codes")
+	}
+}
+
+func TestBeginTwoFactorSetupRequiresDisable(t *testing.T) {
+	testutil.SetupDB(t, &DB, &TwoFactor{}, &RecoveryCode{})
+	pending, err := BeginTwoFactorSetup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未确认前重新生成会替换密钥
+	again, err := BeginTwoFactorSetup(1)
+	if err != nil || again.Secret == pending.Secret {
+		t.Fatalf("pending setup not replaced: %v", err)
+	}
+	if IsTwoFactorEnabled(1) {
+		t.Fatal("unconfirmed setup reported as enabled")
+	}
+
+	enableTwoFactor(t, 1)
+	if _, err := BeginTwoFactorSetup(1); err == nil {
+		t.Fatal("setup restarted while enabled")
+	}
+}
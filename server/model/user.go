@@ -17,9 +17,14 @@ type User struct {
 	Role     int     `gorm:"default:1;index" json:"role"`
 	URL      *string `gorm:"size:500" json:"url"`
 	Avatar   *string `gorm:"size:500" json:"avatar"`
+	Bio      *string `gorm:"size:500" json:"bio"`
 	IP       *string `gorm:"size:45" json:"ip"`
 	UA       *string `gorm:"size:1000" json:"ua"`
 	Location *string `gorm:"size:100" json:"location"`
+	// 是否退订评论中被 @提及 的邮件通知
+	MentionEmailOptOut bool `gorm:"default:false" json:"mention_email_opt_out"`
+	// 早于该时间签发的不绑定会话的旧令牌一律失效，修改密码或吊销全部会话时更新
+	TokensValidAfter *time.Time `json:"-"`
 	types.BaseModel
 }
 
@@ -109,11 +114,49 @@ func CreateUser(username, password, email string) (*User, error) {
 	return user, nil
 }
 
+// CreateEmailUser 通过邮箱创建无密码的注册用户
+func CreateEmailUser(email string) (*User, error) {
+	email = strings.TrimSpace(email)
+	user := &User{
+		Username: generateUsernameFromEmail(email),
+		Email:    &email,
+		Role:     types.RoleUser,
+	}
+
+	if err := DB.Create(user).Error; err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpgradeGuestUser 将游客用户升级为注册用户
+func UpgradeGuestUser(user *User) error {
+	if err := DB.Model(&User{}).Where("id = ? AND role = ?", user.ID, types.RoleGuest).Update("role", types.RoleUser).Error; err != nil {
+		return err
+	}
+	user.Role = types.RoleUser
+	return nil
+}
+
 // UpdateUserPassword 更新用户密码
 func UpdateUserPassword(userID uint, encryptedPassword string) error {
 	return DB.Model(&User{}).Where("id = ?", userID).Update("password", encryptedPassword).Error
 }
 
+// UpdateUserProfile 更新用户资料字段，updates 的键为数据库列名
+func UpdateUserProfile(userID uint, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	return DB.Model(&User{}).Where("id = ?", userID).Updates(updates).Error
+}
+
+// UpdateUserEmail 更新用户邮箱
+func UpdateUserEmail(userID uint, email string) error {
+	return DB.Model(&User{}).Where("id = ?", userID).Update("email", strings.TrimSpace(email)).Error
+}
+
 // FindRegisteredUserByAccount 通过账号找到注册用户
 func FindRegisteredUserByAccount(account string) (*User, error) {
 	return GetUserByEmailOrName(strings.TrimSpace(account))
@@ -127,6 +170,31 @@ func VerifyUserPassword(user *User, password string) bool {
 	return utils.CheckPasswordEncrypt(*user.Password, password) == nil
 }
 
+// generateUsernameFromEmail 根据邮箱前缀生成未被占用的用户名
+func generateUsernameFromEmail(email string) string {
+	base := email
+	if at := strings.Index(email, "@"); at > 0 {
+		base = email[:at]
+	}
+	return generateAvailableUsername(base)
+}
+
+// generateAvailableUsername 以给定名称为基础生成未被占用的用户名
+func generateAvailableUsername(base string) string {
+	base = strings.TrimSpace(base)
+	if len(base) > 80 {
+		base = truncateString(base, 80)
+	}
+	if base == "" {
+		return generateGuestUsername()
+	}
+
+	if _, err := GetUserByName(base); err != nil {
+		return base
+	}
+	return base + "_" + fmt.Sprintf("%d", time.Now().UnixNano()%1000000)
+}
+
 // generateGuestUsername 生成游客用户名
 func generateGuestUsername() string {
 	return "guest_" + fmt.Sprintf("%d", time.Now().UnixNano())
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	"marku-server/utils"
+	"time"
+)
+
+const (
+	WebAuthnCeremonyRegister     = "register"
+	WebAuthnCeremonyAuthenticate = "authenticate"
+
+	webAuthnChallengeTTL = 5 * time.Minute
+)
+
+// WebAuthnCredential 用户登记的通行密钥
+type WebAuthnCredential struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	CredentialID string     `gorm:"size:255;not null;uniqueIndex" json:"credential_id"` // base64url 编码
+	PublicKey    []byte     `gorm:"not null" json:"-"`                                  // COSE_Key 原始编码
+	Algorithm    int        `gorm:"not null" json:"algorithm"`
+	SignCount    uint32     `gorm:"default:0" json:"sign_count"`
+	AAGUID       string     `gorm:"size:36" json:"aaguid"`
+	RPID         string     `gorm:"size:255;not null;index" json:"rp_id"`
+	Name         string     `gorm:"size:100" json:"name"`
+	Transports   string     `gorm:"size:100" json:"transports"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// WebAuthnChallenge 注册或登录仪式中下发的一次性挑战
+type WebAuthnChallenge struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Challenge string    `gorm:"size:100;not null;uniqueIndex" json:"challenge"`
+	Ceremony  string    `gorm:"size:20;not null" json:"ceremony"`
+	UserID    uint      `gorm:"default:0" json:"user_id"` // 登录仪式未指定账户时为 0
+	RPID      string    `gorm:"size:255;not null" json:"rp_id"`
+	Origin    string    `gorm:"size:500;not null" json:"origin"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// CreateWebAuthnChallenge 生成挑战并记录仪式上下文
+func CreateWebAuthnChallenge(ceremony string, userID uint, rpID, origin string) (*WebAuthnChallenge, error) {
+	challenge, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = DB.Where("expires_at < ?", time.Now()).Delete(&WebAuthnChallenge{}).Error
+
+	record := &WebAuthnChallenge{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		UserID:    userID,
+		RPID:      rpID,
+		Origin:    origin,
+		ExpiresAt: time.Now().Add(webAuthnChallengeTTL),
+	}
+	if err := DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ConsumeWebAuthnChallenge 取出并删除挑战，保证只能使用一次
+func ConsumeWebAuthnChallenge(ceremony, challenge string) (*WebAuthnChallenge, error) {
+	var record WebAuthnChallenge
+	if err := DB.Where("challenge = ? AND ceremony = ?", challenge, ceremony).First(&record).Error; err != nil {
+		return nil, fmt.Errorf("挑战无效")
+	}
+
+	result := DB.Delete(&WebAuthnChallenge{}, record.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("挑战已使用")
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, fmt.Errorf("挑战已过期")
+	}
+	return &record, nil
+}
+
+// CreateWebAuthnCredential 保存新登记的通行密钥
+func CreateWebAuthnCredential(credential *WebAuthnCredential) error {
+	var count int64
+	if err := DB.Model(&WebAuthnCredential{}).Where("credential_id = ?", credential.CredentialID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("该通行密钥已登记")
+	}
+	return DB.Create(credential).Error
+}
+
+// GetWebAuthnCredential 根据凭证ID获取通行密钥
+func GetWebAuthnCredential(credentialID string) (*WebAuthnCredential, error) {
+	var credential WebAuthnCredential
+	if err := DB.Where("credential_id = ?", credentialID).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// ListUserWebAuthnCredentials 列出用户在指定 RP 下的通行密钥，rpID 为空时列出全部
+func ListUserWebAuthnCredentials(userID uint, rpID string) ([]WebAuthnCredential, error) {
+	var credentials []WebAuthnCredential
+	db := DB.Where("user_id = ?", userID)
+	if rpID != "" {
+		db = db.Where("rp_id = ?", rpID)
+	}
+	err := db.Order("created_at ASC").Find(&credentials).Error
+	return credentials, err
+}
+
+// UpdateWebAuthnSignCount 登录成功后更新签名计数器，计数器回退说明凭证可能被克隆
+func UpdateWebAuthnSignCount(credential *WebAuthnCredential, signCount uint32) error {
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		return fmt.Errorf("签名计数器异常，通行密钥可能被复制")
+	}
+
+	now := time.Now()
+	result := DB.Model(&WebAuthnCredential{}).
+		Where("id = ? AND sign_count = ?", credential.ID, credential.SignCount).
+		Updates(map[string]interface{}{"sign_count": signCount, "last_used_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("通行密钥并发使用")
+	}
+	credential.SignCount = signCount
+	credential.LastUsedAt = &now
+	return nil
+}
+
+// DeleteWebAuthnCredential 删除用户的通行密钥
+func DeleteWebAuthnCredential(userID, id uint) (bool, error) {
+	result := DB.Where("id = ? AND user_id = ?", id, userID).Delete(&WebAuthnCredential{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"log"
 	"marku-server/config"
+	"marku-server/handle/admin"
 	"marku-server/handle/app"
 	"marku-server/handle/comment"
 	"marku-server/handle/count"
@@ -16,12 +17,19 @@ func InitRouter() {
 	r := gin.Default()
 	//r.Use(middleware.Logger())
 	r.Use(middleware.Cors())
+	r.Use(middleware.APIKeyAuth())
+
+	// 令牌校验公钥 (JWKS)
+	r.GET("/.well-known/jwks.json", app.JWKS)
 
 	// 公开路由
 	public := r.Group("api")
 	{
 		// 健康检查
 		public.GET("/health", app.HealthCheck)
+		public.GET("/auth/jwks", app.JWKS)
+		// 本地生成的头像
+		public.GET("/avatar/:hash", app.Identicon)
 
 		// 计数器批量查询
 		public.POST("/count/batch", count.BatchGetCounters)
@@ -30,17 +38,106 @@ func InitRouter() {
 
 		// 评论提交
 		public.POST("/comment/submit", comment.SubmitComment)
+		// 评论数批量查询
+		public.POST("/comment/count/batch", comment.BatchCommentCounts)
+		// 站点最新评论与活跃评论者
+		public.GET("/comment/recent", comment.GetRecentComments)
+		public.GET("/comment/top-commenters", comment.GetTopCommenters)
+		// 评论订阅源
+		public.GET("/comment/feed.atom", comment.CommentAtomFeed)
+		public.GET("/comment/feed.rss", comment.CommentRSSFeed)
+		// 评论搜索
+		public.GET("/comment/search", middleware.OptionalAuth(), comment.SearchComments)
 		// 评论列表
-		public.GET("/comment/list", comment.GetComments)
+		public.GET("/comment/list", middleware.OptionalAuth(), comment.GetComments)
+		// 评论审核、置顶与精选（管理员或拥有 comments:moderate 权限的 API 密钥）
+		public.PUT("/comment/:id/status", middleware.OptionalAuth(), comment.ModerateComment)
+		public.PUT("/comment/:id/pin", middleware.OptionalAuth(), comment.PinComment)
+		public.PUT("/comment/:id/featured", middleware.OptionalAuth(), comment.FeatureComment)
+		// 评论与页面的表情回应
+		public.POST("/comment/:id/reactions", middleware.OptionalAuth(), comment.ToggleCommentReaction)
+		public.GET("/reactions", middleware.OptionalAuth(), comment.GetPageReactions)
+		public.POST("/reactions", middleware.OptionalAuth(), comment.TogglePageReaction)
+		// 举报评论
+		public.POST("/comment/:id/report", middleware.OptionalAuth(), comment.ReportComment)
+		// 评论图片上传与访问
+		public.POST("/comment/upload", middleware.OptionalAuth(), comment.UploadImage)
+		public.GET("/uploads/*key", comment.ServeUpload)
+		public.HEAD("/uploads/*key", comment.ServeUpload)
+
+		// 游客个人数据导出与删除
+		public.POST("/privacy/export", userhandler.ExportGuestData)
+		public.POST("/privacy/delete", userhandler.DeleteGuestData)
+
+		// 第三方登录
+		oauth := public.Group("/oauth")
+		{
+			oauth.GET("/providers", userhandler.ListOAuthProviders)
+			oauth.GET("/:provider/authorize", userhandler.OAuthAuthorize)
+			oauth.GET("/:provider/callback", userhandler.OAuthCallback)
+		}
 
 		// 用户模块
 		user := public.Group("/user")
 		{
 			user.POST("/register", userhandler.Register)
 			user.POST("/login", userhandler.Login)
+			user.POST("/login/email", userhandler.LoginByEmailCode)
+			user.POST("/login/2fa", userhandler.LoginTwoFactor)
+			user.POST("/passkeys/login/begin", userhandler.BeginPasskeyLogin)
+			user.POST("/passkeys/login/finish", userhandler.FinishPasskeyLogin)
 			user.POST("/password/recover", userhandler.RecoverPassword)
 			user.POST("/email/code/send", userhandler.SendEmailCode)
 			user.POST("/email/code/verify", userhandler.VerifyEmailCode)
+			user.POST("/token/refresh", userhandler.RefreshToken)
+			user.GET("/mentions/unsubscribe", userhandler.UnsubscribeMentions)
+
+			// 需要登录的用户接口
+			authed := user.Group("", middleware.AuthRequired())
+			{
+				authed.POST("/logout", userhandler.Logout)
+				authed.GET("/me", userhandler.GetProfile)
+				authed.PUT("/me", userhandler.UpdateProfile)
+				authed.POST("/me/password", userhandler.ChangePassword)
+				authed.POST("/me/email/code", userhandler.SendChangeEmailCode)
+				authed.POST("/me/email", userhandler.ChangeEmail)
+				authed.GET("/me/export", userhandler.ExportMyData)
+				authed.DELETE("/me", userhandler.DeleteMyAccount)
+				authed.GET("/sessions", userhandler.ListSessions)
+				authed.DELETE("/sessions", userhandler.RevokeOtherSessions)
+				authed.DELETE("/sessions/:id", userhandler.RevokeSession)
+				authed.GET("/identities", userhandler.ListIdentities)
+				authed.POST("/identities/:provider/link", userhandler.OAuthLink)
+				authed.GET("/2fa", userhandler.GetTwoFactorStatus)
+				authed.POST("/2fa/setup", userhandler.SetupTwoFactor)
+				authed.POST("/2fa/confirm", userhandler.ConfirmTwoFactor)
+				authed.POST("/2fa/disable", userhandler.DisableTwoFactor)
+				authed.POST("/2fa/recovery-codes", userhandler.RegenerateRecoveryCodes)
+				authed.GET("/passkeys", userhandler.ListPasskeys)
+				authed.POST("/passkeys/register/begin", userhandler.BeginPasskeyRegistration)
+				authed.POST("/passkeys/register/finish", userhandler.FinishPasskeyRegistration)
+				authed.DELETE("/passkeys/:id", userhandler.DeletePasskey)
+			}
+		}
+
+		// 管理接口
+		adminGroup := public.Group("/admin", middleware.AuthRequired(), middleware.AdminRequired())
+		{
+			adminGroup.GET("/lockouts", admin.ListLockouts)
+			adminGroup.DELETE("/lockouts/:id", admin.UnlockLockout)
+			adminGroup.GET("/sites", admin.ListSites)
+			adminGroup.POST("/sites", admin.CreateSite)
+			adminGroup.PUT("/sites/:id", admin.UpdateSite)
+			adminGroup.DELETE("/sites/:id", admin.DeleteSite)
+			adminGroup.GET("/api-keys", admin.ListAPIKeys)
+			adminGroup.POST("/api-keys", admin.CreateAPIKey)
+			adminGroup.DELETE("/api-keys/:id", admin.RevokeAPIKey)
+			adminGroup.GET("/api-keys/:id/audits", admin.ListAPIKeyAudits)
+			adminGroup.GET("/threads", admin.ListThreadSettings)
+			adminGroup.PUT("/threads", admin.SaveThreadSetting)
+			adminGroup.DELETE("/threads/:id", admin.DeleteThreadSetting)
+			adminGroup.GET("/reports", admin.ListReports)
+			adminGroup.PUT("/reports/:id", admin.ResolveReport)
 		}
 	}
 
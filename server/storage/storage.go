@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"marku-server/config"
+	"time"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// Object 读取到的对象，调用方负责关闭 Body
+type Object struct {
+	Body         io.ReadCloser
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Storage 上传文件的存储后端，key 为以 / 分隔的相对路径
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Default 当前使用的存储后端，由 Init 按配置创建
+var Default Storage
+
+// Init 按上传配置初始化存储后端
+func Init(cfg config.StorageConfig) error {
+	var err error
+	switch cfg.Type {
+	case "local":
+		Default, err = NewLocalStorage(cfg.Local.Path)
+	case "s3":
+		Default, err = NewS3Storage(cfg.S3)
+	default:
+		return fmt.Errorf("未知的存储类型: %s", cfg.Type)
+	}
+	return err
+}
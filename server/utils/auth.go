@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
@@ -12,53 +13,221 @@ import (
 	"time"
 )
 
-const authTokenTTL = 30 * 24 * time.Hour
+const (
+	AccessTokenTTL        = 30 * time.Minute
+	TwoFactorChallengeTTL = 5 * time.Minute
+	UnsubscribeTokenTTL   = 365 * 24 * time.Hour
 
-// GenerateAuthToken 生成登录令牌
-func GenerateAuthToken(userID uint) (string, error) {
-	expiresAt := time.Now().Add(authTokenTTL).Unix()
-	payload := fmt.Sprintf("%d:%d", userID, expiresAt)
-	signature := signAuthPayload(payload)
-	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload + ":" + signature))
-	return encoded, nil
+	TokenUseAccess      = "access"
+	TokenUseChallenge   = "2fa_challenge"
+	TokenUseUnsubscribe = "unsubscribe"
+)
+
+// legacyAuthTokenTTL 升级前旧令牌的有效期，用于由过期时间反推签发时间
+const legacyAuthTokenTTL = 30 * 24 * time.Hour
+
+// AuthClaims 访问令牌中携带的登录信息
+type AuthClaims struct {
+	JWTRegisteredClaims
+	SessionID uint   `json:"sid"`
+	Role      int    `json:"role"`
+	SiteID    string `json:"site,omitempty"`
+	TokenUse  string `json:"token_use"`
+	UserID    uint   `json:"-"`
+}
+
+// GenerateAuthToken 生成绑定会话的短期访问令牌（JWT）
+func GenerateAuthToken(userID, sessionID uint, role int, siteID string) (string, time.Time, error) {
+	registered, err := NewRegisteredClaims(strconv.FormatUint(uint64(userID), 10), AccessTokenTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, err := SignJWT(AuthClaims{
+		JWTRegisteredClaims: registered,
+		SessionID:           sessionID,
+		Role:                role,
+		SiteID:              siteID,
+		TokenUse:            TokenUseAccess,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Unix(registered.ExpiresAt, 0), nil
+}
+
+// ParseAuthToken 解析并校验登录令牌，迁移期内兼容旧格式令牌
+func ParseAuthToken(token string) (*AuthClaims, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, fmt.Errorf("令牌不能为空")
+	}
+
+	if strings.Count(token, ".") != 2 {
+		return parseLegacyAuthToken(token)
+	}
+
+	var claims AuthClaims
+	if err := VerifyJWT(token, &claims); err != nil {
+		return nil, err
+	}
+	if err := ValidateRegisteredClaims(claims.JWTRegisteredClaims); err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != TokenUseAccess {
+		return nil, fmt.Errorf("令牌用途无效")
+	}
+
+	userIDValue, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userIDValue == 0 {
+		return nil, fmt.Errorf("令牌用户信息无效")
+	}
+	claims.UserID = uint(userIDValue)
+	return &claims, nil
+}
+
+// GenerateChallengeToken 生成两步验证的短期挑战令牌，仅能用于完成登录第二步
+func GenerateChallengeToken(userID uint) (string, time.Time, error) {
+	registered, err := NewRegisteredClaims(strconv.FormatUint(uint64(userID), 10), TwoFactorChallengeTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, err := SignJWT(AuthClaims{
+		JWTRegisteredClaims: registered,
+		TokenUse:            TokenUseChallenge,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, time.Unix(registered.ExpiresAt, 0), nil
 }
 
-// ParseAuthToken 解析并校验登录令牌
-func ParseAuthToken(token string) (uint, error) {
-	if strings.TrimSpace(token) == "" {
-		return 0, fmt.Errorf("令牌不能为空")
+// ParseChallengeToken 校验两步验证挑战令牌并返回用户ID
+func ParseChallengeToken(token string) (uint, error) {
+	return parsePurposeToken(token, TokenUseChallenge)
+}
+
+// GenerateUnsubscribeToken 生成邮件退订链接使用的长期令牌，仅能用于退订通知
+func GenerateUnsubscribeToken(userID uint) (string, error) {
+	registered, err := NewRegisteredClaims(strconv.FormatUint(uint64(userID), 10), UnsubscribeTokenTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return SignJWT(AuthClaims{
+		JWTRegisteredClaims: registered,
+		TokenUse:            TokenUseUnsubscribe,
+	})
+}
+
+// ParseUnsubscribeToken 校验退订令牌并返回用户ID
+func ParseUnsubscribeToken(token string) (uint, error) {
+	return parsePurposeToken(token, TokenUseUnsubscribe)
+}
+
+// parsePurposeToken 校验仅携带用户ID的专用令牌
+func parsePurposeToken(token, use string) (uint, error) {
+	var claims AuthClaims
+	if err := VerifyJWT(strings.TrimSpace(token), &claims); err != nil {
+		return 0, err
+	}
+	if err := ValidateRegisteredClaims(claims.JWTRegisteredClaims); err != nil {
+		return 0, err
+	}
+	if claims.TokenUse != use {
+		return 0, fmt.Errorf("令牌用途无效")
+	}
+
+	userIDValue, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userIDValue == 0 {
+		return 0, fmt.Errorf("令牌用户信息无效")
+	}
+	return uint(userIDValue), nil
+}
+
+// parseLegacyAuthToken 解析升级前签发的旧版令牌 userID:expiry:hexsig，旧令牌不绑定会话
+func parseLegacyAuthToken(token string) (*AuthClaims, error) {
+	if !legacyTokensAccepted() {
+		return nil, fmt.Errorf("令牌格式已停用，请重新登录")
 	}
 
 	decoded, err := base64.RawURLEncoding.DecodeString(token)
 	if err != nil {
-		return 0, fmt.Errorf("令牌无效")
+		return nil, fmt.Errorf("令牌无效")
 	}
 
 	parts := strings.Split(string(decoded), ":")
 	if len(parts) != 3 {
-		return 0, fmt.Errorf("令牌格式错误")
+		return nil, fmt.Errorf("令牌格式错误")
 	}
 
 	userIDValue, err := strconv.ParseUint(parts[0], 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("令牌用户信息无效")
+	if err != nil || userIDValue == 0 {
+		return nil, fmt.Errorf("令牌用户信息无效")
 	}
 
 	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("令牌过期信息无效")
+		return nil, fmt.Errorf("令牌过期信息无效")
 	}
 
 	if time.Now().Unix() > expiresAt {
-		return 0, fmt.Errorf("令牌已过期")
+		return nil, fmt.Errorf("令牌已过期")
 	}
 
 	payload := parts[0] + ":" + parts[1]
-	if !hmac.Equal([]byte(parts[2]), []byte(signAuthPayload(payload))) {
-		return 0, fmt.Errorf("令牌签名无效")
+	if !hmac.Equal([]byte(parts[2]), []byte(signLegacyAuthPayload(payload))) {
+		return nil, fmt.Errorf("令牌签名无效")
 	}
 
-	return uint(userIDValue), nil
+	return &AuthClaims{
+		JWTRegisteredClaims: JWTRegisteredClaims{
+			ExpiresAt: expiresAt,
+			IssuedAt:  expiresAt - int64(legacyAuthTokenTTL/time.Second),
+		},
+		TokenUse: TokenUseAccess,
+		UserID:   uint(userIDValue),
+	}, nil
+}
+
+// legacyTokensAccepted 判断是否仍处于旧格式令牌的迁移窗口内
+func legacyTokensAccepted() bool {
+	if strings.TrimSpace(config.AppKey) == "" {
+		return false
+	}
+	jwtConfig := config.GetJWTConfig()
+	if jwtConfig == nil {
+		return false
+	}
+	value := strings.TrimSpace(jwtConfig.LegacyTokenUntil)
+	if value == "" {
+		return false
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		until, err = time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return false
+		}
+		until = until.Add(24 * time.Hour)
+	}
+	return time.Now().Before(until)
+}
+
+// GenerateRandomToken 生成指定字节长度的随机令牌（base64url 编码）
+func GenerateRandomToken(byteLength int) (string, error) {
+	buf := make([]byte, byteLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken 计算令牌的 SHA-256 摘要，用于数据库存储
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // ExtractBearerToken 从 Authorization 头中提取 Bearer token
@@ -75,12 +244,8 @@ func ExtractBearerToken(authorizationHeader string) string {
 	return trimmed
 }
 
-func signAuthPayload(payload string) string {
-	secret := config.AppKey
-	if secret == "" {
-		secret = "marku"
-	}
-	mac := hmac.New(sha256.New, []byte(secret))
+func signLegacyAuthPayload(payload string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppKey))
 	_, _ = mac.Write([]byte(payload))
 	return hex.EncodeToString(mac.Sum(nil))
-}
\ No newline at end of file
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"testing"
+	"time"
+)
+
+func legacyToken(payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(payload + ":" + signLegacyAuthPayload(payload)))
+}
+
+func TestParseLegacyAuthToken(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "legacy-app-key"
+	cfg.Auth.JWT.LegacyTokenUntil = time.Now().Add(time.Hour).Format(time.RFC3339)
+	testutil.UseConfig(t, cfg)
+
+	future := time.Now().Add(24 * time.Hour).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name        string
+		token       string
+		wantErr     bool
+		wantUser    uint
+		wantSession uint
+	}{
+		{"baseline sessionless", legacyToken(fmt.Sprintf("7:%d", future)), false, 7, 0},
+		{"session bound (never released)", legacyToken(fmt.Sprintf("7:3:%d", future)), true, 0, 0},
+		{"expired", legacyToken(fmt.Sprintf("7:%d", past)), true, 0, 0},
+		{"zero user", legacyToken(fmt.Sprintf("0:%d", future)), true, 0, 0},
+		{"bad signature", base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("7:%d:deadbeef", future))), true, 0, 0},
+		{"too many parts", legacyToken(fmt.Sprintf("7:3:1:%d", future)), true, 0, 0},
+		{"not base64", "!!!", true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseAuthToken(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got claims %+v", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claims.UserID != tt.wantUser || claims.SessionID != tt.wantSession {
+				t.Fatalf("got user %d session %d, want %d %d", claims.UserID, claims.SessionID, tt.wantUser, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestLegacyAuthTokenWindow(t *testing.T) {
+	payload := fmt.Sprintf("7:%d", time.Now().Add(24*time.Hour).Unix())
+
+	tests := []struct {
+		name    string
+		appKey  string
+		until   string
+		wantErr bool
+	}{
+		{"window open", "legacy-app-key", time.Now().Add(time.Hour).Format(time.RFC3339), false},
+		{"window date only", "legacy-app-key", time.Now().AddDate(0, 0, 1).Format("2006-01-02"), false},
+		{"window closed", "legacy-app-key", time.Now().Add(-time.Hour).Format(time.RFC3339), true},
+		{"window unset", "legacy-app-key", "", true},
+		{"empty app key", "", time.Now().Add(time.Hour).Format(time.RFC3339), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Site.AppKey = "legacy-app-key"
+			cfg.Auth.JWT.LegacyTokenUntil = tt.until
+			testutil.UseConfig(t, cfg)
+			token := legacyToken(payload)
+			// 令牌按原 app_key 签发后再切换，模拟未配置 app_key 的部署
+			config.AppKey = tt.appKey
+
+			_, err := ParseAuthToken(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
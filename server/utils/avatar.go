@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"marku-server/config"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 内置头像镜像地址模板
+var avatarMirrors = map[string]string{
+	"gravatar": "https://gravatar.com/avatar/{hash}?s={size}&d={default}",
+	"cravatar": "https://cravatar.cn/avatar/{hash}?s={size}&d={default}",
+	"weavatar": "https://weavatar.com/avatar/{hash}?s={size}&d={default}",
+}
+
+const (
+	identiconGrid    = 5
+	identiconMinSize = 16
+	identiconMaxSize = 512
+)
+
+// EmailHash 计算邮箱摘要（去除首尾空白并转为小写），算法由头像配置决定
+func EmailHash(email string) string {
+	normalized := []byte(strings.ToLower(strings.TrimSpace(email)))
+	if config.GetAvatarConfig().Hash == "sha256" {
+		sum := sha256.Sum256(normalized)
+		return hex.EncodeToString(sum[:])
+	}
+	sum := md5.Sum(normalized)
+	return hex.EncodeToString(sum[:])
+}
+
+// ResolveAvatarURL 根据邮箱计算头像地址；没有邮箱时以 seed 生成本地头像。
+// baseURL 为服务对外访问地址，头像配置中设置了 public_url 时优先使用配置值
+func ResolveAvatarURL(email, seed, baseURL string) string {
+	avatar := config.GetAvatarConfig()
+	if avatar.PublicURL != "" {
+		baseURL = avatar.PublicURL
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		sum := sha256.Sum256([]byte(seed))
+		return IdenticonURL(baseURL, hex.EncodeToString(sum[:]))
+	}
+
+	hash := EmailHash(email)
+	identicon := IdenticonURL(baseURL, hash)
+
+	template := avatarMirrors[strings.ToLower(avatar.Mirror)]
+	if template == "" {
+		template = avatar.Mirror
+	}
+	if template == "" || template == "local" {
+		return identicon
+	}
+
+	fallback := avatar.Default
+	if fallback == "local" {
+		fallback = identicon
+	}
+	return strings.NewReplacer(
+		"{hash}", hash,
+		"{size}", strconv.Itoa(avatar.Size),
+		"{default}", url.QueryEscape(fallback),
+	).Replace(template)
+}
+
+// IdenticonURL 本地生成头像的访问地址
+func IdenticonURL(baseURL, hash string) string {
+	return strings.TrimRight(baseURL, "/") + "/api/avatar/" + hash + ".svg"
+}
+
+// GenerateIdenticon 根据摘要生成左右对称的 5x5 像素风格 SVG 头像，相同摘要总是得到相同图案
+func GenerateIdenticon(hash string, size int) []byte {
+	if size < identiconMinSize {
+		size = identiconMinSize
+	}
+	if size > identiconMaxSize {
+		size = identiconMaxSize
+	}
+
+	seed, err := hex.DecodeString(hash)
+	if err != nil || len(seed) < 16 {
+		sum := sha256.Sum256([]byte(hash))
+		seed = sum[:]
+	}
+
+	hue := (int(seed[0])<<8 | int(seed[1])) % 360
+	saturation := 45 + int(seed[2])%20
+	lightness := 45 + int(seed[3])%15
+	color := fmt.Sprintf("hsl(%d,%d%%,%d%%)", hue, saturation, lightness)
+
+	cell := size / (identiconGrid + 1)
+	margin := (size - cell*identiconGrid) / 2
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, size, size, size, size)
+	fmt.Fprintf(&builder, `<rect width="%d" height="%d" fill="#f0f0f0"/>`, size, size)
+
+	// 只决定左侧三列，右侧两列镜像
+	columns := (identiconGrid + 1) / 2
+	for column := 0; column < columns; column++ {
+		for row := 0; row < identiconGrid; row++ {
+			index := column*identiconGrid + row
+			if seed[4+index/8]>>(uint(index)%8)&1 == 0 {
+				continue
+			}
+			y := margin + row*cell
+			fmt.Fprintf(&builder, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, margin+column*cell, y, cell, cell, color)
+			if mirrored := identiconGrid - 1 - column; mirrored != column {
+				fmt.Fprintf(&builder, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, margin+mirrored*cell, y, cell, cell, color)
+			}
+		}
+	}
+	builder.WriteString(`</svg>`)
+	return []byte(builder.String())
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLCache 带过期时间与条目上限的进程内缓存，适合短时间内重复的只读查询，多实例之间不共享
+type TTLCache[V any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	items      map[string]ttlCacheEntry[V]
+}
+
+type ttlCacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// NewTTLCache 创建缓存，ttl 为每个条目的有效期，maxEntries 为最多保留的条目数
+func NewTTLCache[V any](ttl time.Duration, maxEntries int) *TTLCache[V] {
+	if maxEntries <= 0 {
+		maxEntries = 1
+	}
+	return &TTLCache[V]{ttl: ttl, maxEntries: maxEntries, items: make(map[string]ttlCacheEntry[V])}
+}
+
+// Get 读取未过期的缓存值
+func (c *TTLCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.items[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set 写入缓存值；达到条目上限时先清理过期条目，仍然已满则淘汰最早过期的条目
+func (c *TTLCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, exists := c.items[key]; !exists && len(c.items) >= c.maxEntries {
+		for k, entry := range c.items {
+			if now.After(entry.expiresAt) {
+				delete(c.items, k)
+			}
+		}
+		for len(c.items) >= c.maxEntries {
+			oldestKey := ""
+			var oldest time.Time
+			for k, entry := range c.items {
+				if oldestKey == "" || entry.expiresAt.Before(oldest) {
+					oldestKey, oldest = k, entry.expiresAt
+				}
+			}
+			delete(c.items, oldestKey)
+		}
+	}
+	c.items[key] = ttlCacheEntry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Len 当前缓存的条目数，包含尚未清理的过期条目
+func (c *TTLCache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
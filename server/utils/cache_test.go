@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTTLCacheExpires(t *testing.T) {
+	cache := NewTTLCache[int](20*time.Millisecond, 4)
+	cache.Set("a", 1)
+	if value, ok := cache.Get("a"); !ok || value != 1 {
+		t.Fatalf("Get(a) = %d, %v", value, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+}
+
+func TestTTLCacheMaxEntries(t *testing.T) {
+	cache := NewTTLCache[int](time.Minute, 4)
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), i)
+		time.Sleep(time.Microsecond)
+	}
+	if got := cache.Len(); got != 4 {
+		t.Fatalf("Len() = %d, want 4", got)
+	}
+	if _, ok := cache.Get("key-0"); ok {
+		t.Fatal("oldest entry should have been evicted")
+	}
+	if value, ok := cache.Get("key-99"); !ok || value != 99 {
+		t.Fatalf("Get(key-99) = %d, %v", value, ok)
+	}
+
+	// 覆盖已有条目不触发淘汰
+	cache.Set("key-99", -1)
+	if got := cache.Len(); got != 4 {
+		t.Fatalf("Len() after overwrite = %d, want 4", got)
+	}
+}
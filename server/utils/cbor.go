@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const cborMaxDepth = 16
+
+// cborDecoder 极简 CBOR (RFC 8949) 解码器，仅覆盖 WebAuthn 所需的数据类型
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+// DecodeCBOR 解码一个 CBOR 数据项，返回解码结果与消耗的字节数。
+// 整数解码为 int64，字节串为 []byte，文本为 string，数组为 []interface{}，
+// 映射为 map[interface{}]interface{}。
+func DecodeCBOR(data []byte) (interface{}, int, error) {
+	decoder := &cborDecoder{data: data}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, decoder.offset, nil
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("CBOR 嵌套过深")
+	}
+	if d.offset >= len(d.data) {
+		return nil, fmt.Errorf("CBOR 数据不完整")
+	}
+
+	initial := d.data[d.offset]
+	d.offset++
+	major := initial >> 5
+	info := initial & 0x1f
+
+	if major == 7 {
+		return d.decodeSimple(info)
+	}
+
+	argument, err := d.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if argument > math.MaxInt64 {
+			return nil, fmt.Errorf("CBOR 整数溢出")
+		}
+		return int64(argument), nil
+	case 1:
+		if argument > math.MaxInt64 {
+			return nil, fmt.Errorf("CBOR 整数溢出")
+		}
+		return -1 - int64(argument), nil
+	case 2:
+		raw, err := d.readBytes(argument)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+	case 3:
+		raw, err := d.readBytes(argument)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+	case 4:
+		if argument > uint64(len(d.data)) {
+			return nil, fmt.Errorf("CBOR 数组长度无效")
+		}
+		items := make([]interface{}, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if argument > uint64(len(d.data)) {
+			return nil, fmt.Errorf("CBOR 映射长度无效")
+		}
+		result := make(map[interface{}]interface{}, argument)
+		for i := uint64(0); i < argument; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("CBOR 映射键类型不支持")
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+		}
+		return result, nil
+	case 6:
+		// 标签不影响 WebAuthn 数据的含义，直接返回被标记的值
+		return d.decode(depth + 1)
+	default:
+		return nil, fmt.Errorf("CBOR 类型不支持")
+	}
+}
+
+func (d *cborDecoder) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		raw, err := d.readBytes(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(raw[0]), nil
+	case info == 25:
+		raw, err := d.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(raw)), nil
+	case info == 26:
+		raw, err := d.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(raw)), nil
+	case info == 27:
+		raw, err := d.readBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(raw), nil
+	default:
+		// 不定长编码在 WebAuthn 的规范编码中不会出现
+		return 0, fmt.Errorf("CBOR 不定长编码不支持")
+	}
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		raw, err := d.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return float64(decodeHalfFloat(binary.BigEndian.Uint16(raw))), nil
+	case 26:
+		raw, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+	case 27:
+		raw, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+	default:
+		return nil, fmt.Errorf("CBOR 简单值不支持")
+	}
+}
+
+func (d *cborDecoder) readBytes(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)-d.offset) {
+		return nil, fmt.Errorf("CBOR 数据不完整")
+	}
+	raw := d.data[d.offset : d.offset+int(length)]
+	d.offset += int(length)
+	return raw, nil
+}
+
+func decodeHalfFloat(bits uint16) float32 {
+	sign := uint32(bits&0x8000) << 16
+	exponent := (bits >> 10) & 0x1f
+	mantissa := uint32(bits & 0x03ff)
+
+	switch exponent {
+	case 0:
+		value := float32(mantissa) / 1024 / 16384
+		if sign != 0 {
+			return -value
+		}
+		return value
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	default:
+		return math.Float32frombits(sign | uint32(exponent+112)<<23 | mantissa<<13)
+	}
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, value string) []byte {
+	t.Helper()
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// RFC 8949 附录 A 中的示例
+func TestDecodeCBORVectors(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"20", int64(-1)},
+		{"3863", int64(-100)},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f93c00", float64(1)},
+		{"fb3ff199999999999a", 1.1},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"c11a514b67b0", int64(1363896240)},
+	}
+	for _, tt := range tests {
+		data := mustHex(t, tt.hex)
+		got, consumed, err := DecodeCBOR(data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.hex, err)
+		}
+		if consumed != len(data) {
+			t.Fatalf("%s: consumed %d of %d bytes", tt.hex, consumed, len(data))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: got %#v, want %#v", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCBORReportsConsumedBytes(t *testing.T) {
+	// 认证器数据中公钥之后可能紧跟扩展数据，解码器只消耗第一个数据项
+	got, consumed, err := DecodeCBOR(mustHex(t, "0102"))
+	if err != nil || got != int64(1) || consumed != 1 {
+		t.Fatalf("got %v, consumed %d, err %v", got, consumed, err)
+	}
+}
+
+func TestDecodeCBORRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"empty", ""},
+		{"truncated uint16", "19 03"},
+		{"truncated uint64", "1b 0000 00e8"},
+		{"truncated bytes", "44 0102"},
+		{"truncated text", "64 4945"},
+		{"truncated array", "83 0102"},
+		{"truncated map value", "a2 0102 03"},
+		{"oversized byte string", "5a ffffffff 00"},
+		{"oversized array", "9b 7fffffffffffffff"},
+		{"oversized map", "ba ffffffff"},
+		{"integer overflow", "1b ffffffffffffffff"},
+		{"negative overflow", "3b ffffffffffffffff"},
+		{"indefinite array", "9f 01 ff"},
+		{"indefinite bytes", "5f 4101 ff"},
+		{"reserved info", "1c"},
+		{"unsupported simple", "f0"},
+		{"array map key", "a1 8101 01"},
+		{"truncated float", "fb 3ff1"},
+		{"too deep", strings.Repeat("81", cborMaxDepth+2) + "01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := mustHex(t, strings.ReplaceAll(tt.hex, " ", ""))
+			if value, _, err := DecodeCBOR(data); err == nil {
+				t.Fatalf("expected error, got %#v", value)
+			}
+		})
+	}
+}
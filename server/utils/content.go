@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MaxImagesPerComment 单条评论最多关联的图片数量
+const MaxImagesPerComment = 10
+
+var (
+	// Markdown 图片语法 ![alt](url)
+	markdownImagePattern = regexp.MustCompile(`!\[([^\]\n]{0,200})\]\(([^)\s]{1,1000})\)`)
+	// 上传图片的存储键：年/月/随机串.扩展名
+	uploadKeyPattern = regexp.MustCompile(`(?:^|/)(\d{4}/\d{2}/[A-Za-z0-9_-]{16,64}\.(?:jpg|png|gif))$`)
+)
+
+// EmbeddedImage 评论中引用的已上传图片
+type EmbeddedImage struct {
+	URL          string
+	ThumbnailURL string
+}
+
+// UploadKeyFromURL 从图片地址中提取上传存储键，不是上传图片时返回空字符串
+func UploadKeyFromURL(value string) string {
+	if index := strings.IndexAny(value, "?#"); index >= 0 {
+		value = value[:index]
+	}
+	if match := uploadKeyPattern.FindStringSubmatch(value); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// ParseImageKeys 提取评论 Markdown 中引用的上传图片存储键，按首次出现顺序去重
+func ParseImageKeys(content string) []string {
+	keys := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, match := range markdownImagePattern.FindAllStringSubmatch(content, -1) {
+		key := UploadKeyFromURL(match[2])
+		if key == "" {
+			continue
+		}
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+		if len(keys) >= MaxImagesPerComment {
+			break
+		}
+	}
+	return keys
+}
+
+// RenderCommentHTML 转义评论内容，并将已解析的提及与已上传图片渲染为标签。
+// mentions 为用户名到被链接评论ID的映射，0 表示渲染为 span；images 按存储键索引，其余 Markdown 图片保持原文
+func RenderCommentHTML(content string, mentions map[string]uint, images map[string]EmbeddedImage) string {
+	type replacement struct {
+		start, end int
+		html       string
+	}
+	replacements := make([]replacement, 0)
+
+	if len(mentions) > 0 {
+		for _, match := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+			// match[4]:match[5] 为用户名，@ 位于其前一个字节
+			name := content[match[4]:match[5]]
+			target, ok := mentions[name]
+			if !ok {
+				continue
+			}
+			escaped := html.EscapeString(name)
+			tag := fmt.Sprintf(`<span class="marku-mention" data-marku-mention="%s">@%s</span>`, escaped, escaped)
+			if target > 0 {
+				tag = fmt.Sprintf(`<a class="marku-mention" href="#marku-comment-%d" data-marku-mention="%s">@%s</a>`, target, escaped, escaped)
+			}
+			replacements = append(replacements, replacement{match[4] - 1, match[5], tag})
+		}
+	}
+
+	if len(images) > 0 {
+		for _, match := range markdownImagePattern.FindAllStringSubmatchIndex(content, -1) {
+			image, ok := images[UploadKeyFromURL(content[match[4]:match[5]])]
+			if !ok {
+				continue
+			}
+			// 地址由服务端按存储键重新生成，不使用评论中的原始地址
+			tag := fmt.Sprintf(`<a class="marku-attachment" href="%s" target="_blank" rel="noopener"><img src="%s" alt="%s" loading="lazy"></a>`,
+				html.EscapeString(image.URL), html.EscapeString(image.ThumbnailURL), html.EscapeString(content[match[2]:match[3]]))
+			replacements = append(replacements, replacement{match[0], match[1], tag})
+		}
+	}
+
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].start < replacements[j].start
+	})
+
+	var builder strings.Builder
+	last := 0
+	for _, item := range replacements {
+		// 提及出现在图片替代文本或地址中时以图片为准
+		if item.start < last {
+			continue
+		}
+		builder.WriteString(html.EscapeString(content[last:item.start]))
+		builder.WriteString(item.html)
+		last = item.end
+	}
+	builder.WriteString(html.EscapeString(content[last:]))
+	return builder.String()
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// HighlightSnippet 截取 text 中第一处匹配前后各 context 个字符的片段，HTML 转义后用 <mark> 包裹所有匹配，忽略大小写。
+// 没有匹配时返回开头的片段
+func HighlightSnippet(text, keyword string, context int) string {
+	source := []rune(text)
+	pattern := []rune(strings.TrimSpace(keyword))
+
+	var matches []int
+	if len(pattern) > 0 {
+		lowered := lowerRunes(source)
+		loweredPattern := lowerRunes(pattern)
+		for i := 0; i+len(loweredPattern) <= len(lowered); {
+			if runesEqual(lowered[i:i+len(loweredPattern)], loweredPattern) {
+				matches = append(matches, i)
+				i += len(loweredPattern)
+				continue
+			}
+			i++
+		}
+	}
+
+	start, end := 0, len(source)
+	if len(matches) > 0 {
+		start = matches[0] - context
+		end = matches[0] + len(pattern) + context
+	} else {
+		end = 2 * context
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(source) {
+		end = len(source)
+	}
+
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("…")
+	}
+	position := start
+	for _, match := range matches {
+		if match < start {
+			continue
+		}
+		if match+len(pattern) > end {
+			break
+		}
+		builder.WriteString(html.EscapeString(string(source[position:match])))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(string(source[match : match+len(pattern)])))
+		builder.WriteString("</mark>")
+		position = match + len(pattern)
+	}
+	builder.WriteString(html.EscapeString(string(source[position:end])))
+	if end < len(source) {
+		builder.WriteString("…")
+	}
+	return builder.String()
+}
+
+func lowerRunes(runes []rune) []rune {
+	lowered := make([]rune, len(runes))
+	for i, r := range runes {
+		lowered[i] = unicode.ToLower(r)
+	}
+	return lowered
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
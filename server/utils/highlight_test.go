@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		keyword string
+		context int
+		want    string
+	}{
+		{"case insensitive, every match", "Go go GO", "go", 10, "<mark>Go</mark> <mark>go</mark> <mark>GO</mark>"},
+		{"html escaped around and inside match", `<b>x</b> & <i>`, "<i>", 20, "&lt;b&gt;x&lt;/b&gt; &amp; <mark>&lt;i&gt;</mark>"},
+		{"keyword with quotes", `say "hi" now`, `"hi"`, 20, "say <mark>&#34;hi&#34;</mark> now"},
+		{"context with ellipses", "aaaaaaaaaa needle bbbbbbbbbb", "needle", 3, "…aa <mark>needle</mark> bb…"},
+		{"multibyte runes", "评论里有关键词和更多内容", "关键词", 2, "…里有<mark>关键词</mark>和更…"},
+		{"match past the window is not marked", "x match yyyyyyyyyy match", "match", 2, "x <mark>match</mark> y…"},
+		{"no match returns the head", "<p>hello world</p>", "zzz", 4, "&lt;p&gt;hello…"},
+		{"empty keyword", "short", " ", 10, "short"},
+	}
+	for _, tt := range tests {
+		if got := HighlightSnippet(tt.text, tt.keyword, tt.context); got != tt.want {
+			t.Errorf("%s: HighlightSnippet = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
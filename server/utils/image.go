@@ -0,0 +1,335 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// ErrUnsupportedImage 图片类型不在允许列表内或无法解码
+var ErrUnsupportedImage = errors.New("不支持的图片类型")
+
+// ImageOptions 图片处理参数
+type ImageOptions struct {
+	AllowedTypes  []string
+	MaxPixels     int
+	ThumbnailSize int
+	JPEGQuality   int
+}
+
+// ProcessedImage 重新编码后的图片与缩略图
+type ProcessedImage struct {
+	ContentType          string
+	Ext                  string
+	Data                 []byte
+	Width                int
+	Height               int
+	Thumbnail            []byte
+	ThumbnailContentType string
+	ThumbnailExt         string
+}
+
+// ProcessImage 按文件内容嗅探图片类型，校验尺寸后重新编码：丢弃 EXIF 等元数据（JPEG 先按方向标记旋转），并生成缩略图
+func ProcessImage(data []byte, opts ImageOptions) (*ProcessedImage, error) {
+	contentType := http.DetectContentType(data)
+	allowed := false
+	for _, value := range opts.AllowedTypes {
+		if value == contentType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, ErrUnsupportedImage
+	}
+
+	// 先只读取头部尺寸，避免解码超大图片耗尽内存
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > opts.MaxPixels {
+		return nil, fmt.Errorf("图片尺寸过大，像素总数不能超过 %d", opts.MaxPixels)
+	}
+
+	result := &ProcessedImage{}
+	var frame image.Image
+	var encoded bytes.Buffer
+	switch format {
+	case "jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedImage
+		}
+		frame = applyJPEGOrientation(img, jpegOrientation(data))
+		if err := jpeg.Encode(&encoded, frame, &jpeg.Options{Quality: opts.JPEGQuality}); err != nil {
+			return nil, err
+		}
+		result.ContentType, result.Ext = "image/jpeg", ".jpg"
+	case "png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedImage
+		}
+		frame = img
+		if err := png.Encode(&encoded, frame); err != nil {
+			return nil, err
+		}
+		result.ContentType, result.Ext = "image/png", ".png"
+	case "gif":
+		// 解码前先扫描块结构统计帧数，每帧解码都会分配一张画布大小的图像
+		maxFrames := opts.MaxPixels * 4 / (cfg.Width * cfg.Height)
+		frames, err := countGIFFrames(data, maxFrames)
+		if err != nil {
+			return nil, ErrUnsupportedImage
+		}
+		if frames == 0 {
+			return nil, ErrUnsupportedImage
+		}
+		if frames > maxFrames {
+			return nil, errors.New("动图帧数过多")
+		}
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(animation.Image) == 0 {
+			return nil, ErrUnsupportedImage
+		}
+		frame = animation.Image[0]
+		// 重新编码只保留帧、延时与循环次数，注释与应用扩展块被丢弃
+		if err := gif.EncodeAll(&encoded, animation); err != nil {
+			return nil, err
+		}
+		result.ContentType, result.Ext = "image/gif", ".gif"
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	bounds := frame.Bounds()
+	result.Data = encoded.Bytes()
+	result.Width, result.Height = bounds.Dx(), bounds.Dy()
+
+	var thumbnail bytes.Buffer
+	scaled := resizeToFit(frame, opts.ThumbnailSize)
+	if format == "jpeg" {
+		err = jpeg.Encode(&thumbnail, scaled, &jpeg.Options{Quality: opts.JPEGQuality})
+		result.ThumbnailContentType, result.ThumbnailExt = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&thumbnail, scaled)
+		result.ThumbnailContentType, result.ThumbnailExt = "image/png", ".png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Thumbnail = thumbnail.Bytes()
+	return result, nil
+}
+
+// countGIFFrames 只遍历 GIF 的块结构统计图像描述符数量，不解压像素数据；
+// 数量超过 limit 时立即返回 limit+1
+func countGIFFrames(data []byte, limit int) (int, error) {
+	errMalformed := errors.New("GIF 结构无效")
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return 0, errMalformed
+	}
+	pos := 13
+	// 逻辑屏幕描述符的最高位表示存在全局颜色表
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1)
+	}
+
+	// skipSubBlocks 跳过以长度为 0 的子块结尾的数据子块序列
+	skipSubBlocks := func() error {
+		for {
+			if pos >= len(data) {
+				return errMalformed
+			}
+			size := int(data[pos])
+			pos++
+			if size == 0 {
+				return nil
+			}
+			pos += size
+		}
+	}
+
+	frames := 0
+	for {
+		if pos >= len(data) {
+			return 0, errMalformed
+		}
+		switch data[pos] {
+		case 0x21: // 扩展块：标签后跟数据子块
+			pos += 2
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x2C: // 图像描述符：10 字节描述符、可选局部颜色表、LZW 最小码长与图像数据子块
+			if pos+10 > len(data) {
+				return 0, errMalformed
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1)
+			}
+			pos++
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+			frames++
+			if frames > limit {
+				return frames, nil
+			}
+		case 0x3B: // 结束标记
+			return frames, nil
+		default:
+			return 0, errMalformed
+		}
+	}
+}
+
+// resizeToFit 按区域平均缩小图片，使最长边不超过 size，较小的图片保持原尺寸
+func resizeToFit(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(1, srcH*size/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*size/srcH), size
+		}
+	}
+	if dstW == srcW && dstH == srcH {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for dy := 0; dy < dstH; dy++ {
+		y0, y1 := dy*srcH/dstH, max((dy+1)*srcH/dstH, dy*srcH/dstH+1)
+		for dx := 0; dx < dstW; dx++ {
+			x0, x1 := dx*srcW/dstW, max((dx+1)*srcW/dstW, dx*srcW/dstW+1)
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				offset := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					r += uint64(src.Pix[offset])
+					g += uint64(src.Pix[offset+1])
+					b += uint64(src.Pix[offset+2])
+					a += uint64(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			offset := dst.PixOffset(dx, dy)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// jpegOrientation 读取 JPEG 中 EXIF 的方向标记，未找到时返回 1
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if marker == 0xDA || length < 2 || offset+2+length > len(data) {
+			return 1
+		}
+		segment := data[offset+4 : offset+2+length]
+		if marker == 0xE1 && len(segment) > 14 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		offset += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation 在 TIFF 结构的第一个 IFD 中查找方向标记 (0x0112)
+func exifOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyJPEGOrientation 按 EXIF 方向标记旋转或翻转图片，使像素方向与显示方向一致
+func applyJPEGOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for dy := 0; dy < dstH; dy++ {
+		for dx := 0; dx < dstW; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-dx, dy
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sx, sy = dx, h-1-dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return dst
+}
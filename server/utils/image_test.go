@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// encodeGIF 生成画布为 width×height、包含 frames 帧 1×1 小图的动图，压缩后体积很小但解码需要为每帧分配内存
+func encodeGIF(t *testing.T, width, height, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{Config: image.Config{Width: width, Height: height, ColorModel: palette}}
+	for i := 0; i < frames; i++ {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette))
+		animation.Delay = append(animation.Delay, 0)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gifOptions(maxPixels int) ImageOptions {
+	return ImageOptions{AllowedTypes: []string{"image/gif"}, MaxPixels: maxPixels, ThumbnailSize: 16, JPEGQuality: 85}
+}
+
+func TestCountGIFFrames(t *testing.T) {
+	data := encodeGIF(t, 8, 8, 7)
+	if frames, err := countGIFFrames(data, 100); err != nil || frames != 7 {
+		t.Fatalf("countGIFFrames = %d, %v; want 7", frames, err)
+	}
+	if frames, err := countGIFFrames(data, 3); err != nil || frames != 4 {
+		t.Fatalf("countGIFFrames with limit 3 = %d, %v; want 4", frames, err)
+	}
+
+	for _, cut := range []int{5, 13, len(data) / 2, len(data) - 1} {
+		if _, err := countGIFFrames(data[:cut], 100); err == nil {
+			t.Fatalf("truncated at %d: expected error", cut)
+		}
+	}
+}
+
+func TestProcessImageRejectsGIFBomb(t *testing.T) {
+	// 1000×1000 画布、1000 帧：文件只有几 KB，完整解码需要约 1GB 内存
+	bomb := encodeGIF(t, 1000, 1000, 1000)
+	if len(bomb) > 64<<10 {
+		t.Fatalf("bomb unexpectedly large: %d bytes", len(bomb))
+	}
+	_, err := ProcessImage(bomb, gifOptions(1000*1000))
+	if err == nil || errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("expected frame count error, got %v", err)
+	}
+
+	processed, err := ProcessImage(encodeGIF(t, 32, 32, 3), gifOptions(1000*1000))
+	if err != nil {
+		t.Fatalf("small animation rejected: %v", err)
+	}
+	if processed.ContentType != "image/gif" || len(processed.Data) == 0 {
+		t.Fatalf("unexpected result %+v", processed)
+	}
+}
@@ -0,0 +1,487 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"marku-server/config"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgEdDSA = "EdDSA"
+	JWTAlgRS256 = "RS256"
+
+	jwtClockSkew = 30 * time.Second
+
+	// HS256 密钥的最小长度，与 SHA-256 输出长度一致
+	jwtMinSecretLength = 32
+	// config.yaml.example 中的占位密钥，公开可见，不能用于签发
+	jwtExampleSecret = "change-me-to-a-long-random-secret"
+)
+
+// jwtKey 单个签名/校验密钥
+type jwtKey struct {
+	kid        string
+	alg        string
+	secret     []byte
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// jwtKeySet 已加载的密钥集合
+type jwtKeySet struct {
+	active *jwtKey
+	keys   map[string]*jwtKey
+	order  []string
+}
+
+var (
+	jwtKeys     *jwtKeySet
+	jwtKeysLock sync.RWMutex
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// JWK 公开的 JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWTAudience 兼容字符串与数组两种形式的 aud 声明
+type JWTAudience []string
+
+func (a JWTAudience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *JWTAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = JWTAudience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// Contains 判断受众中是否包含指定值
+func (a JWTAudience) Contains(value string) bool {
+	for _, item := range a {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTRegisteredClaims RFC 7519 注册声明
+type JWTRegisteredClaims struct {
+	Issuer    string      `json:"iss,omitempty"`
+	Subject   string      `json:"sub,omitempty"`
+	Audience  JWTAudience `json:"aud,omitempty"`
+	ExpiresAt int64       `json:"exp,omitempty"`
+	NotBefore int64       `json:"nbf,omitempty"`
+	IssuedAt  int64       `json:"iat,omitempty"`
+	ID        string      `json:"jti,omitempty"`
+}
+
+// InitJWTKeys 根据配置加载令牌签名密钥
+func InitJWTKeys() error {
+	jwtConfig := config.GetJWTConfig()
+	keySet := &jwtKeySet{keys: make(map[string]*jwtKey)}
+
+	if jwtConfig != nil {
+		for _, keyConfig := range jwtConfig.Keys {
+			key, err := loadJWTKey(keyConfig)
+			if err != nil {
+				return fmt.Errorf("加载密钥 %s 失败: %w", keyConfig.KID, err)
+			}
+			if _, exists := keySet.keys[key.kid]; exists {
+				return fmt.Errorf("密钥 kid 重复: %s", key.kid)
+			}
+			keySet.keys[key.kid] = key
+			keySet.order = append(keySet.order, key.kid)
+		}
+	}
+
+	if len(keySet.keys) == 0 {
+		// 未配置密钥时退回使用 app_key，并与显式配置的 HS256 密钥执行相同的强度校验
+		if strings.TrimSpace(config.AppKey) == "" {
+			return fmt.Errorf("未配置 auth.jwt.keys，且 site.app_key 为空")
+		}
+		if err := validateHS256Secret(config.AppKey); err != nil {
+			return fmt.Errorf("未配置 auth.jwt.keys，site.app_key 不能用作签名密钥: %w", err)
+		}
+		key := &jwtKey{kid: "app_key", alg: JWTAlgHS256, secret: []byte(config.AppKey)}
+		keySet.keys[key.kid] = key
+		keySet.order = append(keySet.order, key.kid)
+		keySet.active = key
+	} else {
+		activeKID := ""
+		if jwtConfig != nil {
+			activeKID = strings.TrimSpace(jwtConfig.ActiveKID)
+		}
+		if activeKID == "" {
+			activeKID = keySet.order[0]
+		}
+		active, exists := keySet.keys[activeKID]
+		if !exists {
+			return fmt.Errorf("active_kid %s 不存在", activeKID)
+		}
+		if active.secret == nil && active.privateKey == nil {
+			return fmt.Errorf("active_kid %s 缺少私钥，无法签发令牌", activeKID)
+		}
+		keySet.active = active
+	}
+
+	jwtKeysLock.Lock()
+	jwtKeys = keySet
+	jwtKeysLock.Unlock()
+	return nil
+}
+
+// SignJWT 使用当前激活密钥签发 JWT
+func SignJWT(claims interface{}) (string, error) {
+	keySet, err := currentJWTKeys()
+	if err != nil {
+		return "", err
+	}
+	key := keySet.active
+
+	header, err := json.Marshal(jwtHeader{Alg: key.alg, Typ: "JWT", Kid: key.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyJWT 校验 JWT 签名并解析声明；时间、签发者与受众由 ValidateRegisteredClaims 校验
+func VerifyJWT(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("令牌格式错误")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("令牌头无效")
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return fmt.Errorf("令牌头无效")
+	}
+
+	keySet, err := currentJWTKeys()
+	if err != nil {
+		return err
+	}
+	key, exists := keySet.keys[header.Kid]
+	if !exists {
+		return fmt.Errorf("令牌密钥未知")
+	}
+	// 以密钥配置的算法为准，防止算法混淆攻击
+	if header.Alg != key.alg {
+		return fmt.Errorf("令牌算法不匹配")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("令牌签名无效")
+	}
+	if err := key.verify([]byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return fmt.Errorf("令牌签名无效")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("令牌内容无效")
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("令牌内容无效")
+	}
+	return nil
+}
+
+// NewRegisteredClaims 按配置生成带签发者、受众与有效期的注册声明
+func NewRegisteredClaims(subject string, ttl time.Duration) (JWTRegisteredClaims, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return JWTRegisteredClaims{}, err
+	}
+	now := time.Now()
+	return JWTRegisteredClaims{
+		Issuer:    jwtIssuer(),
+		Subject:   subject,
+		Audience:  JWTAudience(jwtAudience()),
+		ExpiresAt: now.Add(ttl).Unix(),
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		ID:        jti,
+	}, nil
+}
+
+// ValidateRegisteredClaims 校验有效期、签发者与受众
+func ValidateRegisteredClaims(claims JWTRegisteredClaims) error {
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(jwtClockSkew)) {
+		return fmt.Errorf("令牌已过期")
+	}
+	if claims.NotBefore != 0 && now.Add(jwtClockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("令牌尚未生效")
+	}
+	if claims.Issuer != jwtIssuer() {
+		return fmt.Errorf("令牌签发者无效")
+	}
+	audience := jwtAudience()
+	if len(audience) > 0 {
+		matched := false
+		for _, item := range audience {
+			if claims.Audience.Contains(item) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("令牌受众无效")
+		}
+	}
+	return nil
+}
+
+// GetJWKS 返回可公开的非对称公钥集合
+func GetJWKS() []JWK {
+	keySet, err := currentJWTKeys()
+	if err != nil {
+		return []JWK{}
+	}
+
+	result := make([]JWK, 0, len(keySet.order))
+	for _, kid := range keySet.order {
+		key := keySet.keys[kid]
+		switch publicKey := key.publicKey.(type) {
+		case ed25519.PublicKey:
+			result = append(result, JWK{
+				Kty: "OKP",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.alg,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		case *rsa.PublicKey:
+			result = append(result, JWK{
+				Kty: "RSA",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.alg,
+				N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		}
+	}
+	return result
+}
+
+func currentJWTKeys() (*jwtKeySet, error) {
+	jwtKeysLock.RLock()
+	defer jwtKeysLock.RUnlock()
+	if jwtKeys == nil || jwtKeys.active == nil {
+		return nil, fmt.Errorf("令牌密钥未初始化")
+	}
+	return jwtKeys, nil
+}
+
+func jwtIssuer() string {
+	if jwtConfig := config.GetJWTConfig(); jwtConfig != nil && strings.TrimSpace(jwtConfig.Issuer) != "" {
+		return strings.TrimSpace(jwtConfig.Issuer)
+	}
+	return "marku"
+}
+
+func jwtAudience() []string {
+	if jwtConfig := config.GetJWTConfig(); jwtConfig != nil && len(jwtConfig.Audience) > 0 {
+		return jwtConfig.Audience
+	}
+	return []string{"marku"}
+}
+
+// validateHS256Secret 校验 HS256 密钥的长度，并拒绝示例配置中的占位值
+func validateHS256Secret(secret string) error {
+	if len(secret) < jwtMinSecretLength {
+		return fmt.Errorf("HS256 密钥长度至少 %d 字节", jwtMinSecretLength)
+	}
+	if secret == jwtExampleSecret {
+		return fmt.Errorf("HS256 密钥仍是示例配置中的占位值，请更换为随机密钥")
+	}
+	return nil
+}
+
+func loadJWTKey(keyConfig config.JWTKeyConfig) (*jwtKey, error) {
+	kid := strings.TrimSpace(keyConfig.KID)
+	if kid == "" {
+		return nil, fmt.Errorf("kid 不能为空")
+	}
+
+	key := &jwtKey{kid: kid, alg: strings.TrimSpace(keyConfig.Algorithm)}
+	switch key.alg {
+	case JWTAlgHS256:
+		if err := validateHS256Secret(keyConfig.Secret); err != nil {
+			return nil, err
+		}
+		key.secret = []byte(keyConfig.Secret)
+		return key, nil
+	case JWTAlgEdDSA, JWTAlgRS256:
+	default:
+		return nil, fmt.Errorf("不支持的算法: %s", keyConfig.Algorithm)
+	}
+
+	if keyConfig.PrivateKeyPath != "" {
+		block, err := readPEMBlock(keyConfig.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if rsaErr != nil {
+				return nil, fmt.Errorf("私钥解析失败: %w", err)
+			}
+			privateKey = rsaKey
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("私钥类型不支持签名")
+		}
+		key.privateKey = signer
+		key.publicKey = signer.Public()
+	} else if keyConfig.PublicKeyPath != "" {
+		block, err := readPEMBlock(keyConfig.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("公钥解析失败: %w", err)
+		}
+		key.publicKey = publicKey
+	} else {
+		return nil, fmt.Errorf("%s 需要配置 private_key_path 或 public_key_path", key.alg)
+	}
+
+	switch key.publicKey.(type) {
+	case ed25519.PublicKey:
+		if key.alg != JWTAlgEdDSA {
+			return nil, fmt.Errorf("Ed25519 密钥只能用于 EdDSA")
+		}
+	case *rsa.PublicKey:
+		if key.alg != JWTAlgRS256 {
+			return nil, fmt.Errorf("RSA 密钥只能用于 RS256")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型")
+	}
+	return key, nil
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是有效的 PEM 文件", path)
+	}
+	return block, nil
+}
+
+func (k *jwtKey) sign(input []byte) ([]byte, error) {
+	switch k.alg {
+	case JWTAlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		_, _ = mac.Write(input)
+		return mac.Sum(nil), nil
+	case JWTAlgEdDSA:
+		if k.privateKey == nil {
+			return nil, fmt.Errorf("密钥 %s 缺少私钥", k.kid)
+		}
+		return k.privateKey.Sign(rand.Reader, input, crypto.Hash(0))
+	case JWTAlgRS256:
+		if k.privateKey == nil {
+			return nil, fmt.Errorf("密钥 %s 缺少私钥", k.kid)
+		}
+		digest := sha256.Sum256(input)
+		return k.privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("不支持的算法: %s", k.alg)
+	}
+}
+
+func (k *jwtKey) verify(input, signature []byte) error {
+	switch k.alg {
+	case JWTAlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		_, _ = mac.Write(input)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("签名不匹配")
+		}
+		return nil
+	case JWTAlgEdDSA:
+		publicKey, ok := k.publicKey.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(publicKey, input, signature) {
+			return fmt.Errorf("签名不匹配")
+		}
+		return nil
+	case JWTAlgRS256:
+		publicKey, ok := k.publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("签名不匹配")
+		}
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature)
+	default:
+		return fmt.Errorf("不支持的算法: %s", k.alg)
+	}
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeEd25519Key 生成 Ed25519 密钥并写入 PEM 文件，返回私钥与公钥路径
+func writeEd25519Key(t *testing.T) (ed25519.PublicKey, string, string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return publicKey, privatePath, publicPath
+}
+
+func useJWTConfig(t *testing.T, jwt config.JWTConfig) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Site.AppKey = "jwt-test-app-key-0123456789abcdef"
+	cfg.Auth.JWT = jwt
+	testutil.UseConfig(t, cfg)
+	if err := InitJWTKeys(); err != nil {
+		t.Fatalf("InitJWTKeys: %v", err)
+	}
+	t.Cleanup(func() {
+		jwtKeysLock.Lock()
+		jwtKeys = nil
+		jwtKeysLock.Unlock()
+	})
+}
+
+// forgeJWT 以任意头部与 HMAC 密钥构造令牌，secret 为 nil 时签名为空
+func forgeJWT(t *testing.T, header map[string]string, claims interface{}, secret []byte) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(header)
+	payloadJSON, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+	if secret == nil {
+		return input + "."
+	}
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWTRejectsForgedTokens(t *testing.T) {
+	publicKey, privatePath, _ := writeEd25519Key(t)
+	useJWTConfig(t, config.JWTConfig{Keys: []config.JWTKeyConfig{
+		{KID: "ed", Algorithm: JWTAlgEdDSA, PrivateKeyPath: privatePath},
+		{KID: "hs", Algorithm: JWTAlgHS256, Secret: "0123456789abcdef0123456789abcdef"},
+	}})
+
+	claims, err := NewRegisteredClaims("7", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := SignJWT(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed JWTRegisteredClaims
+	if err := VerifyJWT(valid, &parsed); err != nil || parsed.Subject != "7" {
+		t.Fatalf("valid token rejected: %v %+v", err, parsed)
+	}
+	publicDER, _ := x509.MarshalPKIXPublicKey(publicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256 signed with the EdDSA public key", forgeJWT(t, map[string]string{"alg": JWTAlgHS256, "kid": "ed"}, claims, publicKey)},
+		{"HS256 signed with the EdDSA public PEM", forgeJWT(t, map[string]string{"alg": JWTAlgHS256, "kid": "ed"}, claims, publicPEM)},
+		{"alg none", forgeJWT(t, map[string]string{"alg": "none", "kid": "ed"}, claims, nil)},
+		{"alg none without kid", forgeJWT(t, map[string]string{"alg": "none"}, claims, nil)},
+		{"EdDSA header on the HS256 key", forgeJWT(t, map[string]string{"alg": JWTAlgEdDSA, "kid": "hs"}, claims, []byte("0123456789abcdef0123456789abcdef"))},
+		{"unknown kid", forgeJWT(t, map[string]string{"alg": JWTAlgHS256, "kid": "missing"}, claims, []byte("0123456789abcdef0123456789abcdef"))},
+		{"wrong HS256 secret", forgeJWT(t, map[string]string{"alg": JWTAlgHS256, "kid": "hs"}, claims, []byte("fedcba9876543210fedcba9876543210"))},
+		{"tampered payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1"}`)) + "." + parts[2]},
+		{"stripped signature", parts[0] + "." + parts[1] + "."},
+		{"two segments", parts[0] + "." + parts[1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out JWTRegisteredClaims
+			if err := VerifyJWT(tt.token, &out); err == nil {
+				t.Fatalf("forged token accepted: %+v", out)
+			}
+		})
+	}
+
+	// 正确的 HS256 密钥签发的令牌应通过，证明上面的拒绝来自伪造而非密钥配置
+	if err := VerifyJWT(forgeJWT(t, map[string]string{"alg": JWTAlgHS256, "kid": "hs"}, claims, []byte("0123456789abcdef0123456789abcdef")), &parsed); err != nil {
+		t.Fatalf("HS256 token rejected: %v", err)
+	}
+}
+
+func TestVerifyJWTWithRetiredKey(t *testing.T) {
+	_, oldPrivate, oldPublic := writeEd25519Key(t)
+	_, newPrivate, _ := writeEd25519Key(t)
+
+	useJWTConfig(t, config.JWTConfig{Keys: []config.JWTKeyConfig{{KID: "old", Algorithm: JWTAlgEdDSA, PrivateKeyPath: oldPrivate}}})
+	claims, _ := NewRegisteredClaims("7", time.Hour)
+	token, err := SignJWT(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换后旧密钥仅保留公钥，旧令牌仍可校验
+	useJWTConfig(t, config.JWTConfig{ActiveKID: "new", Keys: []config.JWTKeyConfig{
+		{KID: "new", Algorithm: JWTAlgEdDSA, PrivateKeyPath: newPrivate},
+		{KID: "old", Algorithm: JWTAlgEdDSA, PublicKeyPath: oldPublic},
+	}})
+	var parsed JWTRegisteredClaims
+	if err := VerifyJWT(token, &parsed); err != nil {
+		t.Fatalf("token signed by retired key rejected: %v", err)
+	}
+
+	// 移除旧密钥后旧令牌失效
+	useJWTConfig(t, config.JWTConfig{Keys: []config.JWTKeyConfig{{KID: "new", Algorithm: JWTAlgEdDSA, PrivateKeyPath: newPrivate}}})
+	if err := VerifyJWT(token, &parsed); err == nil {
+		t.Fatal("token signed by removed key accepted")
+	}
+}
+
+func TestInitJWTKeysRejectsMisconfiguration(t *testing.T) {
+	_, privatePath, publicPath := writeEd25519Key(t)
+
+	tests := []struct {
+		name   string
+		appKey string
+		jwt    config.JWTConfig
+	}{
+		{"no keys and empty app_key", "", config.JWTConfig{}},
+		{"no keys and short app_key", "123456", config.JWTConfig{}},
+		{"no keys and example app_key", "change-me-to-a-long-random-secret", config.JWTConfig{}},
+		{"short HS256 secret", "k", config.JWTConfig{Keys: []config.JWTKeyConfig{{KID: "hs", Algorithm: JWTAlgHS256, Secret: "0123456789abcdef0123456789abcde"}}}},
+		{"example HS256 secret", "k", config.JWTConfig{Keys: []config.JWTKeyConfig{{KID: "hs", Algorithm: JWTAlgHS256, Secret: "change-me-to-a-long-random-secret"}}}},
+		{"Ed25519 key as RS256", "k", config.JWTConfig{Keys: []config.JWTKeyConfig{{KID: "ed", Algorithm: JWTAlgRS256, PrivateKeyPath: privatePath}}}},
+		{"unsupported alg", "k", config.JWTConfig{Keys: []config.JWTKeyConfig{{KID: "x", Algorithm: "none", Secret: "0123456789abcdef0123456789abcdef"}}}},
+		{"duplicate kid", "k", config.JWTConfig{Keys: []config.JWTKeyConfig{
+			{KID: "hs", Algorithm: JWTAlgHS256, Secret: "0123456789abcdef0123456789abcdef"},
+			{KID: "hs", Algorithm: JWTAlgHS256, Secret: "fedcba9876543210fedcba9876543210"},
+		}}},
+		{"unknown active kid", "k", config.JWTConfig{ActiveKID: "missing", Keys: []config.JWTKeyConfig{{KID: "hs", Algorithm: JWTAlgHS256, Secret: "0123456789abcdef0123456789abcdef"}}}},
+		{"active kid without private key", "k", config.JWTConfig{Keys: []config.JWTKeyConfig{{KID: "ed", Algorithm: JWTAlgEdDSA, PublicKeyPath: publicPath}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Site.AppKey = tt.appKey
+			cfg.Auth.JWT = tt.jwt
+			testutil.UseConfig(t, cfg)
+			if err := InitJWTKeys(); err == nil {
+				t.Fatal("expected configuration error")
+			}
+		})
+	}
+}
+
+func TestValidateRegisteredClaims(t *testing.T) {
+	useJWTConfig(t, config.JWTConfig{Issuer: "https://marku.example", Audience: []string{"marku-web", "marku-app"}})
+	now := time.Now()
+	base := func() JWTRegisteredClaims {
+		return JWTRegisteredClaims{
+			Issuer:    "https://marku.example",
+			Audience:  JWTAudience{"marku-web"},
+			ExpiresAt: now.Add(time.Hour).Unix(),
+			NotBefore: now.Unix(),
+			IssuedAt:  now.Unix(),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*JWTRegisteredClaims)
+		wantErr bool
+	}{
+		{"valid", func(*JWTRegisteredClaims) {}, false},
+		{"expired", func(c *JWTRegisteredClaims) { c.ExpiresAt = now.Add(-time.Minute).Unix() }, true},
+		{"expired within clock skew", func(c *JWTRegisteredClaims) { c.ExpiresAt = now.Add(-10 * time.Second).Unix() }, false},
+		{"missing exp", func(c *JWTRegisteredClaims) { c.ExpiresAt = 0 }, true},
+		{"nbf in the future", func(c *JWTRegisteredClaims) { c.NotBefore = now.Add(time.Minute).Unix() }, true},
+		{"nbf within clock skew", func(c *JWTRegisteredClaims) { c.NotBefore = now.Add(10 * time.Second).Unix() }, false},
+		{"missing nbf", func(c *JWTRegisteredClaims) { c.NotBefore = 0 }, false},
+		{"wrong issuer", func(c *JWTRegisteredClaims) { c.Issuer = "https://evil.example" }, true},
+		{"missing issuer", func(c *JWTRegisteredClaims) { c.Issuer = "" }, true},
+		{"second audience", func(c *JWTRegisteredClaims) { c.Audience = JWTAudience{"other", "marku-app"} }, false},
+		{"wrong audience", func(c *JWTRegisteredClaims) { c.Audience = JWTAudience{"other"} }, true},
+		{"missing audience", func(c *JWTRegisteredClaims) { c.Audience = nil }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := base()
+			tt.modify(&claims)
+			if err := ValidateRegisteredClaims(claims); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTAudienceJSON(t *testing.T) {
+	var audience JWTAudience
+	if err := json.Unmarshal([]byte(`"marku"`), &audience); err != nil || !audience.Contains("marku") {
+		t.Fatalf("string aud: %v %v", audience, err)
+	}
+	if err := json.Unmarshal([]byte(`["a","b"]`), &audience); err != nil || !audience.Contains("b") {
+		t.Fatalf("array aud: %v %v", audience, err)
+	}
+	if err := json.Unmarshal([]byte(`1`), &audience); err == nil {
+		t.Fatal("numeric aud accepted")
+	}
+	if data, _ := json.Marshal(JWTAudience{"marku"}); string(data) != `"marku"` {
+		t.Fatalf("single aud marshalled as %s", data)
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+import "regexp"
+
+// MaxMentionsPerComment 单条评论最多解析的提及数量
+const MaxMentionsPerComment = 10
+
+// @ 前不能紧跟字母数字，避免把邮箱地址识别为提及
+var mentionPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_-]{1,100})`)
+
+// ParseMentions 提取内容中的 @用户名，按首次出现顺序去重
+func ParseMentions(content string) []string {
+	names := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := match[2]
+		if _, exists := seen[name]; exists {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+		if len(names) >= MaxMentionsPerComment {
+			break
+		}
+	}
+	return names
+}
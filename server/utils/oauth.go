@@ -0,0 +1,347 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"marku-server/config"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OAuthTypeGitHub = "github"
+	OAuthTypeGitee  = "gitee"
+	OAuthTypeOIDC   = "oidc"
+)
+
+var (
+	oauthHTTPClient = &http.Client{Timeout: 10 * time.Second}
+	oidcDiscovery   sync.Map // issuer -> *oidcDiscoveryDocument
+)
+
+// OAuthProvider 解析后的第三方登录提供方
+type OAuthProvider struct {
+	Name         string
+	Type         string
+	ClientID     string
+	ClientSecret string
+	CallbackURL  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	EmailsURL    string
+	Scopes       []string
+}
+
+// OAuthUserInfo 第三方账户的用户信息
+type OAuthUserInfo struct {
+	Subject       string
+	Username      string
+	Name          string
+	Email         string
+	EmailVerified bool
+	AvatarURL     string
+	ProfileURL    string
+	WebsiteURL    string
+}
+
+type oidcDiscoveryDocument struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// GetOAuthProvider 根据名称获取提供方，补全内置默认端点或 OIDC 自动发现结果
+func GetOAuthProvider(name string) (*OAuthProvider, error) {
+	providerConfig := config.GetOAuthProviderConfig(name)
+	if providerConfig == nil {
+		return nil, fmt.Errorf("未配置的登录方式: %s", name)
+	}
+
+	provider := &OAuthProvider{
+		Name:         providerConfig.Name,
+		Type:         strings.ToLower(strings.TrimSpace(providerConfig.Type)),
+		ClientID:     providerConfig.ClientID,
+		ClientSecret: providerConfig.ClientSecret,
+		CallbackURL:  providerConfig.CallbackURL,
+		AuthURL:      providerConfig.AuthURL,
+		TokenURL:     providerConfig.TokenURL,
+		UserInfoURL:  providerConfig.UserInfoURL,
+		EmailsURL:    providerConfig.EmailsURL,
+		Scopes:       providerConfig.Scopes,
+	}
+
+	switch provider.Type {
+	case OAuthTypeGitHub:
+		provider.AuthURL = firstNonEmpty(provider.AuthURL, "https://github.com/login/oauth/authorize")
+		provider.TokenURL = firstNonEmpty(provider.TokenURL, "https://github.com/login/oauth/access_token")
+		provider.UserInfoURL = firstNonEmpty(provider.UserInfoURL, "https://api.github.com/user")
+		provider.EmailsURL = firstNonEmpty(provider.EmailsURL, "https://api.github.com/user/emails")
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"read:user", "user:email"}
+		}
+	case OAuthTypeGitee:
+		provider.AuthURL = firstNonEmpty(provider.AuthURL, "https://gitee.com/oauth/authorize")
+		provider.TokenURL = firstNonEmpty(provider.TokenURL, "https://gitee.com/oauth/token")
+		provider.UserInfoURL = firstNonEmpty(provider.UserInfoURL, "https://gitee.com/api/v5/user")
+		provider.EmailsURL = firstNonEmpty(provider.EmailsURL, "https://gitee.com/api/v5/emails")
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"user_info", "emails"}
+		}
+	case OAuthTypeOIDC:
+		if provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "" {
+			document, err := discoverOIDC(providerConfig.Issuer)
+			if err != nil {
+				return nil, err
+			}
+			provider.AuthURL = firstNonEmpty(provider.AuthURL, document.AuthorizationEndpoint)
+			provider.TokenURL = firstNonEmpty(provider.TokenURL, document.TokenEndpoint)
+			provider.UserInfoURL = firstNonEmpty(provider.UserInfoURL, document.UserInfoEndpoint)
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "profile", "email"}
+		}
+	default:
+		return nil, fmt.Errorf("不支持的登录方式类型: %s", providerConfig.Type)
+	}
+
+	if provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "" {
+		return nil, fmt.Errorf("登录方式 %s 的端点配置不完整", name)
+	}
+	return provider, nil
+}
+
+// NewPKCEVerifier 生成 PKCE code_verifier 与 S256 code_challenge
+func NewPKCEVerifier() (string, string, error) {
+	verifier, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AuthCodeURL 构造授权页跳转地址
+func (p *OAuthProvider) AuthCodeURL(state, codeChallenge, callbackURL string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", callbackURL)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.AuthURL + separator + query.Encode()
+}
+
+// Exchange 使用授权码换取访问令牌
+func (p *OAuthProvider) Exchange(code, codeVerifier, callbackURL string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", callbackURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := doOAuthJSONRequest(req, &result); err != nil {
+		return "", err
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("%s: %s", result.Error, result.ErrorDescription)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("未获取到访问令牌")
+	}
+	return result.AccessToken, nil
+}
+
+// FetchUserInfo 获取第三方账户信息
+func (p *OAuthProvider) FetchUserInfo(accessToken string) (*OAuthUserInfo, error) {
+	switch p.Type {
+	case OAuthTypeGitHub, OAuthTypeGitee:
+		return p.fetchGitUserInfo(accessToken)
+	default:
+		return p.fetchOIDCUserInfo(accessToken)
+	}
+}
+
+func (p *OAuthProvider) fetchGitUserInfo(accessToken string) (*OAuthUserInfo, error) {
+	var profile struct {
+		ID        json.Number `json:"id"`
+		Login     string      `json:"login"`
+		Name      string      `json:"name"`
+		Email     string      `json:"email"`
+		AvatarURL string      `json:"avatar_url"`
+		HTMLURL   string      `json:"html_url"`
+		Blog      string      `json:"blog"`
+	}
+	if err := p.getJSON(p.UserInfoURL, accessToken, &profile); err != nil {
+		return nil, err
+	}
+	if profile.ID.String() == "" {
+		return nil, fmt.Errorf("第三方账户信息缺少用户ID")
+	}
+
+	info := &OAuthUserInfo{
+		Subject:    profile.ID.String(),
+		Username:   profile.Login,
+		Name:       profile.Name,
+		AvatarURL:  profile.AvatarURL,
+		ProfileURL: profile.HTMLURL,
+		WebsiteURL: profile.Blog,
+	}
+
+	// 资料中的公开邮箱未必已验证，优先使用邮箱接口返回的已验证主邮箱
+	if p.EmailsURL != "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+			State    string `json:"state"`
+		}
+		if err := p.getJSON(p.EmailsURL, accessToken, &emails); err == nil {
+			for _, item := range emails {
+				verified := item.Verified || item.State == "confirmed"
+				if verified && (item.Primary || info.Email == "") {
+					info.Email = item.Email
+					info.EmailVerified = true
+				}
+			}
+		}
+	}
+	if info.Email == "" {
+		info.Email = profile.Email
+	}
+	return info, nil
+}
+
+func (p *OAuthProvider) fetchOIDCUserInfo(accessToken string) (*OAuthUserInfo, error) {
+	var claims struct {
+		Subject           string          `json:"sub"`
+		PreferredUsername string          `json:"preferred_username"`
+		Name              string          `json:"name"`
+		Email             string          `json:"email"`
+		EmailVerified     json.RawMessage `json:"email_verified"`
+		Picture           string          `json:"picture"`
+		Profile           string          `json:"profile"`
+		Website           string          `json:"website"`
+	}
+	if err := p.getJSON(p.UserInfoURL, accessToken, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("第三方账户信息缺少 sub")
+	}
+
+	// 部分提供方以字符串形式返回 email_verified
+	verified, _ := strconv.ParseBool(strings.Trim(string(claims.EmailVerified), `"`))
+	return &OAuthUserInfo{
+		Subject:       claims.Subject,
+		Username:      claims.PreferredUsername,
+		Name:          claims.Name,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		AvatarURL:     claims.Picture,
+		ProfileURL:    claims.Profile,
+		WebsiteURL:    claims.Website,
+	}, nil
+}
+
+func (p *OAuthProvider) getJSON(endpoint, accessToken string, target interface{}) error {
+	if p.Type == OAuthTypeGitee {
+		// Gitee 的 v5 接口通过查询参数传递访问令牌
+		parsed, err := url.Parse(endpoint)
+		if err != nil {
+			return err
+		}
+		query := parsed.Query()
+		query.Set("access_token", accessToken)
+		parsed.RawQuery = query.Encode()
+		endpoint = parsed.String()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.Type != OAuthTypeGitee {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return doOAuthJSONRequest(req, target)
+}
+
+func discoverOIDC(issuer string) (*oidcDiscoveryDocument, error) {
+	issuer = strings.TrimRight(strings.TrimSpace(issuer), "/")
+	if issuer == "" {
+		return nil, fmt.Errorf("OIDC 提供方需要配置 issuer 或显式端点")
+	}
+	if cached, ok := oidcDiscovery.Load(issuer); ok {
+		return cached.(*oidcDiscoveryDocument), nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var document oidcDiscoveryDocument
+	if err := doOAuthJSONRequest(req, &document); err != nil {
+		return nil, fmt.Errorf("OIDC 端点发现失败: %w", err)
+	}
+	oidcDiscovery.Store(issuer, &document)
+	return &document, nil
+}
+
+func doOAuthJSONRequest(req *http.Request, target interface{}) error {
+	resp, err := oauthHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("第三方接口返回 %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("第三方接口响应解析失败: %w", err)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"marku-server/config"
+	"strings"
+)
+
+// MaskEmail 邮箱打码，只保留用户名首字符与域名，例如 a***@example.com
+func MaskEmail(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "***"
+	}
+	local := []rune(email[:at])
+	return string(local[0]) + "***" + email[at:]
+}
+
+// CoarseLocation 将 "国家/省/市" 形式的地区截取到省级，避免公开精确位置
+func CoarseLocation(location string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(location), func(r rune) bool {
+		return r == '/' || r == ' ' || r == '|' || r == ','
+	})
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, "/")
+}
+
+// VisitorFingerprint 由 IP 与 User-Agent 计算未登录访客的匿名指纹，以 app_key 作为密钥，数据库中不保存原始 IP
+func VisitorFingerprint(ip, ua string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppKey))
+	_, _ = mac.Write([]byte(strings.TrimSpace(ip) + "\n" + strings.TrimSpace(ua)))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// ClientIPHash 计算访客 IP 的匿名标识，用于按 IP 计数与限流，数据库中不保存原始 IP
+func ClientIPHash(ip string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppKey))
+	_, _ = mac.Write([]byte("ip\n" + strings.TrimSpace(ip)))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"marku-server/config"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestBaseURL 返回服务对外访问地址：优先使用配置的 site.public_base_url，
+// 未配置时按请求推导（Host 与 X-Forwarded-Proto 由客户端控制，仅适用于开发环境）
+func RequestBaseURL(c *gin.Context) string {
+	if baseURL := config.GetPublicBaseURL(); baseURL != "" {
+		return baseURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"marku-server/config"
+	"marku-server/internal/testutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestBaseURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		host       string
+		proto      string
+		want       string
+	}{
+		{"configured ignores forged host", "https://comments.example.com/", "evil.example", "http", "https://comments.example.com"},
+		{"fallback to request", "", "localhost:12123", "", "http://localhost:12123"},
+		{"fallback honours forwarded proto", "", "localhost:12123", "https", "https://localhost:12123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Site.PublicBaseURL = tt.configured
+			testutil.UseConfig(t, cfg)
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/comment/list", nil)
+			c.Request.Host = tt.host
+			if tt.proto != "" {
+				c.Request.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if got := RequestBaseURL(c); got != tt.want {
+				t.Fatalf("RequestBaseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
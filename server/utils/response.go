@@ -5,6 +5,9 @@ import (
 	"net/http"
 )
 
+// ResponseCodeKey 在上下文中记录响应体 code 的键，供审计等中间件读取
+const ResponseCodeKey = "marku_response_code"
+
 // Response 是标准的响应结构体
 type Response struct {
 	Code    int         `json:"code"`
@@ -24,6 +27,7 @@ func NewResponse(code int, message string, data interface{}) *Response {
 // SendResponse 发送 JSON 响应给客户端
 func SendResponse(c *gin.Context, code int, message string, data interface{}) {
 	response := NewResponse(code, message, data)
+	c.Set(ResponseCodeKey, code)
 	c.JSON(http.StatusOK, response) // 直接使用传入的状态码
 }
 
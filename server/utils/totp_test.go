@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcTOTPSecret 为 RFC 4226 / RFC 6238 附录中 SHA1 测试密钥 "12345678901234567890" 的 Base32 编码
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateHOTPVectors(t *testing.T) {
+	// RFC 4226 附录 D
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	key := []byte("12345678901234567890")
+	for counter, code := range want {
+		if got := generateHOTP(key, int64(counter)); got != code {
+			t.Errorf("counter %d: got %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestValidateTOTPVectors(t *testing.T) {
+	// RFC 6238 附录 B 的 SHA1 用例，取 8 位动态码的后 6 位
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		counter, ok := ValidateTOTP(rfcTOTPSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("T=%d: code %s rejected", tt.unix, tt.code)
+			continue
+		}
+		if counter != tt.unix/totpPeriod {
+			t.Errorf("T=%d: matched counter %d, want %d", tt.unix, counter, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+	code := "081804"
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		now    time.Time
+		wantOK bool
+	}{
+		{"same step", rfcTOTPSecret, code, at, true},
+		{"one step later", rfcTOTPSecret, code, at.Add(30 * time.Second), true},
+		{"one step earlier", rfcTOTPSecret, code, at.Add(-30 * time.Second), true},
+		{"two steps later", rfcTOTPSecret, code, at.Add(61 * time.Second), false},
+		{"two steps earlier", rfcTOTPSecret, code, at.Add(-60 * time.Second), false},
+		{"lowercase secret", strings.ToLower(rfcTOTPSecret), code, at, true},
+		{"surrounding spaces", rfcTOTPSecret, " " + code + " ", at, true},
+		{"8 digit code", rfcTOTPSecret, "07081804", at, false},
+		{"short code", rfcTOTPSecret, "81804", at, false},
+		{"wrong code", rfcTOTPSecret, "081805", at, false},
+		{"invalid secret", "not base32!", code, at, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, tt.now); ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes: %v", secret, len(key), err)
+	}
+
+	uri, err := url.Parse(TOTPProvisioningURI("Marku Blog", "alice@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := uri.Query()
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Marku Blog:alice@example.com" ||
+		query.Get("secret") != secret || query.Get("issuer") != "Marku Blog" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Fatalf("unexpected provisioning uri %s", uri)
+	}
+}
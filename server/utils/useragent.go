@@ -0,0 +1,163 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 内置 User-Agent 解析规则，可通过 comment.ua_rules_path 指定同格式的文件覆盖
+//
+//go:embed ua_rules.json
+var embeddedUARules []byte
+
+// 设备类型
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// UserAgentInfo User-Agent 解析结果
+type UserAgentInfo struct {
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device,omitempty"`
+}
+
+type uaRuleFile struct {
+	Browsers []uaNameRule   `json:"browsers"`
+	OS       []uaNameRule   `json:"os"`
+	Devices  []uaDeviceRule `json:"devices"`
+}
+
+// uaNameRule 按顺序匹配，第一个捕获组为版本号，versions 用于将内部版本号映射为展示名称
+type uaNameRule struct {
+	Name     string            `json:"name"`
+	Pattern  string            `json:"pattern"`
+	Versions map[string]string `json:"versions"`
+	regex    *regexp.Regexp
+}
+
+// uaDeviceRule 匹配 pattern 且不匹配 exclude 时判定为对应设备类型
+type uaDeviceRule struct {
+	Type         string `json:"type"`
+	Pattern      string `json:"pattern"`
+	Exclude      string `json:"exclude"`
+	regex        *regexp.Regexp
+	excludeRegex *regexp.Regexp
+}
+
+var (
+	uaRules     *uaRuleFile
+	uaRulesOnce sync.Once
+)
+
+// LoadUARules 加载 User-Agent 解析规则，path 为空时使用内置规则
+func LoadUARules(path string) error {
+	data := embeddedUARules
+	if path = strings.TrimSpace(path); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取 UA 规则文件失败: %w", err)
+		}
+		data = content
+	}
+
+	rules, err := compileUARules(data)
+	if err != nil {
+		return err
+	}
+	uaRules = rules
+	return nil
+}
+
+// ParseUserAgent 解析 User-Agent，得到浏览器、操作系统与设备类型
+func ParseUserAgent(ua string) UserAgentInfo {
+	uaRulesOnce.Do(func() {
+		if uaRules == nil {
+			uaRules, _ = compileUARules(embeddedUARules)
+		}
+	})
+
+	info := UserAgentInfo{}
+	ua = strings.TrimSpace(ua)
+	if ua == "" || uaRules == nil {
+		return info
+	}
+
+	info.Browser, info.BrowserVersion = matchNameRules(uaRules.Browsers, ua)
+	info.OS, info.OSVersion = matchNameRules(uaRules.OS, ua)
+
+	info.Device = DeviceDesktop
+	for _, rule := range uaRules.Devices {
+		if rule.regex.MatchString(ua) && (rule.excludeRegex == nil || !rule.excludeRegex.MatchString(ua)) {
+			info.Device = rule.Type
+			break
+		}
+	}
+	return info
+}
+
+func matchNameRules(rules []uaNameRule, ua string) (string, string) {
+	for _, rule := range rules {
+		match := rule.regex.FindStringSubmatch(ua)
+		if match == nil {
+			continue
+		}
+		version := ""
+		if len(match) > 1 {
+			version = strings.ReplaceAll(match[1], "_", ".")
+		}
+		if mapped, ok := rule.Versions[version]; ok {
+			version = mapped
+		}
+		return rule.Name, version
+	}
+	return "", ""
+}
+
+func compileUARules(data []byte) (*uaRuleFile, error) {
+	var rules uaRuleFile
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("UA 规则格式错误: %w", err)
+	}
+
+	compileNames := func(items []uaNameRule) error {
+		for i := range items {
+			regex, err := regexp.Compile(items[i].Pattern)
+			if err != nil {
+				return fmt.Errorf("UA 规则 %s 无效: %w", items[i].Name, err)
+			}
+			items[i].regex = regex
+		}
+		return nil
+	}
+	if err := compileNames(rules.Browsers); err != nil {
+		return nil, err
+	}
+	if err := compileNames(rules.OS); err != nil {
+		return nil, err
+	}
+
+	for i := range rules.Devices {
+		regex, err := regexp.Compile(rules.Devices[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("UA 设备规则 %s 无效: %w", rules.Devices[i].Type, err)
+		}
+		rules.Devices[i].regex = regex
+		if rules.Devices[i].Exclude != "" {
+			if rules.Devices[i].excludeRegex, err = regexp.Compile(rules.Devices[i].Exclude); err != nil {
+				return nil, fmt.Errorf("UA 设备规则 %s 无效: %w", rules.Devices[i].Type, err)
+			}
+		}
+	}
+	return &rules, nil
+}
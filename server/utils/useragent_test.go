@@ -0,0 +1,103 @@
+package utils
+
+import "testing"
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want UserAgentInfo
+	}{
+		{
+			"Chrome on Windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.130 Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", BrowserVersion: "120.0.6099.130", OS: "Windows", OSVersion: "10/11", Device: DeviceDesktop},
+		},
+		{
+			"Edge on Windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			UserAgentInfo{Browser: "Edge", BrowserVersion: "120.0.2210.91", OS: "Windows", OSVersion: "10/11", Device: DeviceDesktop},
+		},
+		{
+			"Firefox on Windows 7",
+			"Mozilla/5.0 (Windows NT 6.1; Win64; x64; rv:115.0) Gecko/20100101 Firefox/115.0",
+			UserAgentInfo{Browser: "Firefox", BrowserVersion: "115.0", OS: "Windows", OSVersion: "7", Device: DeviceDesktop},
+		},
+		{
+			"Safari on macOS",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			UserAgentInfo{Browser: "Safari", BrowserVersion: "17.2", OS: "macOS", OSVersion: "10.15.7", Device: DeviceDesktop},
+		},
+		{
+			"Firefox on macOS",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.2; rv:121.0) Gecko/20100101 Firefox/121.0",
+			UserAgentInfo{Browser: "Firefox", BrowserVersion: "121.0", OS: "macOS", OSVersion: "14.2", Device: DeviceDesktop},
+		},
+		{
+			"Safari on iPhone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			UserAgentInfo{Browser: "Safari", BrowserVersion: "17.2", OS: "iOS", OSVersion: "17.2.1", Device: DeviceMobile},
+		},
+		{
+			"Chrome on iPhone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			UserAgentInfo{Browser: "Chrome", BrowserVersion: "120.0.6099.119", OS: "iOS", OSVersion: "17.2", Device: DeviceMobile},
+		},
+		{
+			"Safari on iPad",
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			UserAgentInfo{Browser: "Safari", BrowserVersion: "16.6", OS: "iPadOS", OSVersion: "16.6", Device: DeviceTablet},
+		},
+		{
+			"Chrome on Android phone",
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", BrowserVersion: "120.0.6099.144", OS: "Android", OSVersion: "14", Device: DeviceMobile},
+		},
+		{
+			"Android tablet",
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", BrowserVersion: "119.0.0.0", OS: "Android", OSVersion: "13", Device: DeviceTablet},
+		},
+		{
+			"Edge on Android",
+			"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36 EdgA/120.0.2210.115",
+			UserAgentInfo{Browser: "Edge", BrowserVersion: "120.0.2210.115", OS: "Android", OSVersion: "10", Device: DeviceMobile},
+		},
+		{
+			"WeChat on Android",
+			"Mozilla/5.0 (Linux; Android 12; V2118A Build/SP1A.210812.003; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/107.0.5304.141 Mobile Safari/537.36 XWEB/5023 MMWEBSDK/20230202 MicroMessenger/8.0.33.2320(0x28002151) WeChat/arm64 Weixin NetType/WIFI Language/zh_CN ABI/arm64",
+			UserAgentInfo{Browser: "WeChat", BrowserVersion: "8.0.33.2320", OS: "Android", OSVersion: "12", Device: DeviceMobile},
+		},
+		{
+			"Googlebot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			UserAgentInfo{Device: DeviceBot},
+		},
+		{
+			"curl",
+			"curl/8.4.0",
+			UserAgentInfo{Browser: "curl", BrowserVersion: "8.4.0", Device: DeviceBot},
+		},
+		{"empty", "", UserAgentInfo{}},
+		{"whitespace", "   ", UserAgentInfo{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseUserAgent(tt.ua); got != tt.want {
+				t.Fatalf("ParseUserAgent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadUARulesRejectsInvalidRules(t *testing.T) {
+	if _, err := compileUARules([]byte(`{"browsers": [{"name": "Broken", "pattern": "("}]}`)); err == nil {
+		t.Fatal("invalid pattern accepted")
+	}
+	if _, err := compileUARules([]byte(`not json`)); err == nil {
+		t.Fatal("invalid json accepted")
+	}
+	if err := LoadUARules("/nonexistent/ua_rules.json"); err == nil {
+		t.Fatal("missing rules file accepted")
+	}
+}
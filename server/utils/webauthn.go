@@ -0,0 +1,324 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// COSE 算法标识
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+// 认证器数据标志位
+const (
+	webAuthnFlagUserPresent   = 0x01
+	webAuthnFlagUserVerified  = 0x04
+	webAuthnFlagAttestedData  = 0x40
+	webAuthnFlagExtensionData = 0x80
+)
+
+// WebAuthnSupportedAlgorithms 服务端支持的凭证公钥算法，按优先级排列
+var WebAuthnSupportedAlgorithms = []int{COSEAlgES256, COSEAlgEdDSA, COSEAlgRS256}
+
+// WebAuthnClientData 客户端数据 (clientDataJSON)
+type WebAuthnClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// WebAuthnAuthenticatorData 认证器数据
+type WebAuthnAuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE_Key 原始编码
+}
+
+// UserPresent 用户是否在场
+func (a *WebAuthnAuthenticatorData) UserPresent() bool {
+	return a.Flags&webAuthnFlagUserPresent != 0
+}
+
+// UserVerified 用户是否已通过生物识别或 PIN 验证
+func (a *WebAuthnAuthenticatorData) UserVerified() bool {
+	return a.Flags&webAuthnFlagUserVerified != 0
+}
+
+// WebAuthnAttestation 解析后的注册证明
+type WebAuthnAttestation struct {
+	Format       string
+	Statement    map[interface{}]interface{}
+	AuthData     *WebAuthnAuthenticatorData
+	RawAuthData  []byte
+	PublicKey    crypto.PublicKey
+	KeyAlgorithm int
+}
+
+// DecodeBase64URL 解码 base64url，兼容带填充与标准 base64 的输入
+func DecodeBase64URL(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimRight(value, "=")
+	value = strings.NewReplacer("+", "-", "/", "_").Replace(value)
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+// ParseWebAuthnClientData 解析并校验 clientDataJSON 的类型、挑战与来源
+func ParseWebAuthnClientData(raw []byte, expectedType, expectedChallenge, expectedOrigin string) (*WebAuthnClientData, error) {
+	var clientData WebAuthnClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, fmt.Errorf("clientDataJSON 无效")
+	}
+	if clientData.Type != expectedType {
+		return nil, fmt.Errorf("凭证类型不匹配")
+	}
+	if clientData.Challenge != expectedChallenge {
+		return nil, fmt.Errorf("挑战不匹配")
+	}
+	if clientData.Origin != expectedOrigin {
+		return nil, fmt.Errorf("来源不匹配")
+	}
+	if clientData.CrossOrigin {
+		return nil, fmt.Errorf("不允许跨域凭证")
+	}
+	return &clientData, nil
+}
+
+// ParseWebAuthnAuthenticatorData 解析认证器数据
+func ParseWebAuthnAuthenticatorData(raw []byte) (*WebAuthnAuthenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, fmt.Errorf("认证器数据过短")
+	}
+
+	authData := &WebAuthnAuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	rest := raw[37:]
+	if authData.Flags&webAuthnFlagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("凭证数据不完整")
+		}
+		authData.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, fmt.Errorf("凭证ID不完整")
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, consumed, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("凭证公钥无效: %w", err)
+		}
+		authData.PublicKey = rest[:consumed]
+		rest = rest[consumed:]
+	}
+
+	if authData.Flags&webAuthnFlagExtensionData != 0 {
+		_, consumed, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("扩展数据无效: %w", err)
+		}
+		rest = rest[consumed:]
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("认证器数据存在多余字节")
+	}
+	return authData, nil
+}
+
+// CheckWebAuthnRPID 校验认证器数据中的 RP ID 摘要与用户在场标志
+func CheckWebAuthnRPID(authData *WebAuthnAuthenticatorData, rpID string) error {
+	expected := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(authData.RPIDHash, expected[:]) {
+		return fmt.Errorf("RP ID 不匹配")
+	}
+	if !authData.UserPresent() {
+		return fmt.Errorf("用户未确认操作")
+	}
+	return nil
+}
+
+// ParseWebAuthnAttestation 解析注册证明对象并校验证明签名（支持 none 与 packed）
+func ParseWebAuthnAttestation(attestationObject, clientDataJSON []byte) (*WebAuthnAttestation, error) {
+	decoded, _, err := DecodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("attestationObject 无效: %w", err)
+	}
+	object, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("attestationObject 格式错误")
+	}
+
+	format, _ := object["fmt"].(string)
+	statement, _ := object["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := object["authData"].([]byte)
+	if format == "" || rawAuthData == nil {
+		return nil, fmt.Errorf("attestationObject 缺少字段")
+	}
+
+	authData, err := ParseWebAuthnAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.Flags&webAuthnFlagAttestedData == 0 || len(authData.CredentialID) == 0 {
+		return nil, fmt.Errorf("注册数据缺少凭证")
+	}
+
+	publicKey, algorithm, err := ParseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	attestation := &WebAuthnAttestation{
+		Format:       format,
+		Statement:    statement,
+		AuthData:     authData,
+		RawAuthData:  rawAuthData,
+		PublicKey:    publicKey,
+		KeyAlgorithm: algorithm,
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signedData := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+
+	switch format {
+	case "none":
+		return attestation, nil
+	case "packed":
+		alg, _ := statement["alg"].(int64)
+		signature, _ := statement["sig"].([]byte)
+		if signature == nil {
+			return nil, fmt.Errorf("packed 证明缺少签名")
+		}
+
+		certificates, _ := statement["x5c"].([]interface{})
+		if len(certificates) == 0 {
+			// 自证明：使用凭证私钥签名
+			if int(alg) != algorithm {
+				return nil, fmt.Errorf("证明算法与凭证算法不一致")
+			}
+			if err := VerifyWebAuthnSignature(publicKey, algorithm, signedData, signature); err != nil {
+				return nil, fmt.Errorf("证明签名无效")
+			}
+			return attestation, nil
+		}
+
+		// 带证书的证明只校验签名，不校验证书链的信任根
+		der, _ := certificates[0].([]byte)
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("证明证书无效")
+		}
+		if err := VerifyWebAuthnSignature(certificate.PublicKey, int(alg), signedData, signature); err != nil {
+			return nil, fmt.Errorf("证明签名无效")
+		}
+		return attestation, nil
+	default:
+		return nil, fmt.Errorf("不支持的证明格式: %s", format)
+	}
+}
+
+// ParseCOSEKey 解析 COSE_Key 公钥
+func ParseCOSEKey(raw []byte) (crypto.PublicKey, int, error) {
+	decoded, _, err := DecodeCBOR(raw)
+	if err != nil {
+		return nil, 0, fmt.Errorf("凭证公钥无效: %w", err)
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("凭证公钥格式错误")
+	}
+
+	keyType, _ := key[int64(1)].(int64)
+	algorithm, _ := key[int64(3)].(int64)
+
+	switch int(algorithm) {
+	case COSEAlgES256:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if keyType != 2 || curve != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("ES256 公钥参数无效")
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, 0, fmt.Errorf("ES256 公钥不在曲线上")
+		}
+		return publicKey, COSEAlgES256, nil
+	case COSEAlgEdDSA:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if keyType != 1 || curve != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("EdDSA 公钥参数无效")
+		}
+		return ed25519.PublicKey(x), COSEAlgEdDSA, nil
+	case COSEAlgRS256:
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if keyType != 3 || len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("RS256 公钥参数无效")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, COSEAlgRS256, nil
+	default:
+		return nil, 0, fmt.Errorf("不支持的凭证算法: %d", algorithm)
+	}
+}
+
+// VerifyWebAuthnSignature 按 COSE 算法校验签名
+func VerifyWebAuthnSignature(publicKey crypto.PublicKey, algorithm int, data, signature []byte) error {
+	switch algorithm {
+	case COSEAlgES256:
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("公钥类型不匹配")
+		}
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return fmt.Errorf("签名不匹配")
+		}
+		return nil
+	case COSEAlgEdDSA:
+		key, ok := publicKey.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(key, data, signature) {
+			return fmt.Errorf("签名不匹配")
+		}
+		return nil
+	case COSEAlgRS256:
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("公钥类型不匹配")
+		}
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	default:
+		return fmt.Errorf("不支持的签名算法: %d", algorithm)
+	}
+}
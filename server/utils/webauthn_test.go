@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+)
+
+// cborHead 编码 CBOR 数据项头部
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, -1-n)
+	}
+	return cborHead(0, n)
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, len(b)), b...)
+}
+
+func es256COSEKey(key *ecdsa.PublicKey) []byte {
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	out := cborHead(5, 5)
+	out = append(append(out, cborInt(1)...), cborInt(2)...)
+	out = append(append(out, cborInt(3)...), cborInt(COSEAlgES256)...)
+	out = append(append(out, cborInt(-1)...), cborInt(1)...)
+	out = append(append(out, cborInt(-2)...), cborBytes(x)...)
+	return append(append(out, cborInt(-3)...), cborBytes(y)...)
+}
+
+func ed25519COSEKey(key ed25519.PublicKey) []byte {
+	out := cborHead(5, 4)
+	out = append(append(out, cborInt(1)...), cborInt(1)...)
+	out = append(append(out, cborInt(3)...), cborInt(COSEAlgEdDSA)...)
+	out = append(append(out, cborInt(-1)...), cborInt(6)...)
+	return append(append(out, cborInt(-2)...), cborBytes(key)...)
+}
+
+func testAuthData(rpID string, flags byte, signCount uint32, attested []byte) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	out := append(hash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, signCount)
+	return append(out, attested...)
+}
+
+func attestedCredential(credentialID, coseKey []byte) []byte {
+	out := make([]byte, 16)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(credentialID)))
+	out = append(out, credentialID...)
+	return append(out, coseKey...)
+}
+
+func TestParseWebAuthnClientData(t *testing.T) {
+	const challenge, origin = "Y2hhbGxlbmdl", "https://blog.example"
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{"valid", `{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://blog.example"}`, false},
+		{"wrong type", `{"type":"webauthn.create","challenge":"Y2hhbGxlbmdl","origin":"https://blog.example"}`, true},
+		{"wrong challenge", `{"type":"webauthn.get","challenge":"b3RoZXI","origin":"https://blog.example"}`, true},
+		{"wrong origin", `{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://evil.example"}`, true},
+		{"subdomain origin", `{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://a.blog.example"}`, true},
+		{"cross origin", `{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://blog.example","crossOrigin":true}`, true},
+		{"not json", `type=webauthn.get`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWebAuthnClientData([]byte(tt.raw), "webauthn.get", challenge, origin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseWebAuthnAuthenticatorData(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	coseKey := es256COSEKey(&key.PublicKey)
+	attested := attestedCredential([]byte("credential-1"), coseKey)
+
+	full := testAuthData("blog.example", webAuthnFlagUserPresent|webAuthnFlagAttestedData, 7, attested)
+	authData, err := ParseWebAuthnAuthenticatorData(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authData.SignCount != 7 || string(authData.CredentialID) != "credential-1" || string(authData.PublicKey) != string(coseKey) {
+		t.Fatalf("unexpected auth data %+v", authData)
+	}
+	if err := CheckWebAuthnRPID(authData, "blog.example"); err != nil {
+		t.Fatalf("CheckWebAuthnRPID: %v", err)
+	}
+	if err := CheckWebAuthnRPID(authData, "evil.example"); err == nil {
+		t.Fatal("RP ID hash of another host accepted")
+	}
+
+	notPresent, _ := ParseWebAuthnAuthenticatorData(testAuthData("blog.example", 0, 1, nil))
+	if err := CheckWebAuthnRPID(notPresent, "blog.example"); err == nil {
+		t.Fatal("authenticator data without user presence accepted")
+	}
+
+	malformed := map[string][]byte{
+		"too short":           full[:36],
+		"truncated aaguid":    full[:37+10],
+		"truncated id":        full[:37+18+4],
+		"truncated key":       full[:len(full)-5],
+		"trailing bytes":      append(append([]byte(nil), full...), 0x00),
+		"missing extensions":  testAuthData("blog.example", webAuthnFlagUserPresent|webAuthnFlagExtensionData, 1, nil),
+		"oversized id length": testAuthData("blog.example", webAuthnFlagAttestedData, 1, append(make([]byte, 16), 0xff, 0xff, 0x01)),
+	}
+	for name, raw := range malformed {
+		if _, err := ParseWebAuthnAuthenticatorData(raw); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, alg, err := ParseCOSEKey(es256COSEKey(&ecKey.PublicKey)); err != nil || alg != COSEAlgES256 {
+		t.Fatalf("ES256: alg %d err %v", alg, err)
+	}
+	edPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, alg, err := ParseCOSEKey(ed25519COSEKey(edPublic)); err != nil || alg != COSEAlgEdDSA {
+		t.Fatalf("EdDSA: alg %d err %v", alg, err)
+	}
+
+	offCurve := ecdsa.PublicKey{Curve: elliptic.P256(), X: ecKey.X, Y: new(big.Int).Add(ecKey.Y, big.NewInt(1))}
+	if _, _, err := ParseCOSEKey(es256COSEKey(&offCurve)); err == nil {
+		t.Fatal("point off the curve accepted")
+	}
+	if _, _, err := ParseCOSEKey(ed25519COSEKey(edPublic[:16])); err == nil {
+		t.Fatal("short Ed25519 key accepted")
+	}
+	if _, _, err := ParseCOSEKey(es256COSEKey(&ecKey.PublicKey)[:20]); err == nil {
+		t.Fatal("truncated key accepted")
+	}
+}
+
+func TestVerifyWebAuthnSignature(t *testing.T) {
+	data := []byte("authenticator-data||client-data-hash")
+	tampered := append([]byte(nil), data...)
+	tampered[0] ^= 0x01
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	digest := sha256.Sum256(data)
+	ecSignature, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edSignature := ed25519.Sign(edPrivate, data)
+
+	tests := []struct {
+		name      string
+		key       interface{}
+		alg       int
+		data      []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{"es256", &ecKey.PublicKey, COSEAlgES256, data, ecSignature, false},
+		{"es256 tampered data", &ecKey.PublicKey, COSEAlgES256, tampered, ecSignature, true},
+		{"es256 truncated signature", &ecKey.PublicKey, COSEAlgES256, data, ecSignature[:len(ecSignature)-1], true},
+		{"eddsa", edPublic, COSEAlgEdDSA, data, edSignature, false},
+		{"eddsa tampered data", edPublic, COSEAlgEdDSA, tampered, edSignature, true},
+		{"algorithm confusion", edPublic, COSEAlgES256, data, edSignature, true},
+		{"unsupported algorithm", edPublic, -35, data, edSignature, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebAuthnSignature(tt.key, tt.alg, tt.data, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}